@@ -6,12 +6,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 	"time"
 
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/sonyamoonglade/poison-tg/config"
@@ -19,7 +17,10 @@ import (
 	"github.com/sonyamoonglade/poison-tg/internal/repositories"
 	"github.com/sonyamoonglade/poison-tg/internal/telegram"
 	"github.com/sonyamoonglade/poison-tg/internal/telegram/catalog"
+	"github.com/sonyamoonglade/poison-tg/internal/telegram/pickup"
+	"github.com/sonyamoonglade/poison-tg/pkg/blob"
 	"github.com/sonyamoonglade/poison-tg/pkg/database"
+	"github.com/sonyamoonglade/poison-tg/pkg/lifecycle"
 	"github.com/sonyamoonglade/poison-tg/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -72,25 +73,97 @@ func run() error {
 		return fmt.Errorf("error creating telegram bot: %w", err)
 	}
 
-	if err := telegram.LoadTemplates("templates.json"); err != nil {
+	if err := telegram.LoadLocales(localesDir); err != nil {
+		return fmt.Errorf("can't load locales: %w", err)
+	}
+	if err := telegram.LoadTemplates(templatesPath); err != nil {
 		return fmt.Errorf("can't load templates: %w", err)
 	}
 	rateProvider := api.NewRateProvider()
 
-	handler := telegram.NewHandler(bot,
+	pickupPoints, err := pickup.NewFileDirectory(cfg.Delivery.PickupPointsPath)
+	if err != nil {
+		return fmt.Errorf("can't load pickup points from %s: %w", cfg.Delivery.PickupPointsPath, err)
+	}
+
+	// Everything sent to telegram goes through sender to stay within rate limits
+	sender := telegram.NewSender(bot)
+	senderCtx, stopSender := context.WithCancel(context.Background())
+	defer stopSender()
+	senderDone := make(chan struct{})
+	go func() {
+		sender.Run(senderCtx)
+		close(senderDone)
+	}()
+
+	// Buttons are decoded by router, so both share the codec
+	callbackCodec := telegram.NewCallbackCodec(repos.Callback)
+
+	handler := telegram.NewHandler(sender,
+		sender.Bulk(),
 		repos,
 		rateProvider,
-		catalogProvider)
+		catalogProvider,
+		pickupPoints,
+		callbackCodec)
+
+	// Webhook is mounted on http api below
+	var (
+		webhook *telegram.Webhook
+		updates tg.UpdatesChannel
+	)
+	if cfg.Webhook.URL != "" {
+		webhook = telegram.NewWebhook(cfg.Webhook.SecretToken)
+		updates = webhook.Updates()
+	} else {
+		// Long polling doesn't work while webhook is set
+		if err := bot.DeleteWebhook(); err != nil {
+			return fmt.Errorf("can't delete webhook: %w", err)
+		}
+		updates = bot.GetUpdates()
+	}
 
-	router := telegram.NewRouter(bot.GetUpdates(),
+	router := telegram.NewRouter(updates,
 		handler,
 		repos.Customer,
+		callbackCodec,
 		cfg.Bot.HandlerTimeout)
 
+	catalogScheduler := catalog.NewScheduler(catalogProvider,
+		repos.CatalogState,
+		repos.Customer.NullifyCatalogOffsets,
+		handler.NotifyDrop)
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	// Background jobs are waited for on shutdown
+	jobs := new(sync.WaitGroup)
+	runJob := func(run func(ctx context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			run(schedulerCtx)
+		}()
+	}
+	runJob(catalogScheduler.Run)
+	runJob(catalog.NewPriceWatcher(catalogProvider, handler.NotifyFavourites).Run)
+
+	broadcaster := telegram.NewBroadcaster(sender.Bulk(),
+		repos.Broadcast,
+		repos.Customer,
+		repos.Order)
+	runJob(broadcaster.Run)
+
+	cartReminder := telegram.NewCartReminder(sender.Bulk(), repos.Customer, callbackCodec, cfg.Reminders.AbandonedCart)
+	runJob(cartReminder.Run)
+
+	runJob(telegram.NewTemplateWatcher(templatesPath, localesDir).Run)
+
 	// HTTP api
 	app := fiber.New(fiber.Config{
 		Immutable: true,
 		Prefork:   false,
+		BodyLimit: api.BodyLimit,
 		ErrorHandler: func(ctx *fiber.Ctx, err error) error {
 			logger.Get().Error("error in api endpoint", zap.ByteString("url",
 				ctx.Request().RequestURI()),
@@ -113,33 +186,116 @@ func run() error {
 		return c.Next()
 	})
 
-	apiController := api.NewHandler(repos.Catalog, repos.Order, repos.Customer, rateProvider)
+	imageStore, err := blob.NewLocalStore(cfg.Storage.ImagesDir)
+	if err != nil {
+		return fmt.Errorf("error creating image store: %w", err)
+	}
+
+	apiController := api.NewHandler(repos.Catalog,
+		repos.Order,
+		repos.Customer,
+		repos.Broadcast,
+		rateProvider,
+		imageStore,
+		router,
+		func() error {
+			return telegram.ReloadTexts(templatesPath, localesDir)
+		},
+		cfg.App.PublicURL)
 	apiController.RegisterRoutes(app)
-	wg := new(sync.WaitGroup)
-	wg.Add(1)
+	if cfg.App.PublicURL == "" {
+		logger.Get().Warn("image upload is disabled, PUBLIC_URL is not set")
+	}
+	if webhook != nil {
+		webhook.RegisterRoute(app, cfg.Webhook.Path)
+	}
 	go func() {
 		if err := app.Listen(":" + cfg.App.Port); err != nil {
 			logger.Get().Error("http server error", zap.Error(err))
 		}
-		wg.Done()
 	}()
 	logger.Get().Info("http api server is up")
 
-	if err := router.Bootstrap(); err != nil {
-		return err
+	if webhook != nil {
+		// Telegram retries updates until http server is listening
+		if err := bot.SetWebhook(cfg.Webhook.URL, cfg.Webhook.SecretToken); err != nil {
+			return fmt.Errorf("can't set webhook: %w", err)
+		}
+		logger.Get().Info("receiving updates with webhook", zap.String("path", cfg.Webhook.Path))
 	}
 
-	exitChan := make(chan os.Signal, 1)
-	signal.Notify(exitChan, os.Interrupt, syscall.SIGINT)
+	go router.Bootstrap() //nolint:errcheck
+
+	// Graceful shutdown. Order matters: nothing new comes in, what's in progress is finished, then storage is closed.
+	// In-flight handlers are given their full timeout
+	lc := lifecycle.New(cfg.Bot.HandlerTimeout + shutdownGrace)
+	lc.OnStop("updates", func(ctx context.Context) error {
+		if webhook != nil {
+			webhook.Close()
+			return nil
+		}
+		bot.Shutdown()
+		return nil
+	})
+	lc.OnStop("router", router.Shutdown)
+	lc.OnStop("http api", func(ctx context.Context) error {
+		return app.ShutdownWithTimeout(timeUntil(ctx))
+	})
+	lc.OnStop("background jobs", func(ctx context.Context) error {
+		stopScheduler()
+		return waitGroup(ctx, jobs)
+	})
+	// Goes after everything that sends messages
+	lc.OnStop("telegram sender", func(ctx context.Context) error {
+		stopSender()
+		select {
+		case <-senderDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	lc.OnStop("logger", func(ctx context.Context) error {
+		// Sync of stdout fails on some platforms, nothing to do about it
+		_ = logger.Get().Sync()
+		return nil
+	})
+	lc.OnStop("mongo", mongo.Close)
+
+	sig := lc.Wait(context.Background())
+	logger.Get().Info("shutting down", zap.Stringer("signal", sig))
+	return lc.Shutdown()
+}
+
+const (
+	// Templates of other languages are next to it, see telegram.LoadTemplates
+	templatesPath = "templates.json"
+	localesDir    = "locales"
+)
 
-	// Graceful shutdown
-	<-exitChan
-	if err := app.Shutdown(); err != nil {
-		return fmt.Errorf("api shutdown: %w", err)
+// Time given to shutdown on top of handler timeout
+const shutdownGrace = time.Second * 10
+
+func timeUntil(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
 	}
+	return time.Until(deadline)
+}
 
-	wg.Wait()
-	return mongo.Close(context.Background())
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func readCmdArgs() (string, string, bool, bool) {
@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/spf13/viper"
@@ -11,6 +13,16 @@ import (
 
 var ErrConfigNoExist = errors.New("config file doesn't exist")
 
+const defaultImagesDir = "uploads"
+
+// Abandoned cart reminders are sent after these periods of inactivity
+var defaultAbandonedCartHours = []int{24, 72}
+
+const maxAbandonedCartReminders = 2
+
+// Telegram restricts secret token to these characters
+var webhookSecretRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
 type AppConfig struct {
 	Database struct {
 		// Connection string
@@ -28,6 +40,32 @@ type AppConfig struct {
 
 	App struct {
 		Port string
+		// Public url of http api. Used to build urls of uploaded images, upload is disabled if it's empty
+		PublicURL string
+	}
+
+	Storage struct {
+		// Directory for uploaded images
+		ImagesDir string
+	}
+
+	Reminders struct {
+		// Inactivity periods after which customer with non-empty cart is reminded. One reminder per period
+		AbandonedCart []time.Duration
+	}
+
+	Delivery struct {
+		// JSON file with CDEK and PickPoint offices exported from providers
+		PickupPointsPath string
+	}
+
+	Webhook struct {
+		// Public url Telegram posts updates to. Long polling is used if it's empty
+		URL string
+		// Path of url, route is mounted on http api
+		Path string
+		// Telegram sends it in header of every request, requests without it are rejected
+		SecretToken string
 	}
 }
 
@@ -69,6 +107,56 @@ func ReadConfig(path string) (AppConfig, error) {
 		return AppConfig{}, fmt.Errorf("missing telegram.handler_timeout")
 	}
 
+	// Optional, image upload is disabled without it. Telegram fetches uploaded images by this url
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL != "" {
+		u, err := url.Parse(publicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return AppConfig{}, fmt.Errorf("PUBLIC_URL must be absolute http(s) url")
+		}
+	}
+
+	imagesDir := viper.GetString("storage.images_dir")
+	if imagesDir == "" {
+		imagesDir = defaultImagesDir
+	}
+
+	// Delivery addresses can't be checked without real directory, there's no default
+	pickupPointsPath := viper.GetString("delivery.pickup_points_path")
+	if pickupPointsPath == "" {
+		return AppConfig{}, fmt.Errorf("missing delivery.pickup_points_path")
+	}
+
+	abandonedCartHours := viper.GetIntSlice("reminders.abandoned_cart_hours")
+	if len(abandonedCartHours) == 0 {
+		abandonedCartHours = defaultAbandonedCartHours
+	}
+	if len(abandonedCartHours) > maxAbandonedCartReminders {
+		return AppConfig{}, fmt.Errorf("reminders.abandoned_cart_hours: at most %d reminders", maxAbandonedCartReminders)
+	}
+	// Optional, both are required for webhook mode
+	webhookURL := os.Getenv("WEBHOOK_URL")
+	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+	var webhookPath string
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || u.Scheme != "https" || u.Path == "" {
+			return AppConfig{}, fmt.Errorf("WEBHOOK_URL must be https url with path")
+		}
+		if !webhookSecretRegexp.MatchString(webhookSecret) {
+			return AppConfig{}, fmt.Errorf("WEBHOOK_SECRET must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+		}
+		webhookPath = u.Path
+	}
+
+	abandonedCart := make([]time.Duration, 0, len(abandonedCartHours))
+	for i, h := range abandonedCartHours {
+		if h <= 0 || (i > 0 && h <= abandonedCartHours[i-1]) {
+			return AppConfig{}, fmt.Errorf("reminders.abandoned_cart_hours must be positive and ascending")
+		}
+		abandonedCart = append(abandonedCart, time.Duration(h)*time.Hour)
+	}
+
 	return AppConfig{
 		Database: struct {
 			URI  string
@@ -85,9 +173,35 @@ func ReadConfig(path string) (AppConfig, error) {
 			HandlerTimeout: time.Duration(handlerTimeout) * time.Second,
 		},
 		App: struct {
-			Port string
+			Port      string
+			PublicURL string
+		}{
+			Port:      port,
+			PublicURL: publicURL,
+		},
+		Storage: struct {
+			ImagesDir string
+		}{
+			ImagesDir: imagesDir,
+		},
+		Reminders: struct {
+			AbandonedCart []time.Duration
+		}{
+			AbandonedCart: abandonedCart,
+		},
+		Delivery: struct {
+			PickupPointsPath string
+		}{
+			PickupPointsPath: pickupPointsPath,
+		},
+		Webhook: struct {
+			URL         string
+			Path        string
+			SecretToken string
 		}{
-			Port: port,
+			URL:         webhookURL,
+			Path:        webhookPath,
+			SecretToken: webhookSecret,
 		},
 	}, nil
 }
@@ -1,15 +1,22 @@
 package api
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sonyamoonglade/poison-tg/internal/api/input"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+	"github.com/sonyamoonglade/poison-tg/internal/telegram"
+	"github.com/sonyamoonglade/poison-tg/pkg/blob"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -17,7 +24,20 @@ type Handler struct {
 	catalogRepo  repositories.Catalog
 	orderRepo    repositories.Order
 	customerRepo repositories.Customer
-	rateProvider *RateProvider
+	// Admin newsletters
+	broadcastRepo repositories.Broadcast
+	rateProvider  *RateProvider
+	imageStore    blob.Store
+	routerStats   RouterStats
+	// Reloads templates and locales, see telegram.ReloadTexts
+	reloadTexts func() error
+	// Used to build public urls of uploaded images
+	publicURL string
+}
+
+// RouterStats is implemented by telegram.Router
+type RouterStats interface {
+	QueueStats() telegram.QueueStats
 }
 
 type RateProvider struct {
@@ -45,17 +65,31 @@ func (r *RateProvider) UpdateRate(rate float64) {
 	r.CurrRate = rate
 }
 
-func NewHandler(catalogRepo repositories.Catalog, orderRepo repositories.Order, customerRepo repositories.Customer, provider *RateProvider) *Handler {
+func NewHandler(catalogRepo repositories.Catalog,
+	orderRepo repositories.Order,
+	customerRepo repositories.Customer,
+	broadcastRepo repositories.Broadcast,
+	provider *RateProvider,
+	imageStore blob.Store,
+	routerStats RouterStats,
+	reloadTexts func() error,
+	publicURL string) *Handler {
 	return &Handler{
-		catalogRepo:  catalogRepo,
-		rateProvider: provider,
-		orderRepo:    orderRepo,
-		customerRepo: customerRepo,
+		catalogRepo:   catalogRepo,
+		rateProvider:  provider,
+		orderRepo:     orderRepo,
+		customerRepo:  customerRepo,
+		broadcastRepo: broadcastRepo,
+		imageStore:    imageStore,
+		routerStats:   routerStats,
+		reloadTexts:   reloadTexts,
+		publicURL:     strings.TrimSuffix(publicURL, "/"),
 	}
 }
 
 func (h *Handler) RegisterRoutes(router fiber.Router) {
 	router.Get("/", h.Home)
+	router.Get("/images/:name", h.image)
 
 	api := router.Group("/api")
 	api.Post("/updateRate", h.updateRate)
@@ -76,9 +110,32 @@ func (h *Handler) RegisterRoutes(router fiber.Router) {
 		catalog.Get("/all", h.catalog)
 		catalog.Post("/addItem", h.addItemToCatalog)
 		catalog.Post("/deleteItem", h.removeItemFromCatalog)
+		catalog.Put("/updateItem", h.updateCatalogItem)
 		catalog.Put("/rankUp", h.rankUp)
 		catalog.Put("/rankDown", h.rankDown)
+		catalog.Put("/reorder", h.reorder)
+		// Telegram can't fetch image by relative url
+		if h.publicURL != "" {
+			catalog.Post("/uploadImage", h.uploadImage)
+		}
+	}
+
+	broadcast := api.Group("/broadcast")
+	{
+		broadcast.Post("/create", h.createBroadcast)
+		broadcast.Get("/all", h.getAllBroadcasts)
+		broadcast.Get("/:broadcastId", h.getBroadcastByID)
+		broadcast.Post("/cancel/:broadcastId", h.cancelBroadcast)
+	}
+
+	stats := api.Group("/stats")
+	{
+		// Depth of per-chat update queues
+		stats.Get("/router", h.routerQueueStats)
 	}
+
+	// Same as editing templates or locale files, invalid ones are rejected and current ones stay in use
+	api.Post("/templates/reload", h.reloadTemplates)
 }
 func (h *Handler) Home(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusOK)
@@ -173,6 +230,24 @@ func (h *Handler) removeItemFromCatalog(c *fiber.Ctx) error {
 	return h.withNewCatalog(c)
 }
 
+func (h *Handler) updateCatalogItem(c *fiber.Ctx) error {
+	var inp input.UpdateCatalogItemInput
+	if err := c.BodyParser(&inp); err != nil {
+		return err
+	}
+
+	if err := h.catalogRepo.UpdateItem(c.Context(), inp.ToDTO()); err != nil {
+		if errors.Is(err, domain.ErrItemNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return fmt.Errorf("update item: %w", err)
+	}
+
+	return h.withNewCatalog(c)
+}
+
 func (h *Handler) rankUp(c *fiber.Ctx) error {
 	var inp input.RankUpInput
 	if err := c.BodyParser(&inp); err != nil {
@@ -234,6 +309,40 @@ func (h *Handler) rankDown(c *fiber.Ctx) error {
 	return h.withNewCatalog(c)
 }
 
+func (h *Handler) reorder(c *fiber.Ctx) error {
+	var inp input.ReorderInput
+	if err := c.BodyParser(&inp); err != nil {
+		return err
+	}
+	if inp.IsMove() == inp.IsFullOrder() {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "provide either itemIds or itemId with rank",
+		})
+	}
+
+	// Ranks are computed from catalog read in the same transaction, so concurrent changes aren't overwritten
+	err := h.catalogRepo.Reorder(c.Context(), func(catalog []domain.CatalogItem) ([]domain.CatalogItem, error) {
+		if inp.IsMove() {
+			return domain.MoveToRank(catalog, *inp.ItemID, *inp.Rank)
+		}
+		return domain.ReorderByIDs(catalog, inp.ItemIDs)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidOrder) || errors.Is(err, domain.ErrItemNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return fmt.Errorf("reorder: %w", err)
+	}
+
+	if err := h.customerRepo.NullifyCatalogOffsets(c.Context()); err != nil {
+		return fmt.Errorf("nullify customer catalog offsets: %w", err)
+	}
+
+	return h.withNewCatalog(c)
+}
+
 func (h *Handler) withNewCatalog(c *fiber.Ctx) error {
 	newCatalog, err := h.catalogRepo.GetCatalog(c.Context())
 	if err != nil {
@@ -289,8 +398,160 @@ func (h *Handler) delete(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusOK)
 }
 
+func (h *Handler) createBroadcast(c *fiber.Ctx) error {
+	var inp input.CreateBroadcastInput
+	if err := c.BodyParser(&inp); err != nil {
+		return fmt.Errorf("body parsing error: %w", err)
+	}
+
+	broadcast := inp.ToDomain(time.Now())
+	if err := broadcast.Validate(); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	broadcast, err := h.broadcastRepo.Save(c.Context(), broadcast)
+	if err != nil {
+		return fmt.Errorf("can't save broadcast: %w", err)
+	}
+	return c.Status(http.StatusCreated).JSON(broadcast)
+}
+
+func (h *Handler) getAllBroadcasts(c *fiber.Ctx) error {
+	broadcasts, err := h.broadcastRepo.GetAll(c.Context())
+	if err != nil {
+		return fmt.Errorf("can't get broadcasts: %w", err)
+	}
+	return c.Status(http.StatusOK).JSON(broadcasts)
+}
+
+func (h *Handler) getBroadcastByID(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("broadcastId", ""))
+	if err != nil {
+		return fmt.Errorf("invalid broadcastId: %w", err)
+	}
+	broadcast, err := h.broadcastRepo.GetByID(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrBroadcastNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return fmt.Errorf("can't get broadcast: %w", err)
+	}
+	return c.Status(http.StatusOK).JSON(broadcast)
+}
+
+// cancelBroadcast works only for broadcasts that are not sent yet
+func (h *Handler) cancelBroadcast(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("broadcastId", ""))
+	if err != nil {
+		return fmt.Errorf("invalid broadcastId: %w", err)
+	}
+	if err := h.broadcastRepo.Cancel(c.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrBroadcastNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, domain.ErrBroadcastStatus):
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return fmt.Errorf("cancel: %w", err)
+	}
+	return c.SendStatus(http.StatusOK)
+}
+
 func (h *Handler) currentRate(c *fiber.Ctx) error {
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"rate": h.rateProvider.GetYuanRate(),
 	})
 }
+
+// Telegram fetches photos by url up to 5 MB
+const maxImageSize = 5 << 20
+
+// BodyLimit of http api leaves room for multipart form around uploaded image
+const BodyLimit = maxImageSize + 1<<20
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+func (h *Handler) routerQueueStats(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(h.routerStats.QueueStats())
+}
+
+func (h *Handler) reloadTemplates(c *fiber.Ctx) error {
+	if err := h.reloadTexts(); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.SendStatus(http.StatusOK)
+}
+
+func (h *Handler) uploadImage(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "missing image file",
+		})
+	}
+	if fileHeader.Size > maxImageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "image is too large",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("open uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("read uploaded file: %w", err)
+	}
+
+	ext, ok := imageExtensions[http.DetectContentType(content)]
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "unsupported image type",
+		})
+	}
+
+	name := primitive.NewObjectID().Hex() + ext
+	if err := h.imageStore.Put(c.Context(), name, bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("put image: %w", err)
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"name": name,
+		"url":  h.publicURL + "/images/" + name,
+	})
+}
+
+func (h *Handler) image(c *fiber.Ctx) error {
+	rc, err := h.imageStore.Open(c.Context(), c.Params("name", ""))
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidName) {
+			return c.SendStatus(http.StatusNotFound)
+		}
+		return fmt.Errorf("open image: %w", err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("read image: %w", err)
+	}
+
+	c.Set(fiber.HeaderContentType, http.DetectContentType(content))
+	return c.Status(http.StatusOK).Send(content)
+}
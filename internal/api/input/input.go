@@ -1,6 +1,8 @@
 package input
 
 import (
+	"time"
+
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -38,6 +40,10 @@ type AddItemToCatalogInput struct {
 	Quantity        int      `json:"quantity"`
 	ShopLink        string   `json:"shopLink"`
 	PriceRUB        uint64   `json:"priceRub"`
+	// Optional publication window
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+	IsDrop      bool       `json:"isDrop"`
 }
 
 func (a AddItemToCatalogInput) ToNewCatalogItem(rank uint) domain.CatalogItem {
@@ -50,6 +56,62 @@ func (a AddItemToCatalogInput) ToNewCatalogItem(rank uint) domain.CatalogItem {
 		Quantity:        a.Quantity,
 		PriceRUB:        a.PriceRUB,
 		Rank:            rank,
+		PublishAt:       a.PublishAt,
+		UnpublishAt:     a.UnpublishAt,
+		IsDrop:          a.IsDrop,
+	}
+}
+
+type UpdateCatalogItemInput struct {
+	ItemID   primitive.ObjectID `json:"itemId"`
+	PriceRUB *uint64            `json:"priceRub"`
+	Quantity *int               `json:"quantity"`
+}
+
+func (u UpdateCatalogItemInput) ToDTO() dto.UpdateCatalogItemDTO {
+	return dto.UpdateCatalogItemDTO{
+		ItemID:   u.ItemID,
+		PriceRUB: u.PriceRUB,
+		Quantity: u.Quantity,
+	}
+}
+
+type BroadcastButtonInput struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+type CreateBroadcastInput struct {
+	Text         string                `json:"text"`
+	ImageURLs    []string              `json:"imageUrls"`
+	Button       *BroadcastButtonInput `json:"button"`
+	Audience     string                `json:"audience"`
+	InactiveDays uint                  `json:"inactiveDays"`
+	// Send immediately if nil
+	ScheduledAt *time.Time `json:"scheduledAt"`
+}
+
+func (c CreateBroadcastInput) ToDomain(now time.Time) domain.Broadcast {
+	scheduledAt := now
+	if c.ScheduledAt != nil {
+		scheduledAt = *c.ScheduledAt
+	}
+	var button *domain.BroadcastButton
+	if c.Button != nil {
+		button = &domain.BroadcastButton{
+			Text: c.Button.Text,
+			URL:  c.Button.URL,
+		}
+	}
+	return domain.Broadcast{
+		Text:         c.Text,
+		ImageURLs:    c.ImageURLs,
+		Button:       button,
+		Audience:     domain.BroadcastAudience(c.Audience),
+		InactiveDays: c.InactiveDays,
+		ScheduledAt:  scheduledAt,
+		Status:       domain.BroadcastPending,
+		CreatedAt:    now,
 	}
 }
 
@@ -64,3 +126,19 @@ type RankUpInput struct {
 type RankDownInput struct {
 	ItemID primitive.ObjectID `json:"itemId"`
 }
+
+// ReorderInput is either full ordered list of item ids (ItemIDs)
+// or move command (ItemID to Rank)
+type ReorderInput struct {
+	ItemIDs []primitive.ObjectID `json:"itemIds"`
+	ItemID  *primitive.ObjectID  `json:"itemId"`
+	Rank    *uint                `json:"rank"`
+}
+
+func (r ReorderInput) IsMove() bool {
+	return r.ItemID != nil && r.Rank != nil
+}
+
+func (r ReorderInput) IsFullOrder() bool {
+	return len(r.ItemIDs) > 0
+}
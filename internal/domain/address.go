@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxAddresses is how many delivery addresses customer can keep in address book
+const MaxAddresses = 5
+
+const maxAddressNameLen = 32
+
+var (
+	ErrTooManyAddresses = errors.New("too many addresses")
+	ErrInvalidAddressN  = errors.New("invalid address number")
+	ErrAddressNameTaken = errors.New("address name is taken")
+)
+
+// Address is named delivery address from customer's address book
+type Address struct {
+	Name string `json:"name" bson:"name"`
+	// Human readable address
+	Address string `json:"address" bson:"address"`
+	// Nil for free text addresses saved before addresses became structured
+	Details *StructuredAddress `json:"details,omitempty" bson:"details,omitempty"`
+}
+
+func NewAddress(name string, details StructuredAddress) Address {
+	return Address{
+		Name:    name,
+		Address: details.String(),
+		Details: &details,
+	}
+}
+
+// IsStructured reports whether address can be used for delivery
+func (a Address) IsStructured() bool {
+	return a.Details != nil
+}
+
+// DefaultAddressName is name for address customer didn't name, unique within address book.
+// label is word for address in customer's language
+func (c *Customer) DefaultAddressName(label string) string {
+	for n := len(c.Addresses) + 1; ; n++ {
+		name := fmt.Sprintf("%s %d", label, n)
+		if !c.HasAddressNamed(name) {
+			return name
+		}
+	}
+}
+
+func (c *Customer) HasAddressNamed(name string) bool {
+	for _, a := range c.Addresses {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAddressName checks name given to address by customer
+func ValidateAddressName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAddressNameLen {
+		return fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidAddress, maxAddressNameLen)
+	}
+	return nil
+}
+
+// HasSavedDetails reports whether customer can make order without typing full name and phone number
+func (c *Customer) HasSavedDetails() bool {
+	return c.FullName != nil && *c.FullName != "" &&
+		c.PhoneNumber != nil && *c.PhoneNumber != ""
+}
+
+// AddAddress saves address to address book.
+// The same address is not duplicated, address with the same name is never replaced
+func (c *Customer) AddAddress(a Address) error {
+	for _, saved := range c.Addresses {
+		if saved.Address == a.Address {
+			return nil
+		}
+		if saved.Name == a.Name {
+			return ErrAddressNameTaken
+		}
+	}
+	if len(c.Addresses) >= MaxAddresses {
+		return ErrTooManyAddresses
+	}
+	c.Addresses = append(c.Addresses, a)
+	return nil
+}
+
+// GetAddress returns n-th (1-based) address
+func (c *Customer) GetAddress(n int) (Address, error) {
+	if n < 1 || n > len(c.Addresses) {
+		return Address{}, ErrInvalidAddressN
+	}
+	return c.Addresses[n-1], nil
+}
+
+// RemoveAddress removes n-th (1-based) address
+func (c *Customer) RemoveAddress(n int) error {
+	if n < 1 || n > len(c.Addresses) {
+		return ErrInvalidAddressN
+	}
+	c.Addresses = append(c.Addresses[:n-1], c.Addresses[n:]...)
+	return nil
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAddressName(t *testing.T) {
+	require.NoError(t, ValidateAddressName("Дом"))
+	require.ErrorIs(t, ValidateAddressName("  "), ErrInvalidAddress)
+	require.ErrorIs(t, ValidateAddressName(strings.Repeat("д", maxAddressNameLen+1)), ErrInvalidAddress)
+}
+
+func TestAddressBook(t *testing.T) {
+	var customer Customer
+
+	require.NoError(t, customer.AddAddress(Address{Name: "Дом", Address: "Москва, Тверская, 1"}))
+	// Same address is not duplicated
+	require.NoError(t, customer.AddAddress(Address{Name: "Адрес 2", Address: "Москва, Тверская, 1"}))
+	require.Len(t, customer.Addresses, 1)
+	// Same name doesn't replace address
+	require.ErrorIs(t, customer.AddAddress(Address{Name: "Дом", Address: "Москва, Арбат, 2"}), ErrAddressNameTaken)
+	require.Len(t, customer.Addresses, 1)
+	require.Equal(t, "Москва, Тверская, 1", customer.Addresses[0].Address)
+
+	for len(customer.Addresses) < MaxAddresses {
+		name := customer.DefaultAddressName("Адрес")
+		require.NoError(t, customer.AddAddress(Address{Name: name, Address: name}))
+	}
+	require.ErrorIs(t, customer.AddAddress(Address{Name: "Работа", Address: "Москва, Арбат, 3"}), ErrTooManyAddresses)
+
+	address, err := customer.GetAddress(2)
+	require.NoError(t, err)
+	require.Equal(t, "Адрес 2", address.Name)
+	_, err = customer.GetAddress(0)
+	require.ErrorIs(t, err, ErrInvalidAddressN)
+
+	require.ErrorIs(t, customer.RemoveAddress(MaxAddresses+1), ErrInvalidAddressN)
+	require.NoError(t, customer.RemoveAddress(1))
+	require.Len(t, customer.Addresses, MaxAddresses-1)
+	require.Equal(t, "Адрес 2", customer.Addresses[0].Name)
+	// Name of removed address is not reused while it's taken
+	name := customer.DefaultAddressName("Адрес")
+	require.Equal(t, "Адрес 6", name)
+	require.NoError(t, customer.AddAddress(Address{Name: name, Address: "Москва, Арбат, 4"}))
+	require.Len(t, customer.Addresses, MaxAddresses)
+	require.Equal(t, "Адрес 2", customer.Addresses[0].Name)
+}
+
+func TestHasSavedDetails(t *testing.T) {
+	var customer Customer
+	require.False(t, customer.HasSavedDetails())
+
+	customer.FullName = stringPtr("John Doe")
+	require.False(t, customer.HasSavedDetails())
+
+	customer.PhoneNumber = stringPtr("79128000000")
+	require.True(t, customer.HasSavedDetails())
+}
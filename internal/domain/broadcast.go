@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrBroadcastNotFound = errors.New("broadcast not found")
+	ErrInvalidBroadcast  = errors.New("invalid broadcast")
+	ErrBroadcastStatus   = errors.New("broadcast has unexpected status")
+)
+
+type BroadcastAudience string
+
+const (
+	AudienceAll        BroadcastAudience = "all"
+	AudienceWithOrders BroadcastAudience = "withOrders"
+	AudienceWithCart   BroadcastAudience = "withCart"
+	// Customers who did not interact with bot for InactiveDays
+	AudienceInactive BroadcastAudience = "inactive"
+)
+
+type BroadcastStatus string
+
+const (
+	BroadcastPending   BroadcastStatus = "pending"
+	BroadcastSending   BroadcastStatus = "sending"
+	BroadcastDone      BroadcastStatus = "done"
+	BroadcastCancelled BroadcastStatus = "cancelled"
+)
+
+type BroadcastButton struct {
+	Text string `json:"text" bson:"text"`
+	URL  string `json:"url" bson:"url"`
+}
+
+type BroadcastStats struct {
+	Total   int `json:"total" bson:"total"`
+	Sent    int `json:"sent" bson:"sent"`
+	Failed  int `json:"failed" bson:"failed"`
+	Blocked int `json:"blocked" bson:"blocked"`
+}
+
+type Broadcast struct {
+	BroadcastID primitive.ObjectID `json:"broadcastId" bson:"_id,omitempty"`
+	// HTML formatted
+	Text         string            `json:"text" bson:"text"`
+	ImageURLs    []string          `json:"imageUrls,omitempty" bson:"imageUrls,omitempty"`
+	Button       *BroadcastButton  `json:"button,omitempty" bson:"button,omitempty"`
+	Audience     BroadcastAudience `json:"audience" bson:"audience"`
+	InactiveDays uint              `json:"inactiveDays,omitempty" bson:"inactiveDays,omitempty"`
+	ScheduledAt  time.Time         `json:"scheduledAt" bson:"scheduledAt"`
+	Status       BroadcastStatus   `json:"status" bson:"status"`
+	Stats        BroadcastStats    `json:"stats" bson:"stats"`
+	// Audience is sent in order of customer id. Customers up to cursor are done,
+	// broadcast interrupted by shutdown continues after it
+	Cursor     *primitive.ObjectID `json:"cursor,omitempty" bson:"cursor,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt" bson:"createdAt"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+// Telegram limits media group to 10 items
+const maxBroadcastImages = 10
+
+func (b *Broadcast) Validate() error {
+	if b.Text == "" {
+		return fmt.Errorf("%w: empty text", ErrInvalidBroadcast)
+	}
+	if len(b.ImageURLs) > maxBroadcastImages {
+		return fmt.Errorf("%w: too many images", ErrInvalidBroadcast)
+	}
+	if b.Button != nil && (b.Button.Text == "" || b.Button.URL == "") {
+		return fmt.Errorf("%w: button must have text and url", ErrInvalidBroadcast)
+	}
+	switch b.Audience {
+	case AudienceAll, AudienceWithOrders, AudienceWithCart:
+	case AudienceInactive:
+		if b.InactiveDays == 0 {
+			return fmt.Errorf("%w: inactiveDays must be positive", ErrInvalidBroadcast)
+		}
+	default:
+		return fmt.Errorf("%w: unknown audience", ErrInvalidBroadcast)
+	}
+	return nil
+}
+
+// InactiveSince returns moment customer must have been inactive since to get broadcast
+func (b *Broadcast) InactiveSince(now time.Time) time.Time {
+	return now.Add(-time.Duration(b.InactiveDays) * 24 * time.Hour)
+}
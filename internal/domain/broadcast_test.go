@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastValidate(t *testing.T) {
+	tests := []struct {
+		description string
+		broadcast   Broadcast
+		valid       bool
+	}{
+		{
+			description: "text for everyone",
+			broadcast:   Broadcast{Text: "hello", Audience: AudienceAll},
+			valid:       true,
+		},
+		{
+			description: "empty text",
+			broadcast:   Broadcast{Audience: AudienceAll},
+		},
+		{
+			description: "unknown audience",
+			broadcast:   Broadcast{Text: "hello", Audience: "vip"},
+		},
+		{
+			description: "inactive without days",
+			broadcast:   Broadcast{Text: "hello", Audience: AudienceInactive},
+		},
+		{
+			description: "inactive for a week",
+			broadcast:   Broadcast{Text: "hello", Audience: AudienceInactive, InactiveDays: 7},
+			valid:       true,
+		},
+		{
+			description: "button without url",
+			broadcast:   Broadcast{Text: "hello", Audience: AudienceWithCart, Button: &BroadcastButton{Text: "go"}},
+		},
+		{
+			description: "too many images",
+			broadcast:   Broadcast{Text: "hello", Audience: AudienceWithOrders, ImageURLs: make([]string, 11)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.broadcast.Validate()
+			if tc.valid {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidBroadcast)
+		})
+	}
+}
+
+func TestBroadcastInactiveSince(t *testing.T) {
+	now := time.Date(2023, 4, 20, 12, 0, 0, 0, time.UTC)
+	b := Broadcast{InactiveDays: 3}
+	require.Equal(t, time.Date(2023, 4, 17, 12, 0, 0, 0, time.UTC), b.InactiveSince(now))
+}
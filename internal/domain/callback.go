@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCallbackNotFound = errors.New("callback payload not found")
+
+// StoredCallback is payload of button that doesn't fit into telegram callback data
+type StoredCallback struct {
+	Key     string `json:"key" bson:"_id"`
+	Payload []byte `json:"payload" bson:"payload"`
+	// Mongo removes expired payloads by ttl index, not right at this moment
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
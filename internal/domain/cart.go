@@ -3,9 +3,93 @@ package domain
 type Cart []Position
 
 func (c *Cart) Add(p Position) {
+	if p.Quantity == 0 {
+		p.Quantity = 1
+	}
 	*c = append(*c, p)
 }
 
+// AddRepeated appends positions of order re-priced with current rate after positions already in cart.
+// Returns positions whose price has changed numbered as in cart
+func (c *Cart) AddRepeated(o Order, rate float64) []PriceChange {
+	offset := len(*c)
+	repeated, changes := o.RepeatCart(rate)
+	for _, p := range repeated {
+		c.Add(p)
+	}
+	for i := range changes {
+		changes[i].N += offset
+	}
+	return changes
+}
+
+// IncreaseQuantity returns false if n is out of range or quantity is at max
+func (c Cart) IncreaseQuantity(n int) bool {
+	if n <= 0 || n > len(c) {
+		return false
+	}
+	q := c[n-1].GetQuantity()
+	if q >= MaxPositionQuantity {
+		return false
+	}
+	c[n-1].Quantity = q + 1
+	return true
+}
+
+// DecreaseQuantity returns false if n is out of range or quantity is 1.
+// Position is removed explicitly, not by decreasing
+func (c Cart) DecreaseQuantity(n int) bool {
+	if n <= 0 || n > len(c) {
+		return false
+	}
+	q := c[n-1].GetQuantity()
+	if q <= 1 {
+		return false
+	}
+	c[n-1].Quantity = q - 1
+	return true
+}
+
+// SplitByOrderType groups positions by delivery type keeping cart order.
+// Positions without delivery type get fallback
+func (c Cart) SplitByOrderType(fallback OrderType) []Cart {
+	var (
+		groups []Cart
+		index  = make(map[OrderType]int)
+	)
+	for _, p := range c {
+		if p.OrderType == 0 {
+			p.OrderType = fallback
+		}
+		i, ok := index[p.OrderType]
+		if !ok {
+			i = len(groups)
+			index[p.OrderType] = i
+			groups = append(groups, Cart{})
+		}
+		groups[i] = append(groups[i], p)
+	}
+	return groups
+}
+
+// Totals are quantity-aware
+func (c Cart) Totals() (rub uint64, yuan uint64) {
+	for _, p := range c {
+		rub += p.TotalRUB()
+		yuan += p.TotalYUAN()
+	}
+	return rub, yuan
+}
+
+// ItemsCount is total quantity of all positions
+func (c Cart) ItemsCount() int {
+	var count int
+	for _, p := range c {
+		count += int(p.GetQuantity())
+	}
+	return count
+}
+
 func (c *Cart) Remove(positionID string) {
 	for i, p := range *c {
 		if p.PositionID.Hex() == positionID {
@@ -3,6 +3,7 @@ package domain
 import (
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -79,3 +80,56 @@ func TestRemove(t *testing.T) {
 	})
 
 }
+
+func TestQuantity(t *testing.T) {
+	cart := make(Cart, 0)
+	cart.Add(Position{PriceRUB: 100, PriceYUAN: 10})
+	// Saved before quantity support
+	cart = append(cart, Position{PriceRUB: 200, PriceYUAN: 20})
+
+	require.Equal(t, uint(1), cart[0].Quantity)
+	require.Equal(t, uint(1), cart[1].GetQuantity())
+	require.Equal(t, 2, cart.ItemsCount())
+
+	require.False(t, cart.DecreaseQuantity(1))
+	require.False(t, cart.IncreaseQuantity(0))
+	require.False(t, cart.IncreaseQuantity(3))
+
+	require.True(t, cart.IncreaseQuantity(1))
+	require.True(t, cart.IncreaseQuantity(1))
+	require.True(t, cart.IncreaseQuantity(2))
+	require.Equal(t, uint(3), cart[0].Quantity)
+	require.Equal(t, uint(2), cart[1].Quantity)
+	require.Equal(t, 5, cart.ItemsCount())
+
+	rub, yuan := cart.Totals()
+	require.Equal(t, uint64(700), rub)
+	require.Equal(t, uint64(70), yuan)
+
+	require.True(t, cart.DecreaseQuantity(1))
+	require.Equal(t, uint(2), cart[0].Quantity)
+
+	cart[1].Quantity = MaxPositionQuantity
+	require.False(t, cart.IncreaseQuantity(2))
+}
+
+func TestSplitByOrderType(t *testing.T) {
+	cart := Cart{
+		{ShopLink: "a", OrderType: OrderTypeNormal},
+		{ShopLink: "b", OrderType: OrderTypeExpress},
+		// Legacy position without delivery type
+		{ShopLink: "c"},
+		{ShopLink: "d", OrderType: OrderTypeExpress},
+	}
+
+	groups := cart.SplitByOrderType(OrderTypeNormal)
+	require.Len(t, groups, 2)
+	require.Equal(t, []string{"a", "c"}, []string{groups[0][0].ShopLink, groups[0][1].ShopLink})
+	require.Equal(t, OrderTypeNormal, groups[0][1].OrderType)
+	require.Equal(t, []string{"b", "d"}, []string{groups[1][0].ShopLink, groups[1][1].ShopLink})
+	// Source cart is untouched
+	require.Equal(t, OrderType(0), cart[2].OrderType)
+
+	require.Len(t, Cart{{OrderType: OrderTypeExpress}, {}}.SplitByOrderType(OrderTypeExpress), 1)
+	require.Empty(t, Cart{}.SplitByOrderType(OrderTypeNormal))
+}
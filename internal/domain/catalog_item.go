@@ -3,8 +3,8 @@ package domain
 import (
 	"errors"
 	"fmt"
-	"math"
 	"strings"
+	"time"
 
 	"github.com/sonyamoonglade/poison-tg/pkg/functools"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,6 +13,7 @@ import (
 var (
 	ErrNoCatalog    = errors.New("catalog not found")
 	ErrItemNotFound = errors.New("item not found")
+	ErrInvalidOrder = errors.New("invalid catalog order")
 )
 
 type CatalogItem struct {
@@ -25,18 +26,43 @@ type CatalogItem struct {
 	ShopLink        string             `json:"shopLink" bson:"shopLink"`
 	Rank            uint               `json:"rank" bson:"rank"`
 	PriceRUB        uint64             `json:"priceRub" bson:"priceRub"`
+	// Item is visible in catalog only within [PublishAt, UnpublishAt). Nil means no bound
+	PublishAt   *time.Time `json:"publishAt,omitempty" bson:"publishAt,omitempty"`
+	UnpublishAt *time.Time `json:"unpublishAt,omitempty" bson:"unpublishAt,omitempty"`
+	// Subscribed customers are notified when drop gets published
+	IsDrop bool `json:"isDrop" bson:"isDrop"`
 }
 
-func (c *CatalogItem) GetCaption() string {
-	template := "Товар: <a href=\"%s\">%s</a>\n" +
-		"Размер(ы): %s\n" +
-		"Есть в городе: %s\n" +
-		"Количество товара: %d\n\n" +
-		"Стоимость в рублях: %d ₽"
-	return fmt.Sprintf(template, c.ShopLink, c.Title, c.getSizes(), c.getCities(), c.Quantity, c.PriceRUB)
+func (c *CatalogItem) IsPublishedAt(t time.Time) bool {
+	if c.PublishAt != nil && t.Before(*c.PublishAt) {
+		return false
+	}
+	if c.UnpublishAt != nil && !t.Before(*c.UnpublishAt) {
+		return false
+	}
+	return true
+}
+
+// NextBoundary returns closest publish or unpublish moment after t
+func (c *CatalogItem) NextBoundary(t time.Time) (time.Time, bool) {
+	var (
+		next  time.Time
+		found bool
+	)
+	for _, b := range []*time.Time{c.PublishAt, c.UnpublishAt} {
+		if b == nil || !b.After(t) {
+			continue
+		}
+		if !found || b.Before(next) {
+			next = *b
+			found = true
+		}
+	}
+	return next, found
 }
 
-func (c *CatalogItem) getSizes() string {
+// FormatSizes is a list of sizes for caption
+func (c *CatalogItem) FormatSizes() string {
 	var out string
 	for i, size := range c.AvailableSizes {
 		// last
@@ -49,41 +75,66 @@ func (c *CatalogItem) getSizes() string {
 	return out
 }
 
-func (c *CatalogItem) getCities() string {
+func (c *CatalogItem) FormatCities() string {
 	return strings.Join(c.AvailableInCity, "; ")
 }
 
-// catalog must be sorted by rank ascending
+// catalog must be sorted by rank ascending.
+// UpdateRanks repairs any number of gaps so that ranks become 0..len(catalog)-1
 func UpdateRanks(catalog []CatalogItem) []CatalogItem {
 	if catalog == nil {
 		return nil
 	}
-	// If first item's rank is 0 then down all subsequent
-	if catalog[0].Rank != uint(0) && catalog[0].Rank > uint(0) {
-		return functools.Map(func(item CatalogItem, i int) CatalogItem {
-			item.Rank--
-			return item
-		}, catalog)
+	return functools.Map(func(item CatalogItem, i int) CatalogItem {
+		item.Rank = uint(i)
+		return item
+	}, catalog)
+}
+
+// ReorderByIDs returns catalog ordered as itemIDs with ranks set accordingly.
+// itemIDs must contain every item of catalog exactly once
+func ReorderByIDs(catalog []CatalogItem, itemIDs []primitive.ObjectID) ([]CatalogItem, error) {
+	if len(itemIDs) != len(catalog) {
+		return nil, ErrInvalidOrder
+	}
+	byID := make(map[primitive.ObjectID]CatalogItem, len(catalog))
+	for _, item := range catalog {
+		byID[item.ItemID] = item
 	}
+	out := make([]CatalogItem, 0, len(catalog))
+	for _, id := range itemIDs {
+		item, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidOrder
+		}
+		// Prevent duplicates
+		delete(byID, id)
+		out = append(out, item)
+	}
+	return UpdateRanks(out), nil
+}
 
-	// Found gap somewhere in between (only one at a time)
-	var idxGap int
-	for i := 0; i < len(catalog)-1; i++ {
-		curr, next := catalog[i], catalog[i+1]
-		if math.Abs(float64(curr.Rank)-float64(next.Rank)) > 1 {
-			idxGap = i + 1
+// MoveToRank moves item with itemID to rank shifting the items in between.
+// catalog must be sorted by rank ascending
+func MoveToRank(catalog []CatalogItem, itemID primitive.ObjectID, rank uint) ([]CatalogItem, error) {
+	if int(rank) >= len(catalog) {
+		return nil, ErrInvalidOrder
+	}
+	idx := -1
+	for i, item := range catalog {
+		if item.ItemID == itemID {
+			idx = i
 			break
 		}
 	}
-	// All fine
-	if idxGap == 0 {
-		return catalog
+	if idx == -1 {
+		return nil, ErrItemNotFound
 	}
-	return functools.Map(func(item CatalogItem, i int) CatalogItem {
-		if i >= idxGap {
-			item.Rank--
-			return item
-		}
-		return item
-	}, catalog)
+	moved := catalog[idx]
+	out := make([]CatalogItem, 0, len(catalog))
+	out = append(out, catalog[:idx]...)
+	out = append(out, catalog[idx+1:]...)
+	// insert at rank
+	out = append(out[:rank], append([]CatalogItem{moved}, out[rank:]...)...)
+	return UpdateRanks(out), nil
 }
@@ -2,8 +2,10 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestUpdateRanks(t *testing.T) {
@@ -58,4 +60,148 @@ func TestUpdateRanks(t *testing.T) {
 			require.True(t, item.Rank == uint(i))
 		}
 	})
+
+	t.Run("delete several items. Should repair every gap", func(t *testing.T) {
+		var catalog []CatalogItem
+		for i := 0; i < 100; i++ {
+			catalog = append(catalog, CatalogItem{
+				Rank: uint(i),
+			})
+		}
+		catalog = append(catalog[:10], catalog[11:]...)
+		catalog = append(catalog[:40], catalog[43:]...)
+		catalog = catalog[1:]
+		newCatalog := UpdateRanks(catalog)
+		for i, item := range newCatalog {
+			require.Equal(t, uint(i), item.Rank)
+		}
+	})
+}
+
+func newRankedCatalog(n int) []CatalogItem {
+	var catalog []CatalogItem
+	for i := 0; i < n; i++ {
+		catalog = append(catalog, CatalogItem{
+			ItemID: primitive.NewObjectID(),
+			Rank:   uint(i),
+		})
+	}
+	return catalog
+}
+
+func TestMoveToRank(t *testing.T) {
+	t.Run("move from 30 to 2", func(t *testing.T) {
+		catalog := newRankedCatalog(40)
+		moved := catalog[30]
+		newCatalog, err := MoveToRank(catalog, moved.ItemID, 2)
+		require.NoError(t, err)
+		require.Equal(t, len(catalog), len(newCatalog))
+		require.Equal(t, moved.ItemID, newCatalog[2].ItemID)
+		// items in between are shifted by one
+		require.Equal(t, catalog[2].ItemID, newCatalog[3].ItemID)
+		require.Equal(t, catalog[29].ItemID, newCatalog[30].ItemID)
+		require.Equal(t, catalog[31].ItemID, newCatalog[31].ItemID)
+		for i, item := range newCatalog {
+			require.Equal(t, uint(i), item.Rank)
+		}
+	})
+
+	t.Run("move from 0 to last", func(t *testing.T) {
+		catalog := newRankedCatalog(5)
+		moved := catalog[0]
+		newCatalog, err := MoveToRank(catalog, moved.ItemID, 4)
+		require.NoError(t, err)
+		require.Equal(t, moved.ItemID, newCatalog[4].ItemID)
+		require.Equal(t, catalog[1].ItemID, newCatalog[0].ItemID)
+	})
+
+	t.Run("rank out of range", func(t *testing.T) {
+		catalog := newRankedCatalog(5)
+		_, err := MoveToRank(catalog, catalog[0].ItemID, 5)
+		require.ErrorIs(t, err, ErrInvalidOrder)
+	})
+
+	t.Run("unknown item", func(t *testing.T) {
+		catalog := newRankedCatalog(5)
+		_, err := MoveToRank(catalog, primitive.NewObjectID(), 1)
+		require.ErrorIs(t, err, ErrItemNotFound)
+	})
+}
+
+func TestReorderByIDs(t *testing.T) {
+	t.Run("reverse order", func(t *testing.T) {
+		catalog := newRankedCatalog(10)
+		var ids []primitive.ObjectID
+		for i := len(catalog) - 1; i >= 0; i-- {
+			ids = append(ids, catalog[i].ItemID)
+		}
+		newCatalog, err := ReorderByIDs(catalog, ids)
+		require.NoError(t, err)
+		for i, item := range newCatalog {
+			require.Equal(t, ids[i], item.ItemID)
+			require.Equal(t, uint(i), item.Rank)
+		}
+	})
+
+	t.Run("missing item", func(t *testing.T) {
+		catalog := newRankedCatalog(3)
+		_, err := ReorderByIDs(catalog, []primitive.ObjectID{catalog[0].ItemID, catalog[1].ItemID})
+		require.ErrorIs(t, err, ErrInvalidOrder)
+	})
+
+	t.Run("duplicate item", func(t *testing.T) {
+		catalog := newRankedCatalog(3)
+		_, err := ReorderByIDs(catalog, []primitive.ObjectID{catalog[0].ItemID, catalog[0].ItemID, catalog[1].ItemID})
+		require.ErrorIs(t, err, ErrInvalidOrder)
+	})
+}
+
+func TestIsPublishedAt(t *testing.T) {
+	var (
+		now    = time.Now()
+		past   = now.Add(-time.Hour)
+		future = now.Add(time.Hour)
+	)
+	tests := []struct {
+		description string
+		item        CatalogItem
+		expected    bool
+	}{
+		{"no window", CatalogItem{}, true},
+		{"published in past", CatalogItem{PublishAt: &past}, true},
+		{"published in future", CatalogItem{PublishAt: &future}, false},
+		{"unpublished in past", CatalogItem{UnpublishAt: &past}, false},
+		{"unpublished in future", CatalogItem{UnpublishAt: &future}, true},
+		{"inside window", CatalogItem{PublishAt: &past, UnpublishAt: &future}, true},
+		{"publish bound is inclusive", CatalogItem{PublishAt: &now}, true},
+		{"unpublish bound is exclusive", CatalogItem{UnpublishAt: &now}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			require.Equal(t, test.expected, test.item.IsPublishedAt(now))
+		})
+	}
+}
+
+func TestNextBoundary(t *testing.T) {
+	var (
+		now  = time.Now()
+		past = now.Add(-time.Hour)
+		soon = now.Add(time.Minute)
+		late = now.Add(time.Hour)
+	)
+
+	_, ok := (&CatalogItem{}).NextBoundary(now)
+	require.False(t, ok)
+
+	_, ok = (&CatalogItem{PublishAt: &past}).NextBoundary(now)
+	require.False(t, ok)
+
+	next, ok := (&CatalogItem{PublishAt: &soon, UnpublishAt: &late}).NextBoundary(now)
+	require.True(t, ok)
+	require.Equal(t, soon, next)
+
+	next, ok = (&CatalogItem{PublishAt: &past, UnpublishAt: &late}).NextBoundary(now)
+	require.True(t, ok)
+	require.Equal(t, late, next)
 }
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCatalogStateNotFound = errors.New("catalog state not found")
+
+// CatalogState survives restarts of catalog scheduler
+type CatalogState struct {
+	// Drops published up to this moment are announced
+	LastRefresh time.Time `json:"lastRefresh" bson:"lastRefresh"`
+}
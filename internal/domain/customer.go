@@ -4,6 +4,7 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -24,15 +25,30 @@ var (
 	StateWaitingForFIO                 = State{11}
 	StateWaitingForPhoneNumber         = State{12}
 	StateWaitingForDeliveryAddress     = State{13}
+	StateWaitingForProfileAddress      = State{14}
+	StateWaitingForAddressCountry      = State{15}
+	StateWaitingForAddressRegion       = State{16}
+	StateWaitingForAddressCity         = State{17}
+	StateWaitingForAddressStreet       = State{18}
+	StateWaitingForAddressHouse        = State{19}
+	StateWaitingForPickupProvider      = State{20}
+	StateWaitingForPickupPointCode     = State{21}
 )
 
 var (
 	ErrCustomerNotFound = errors.New("customer not found")
 	ErrNoCustomers      = errors.New("no customers found")
+	ErrInvalidPositionN = errors.New("invalid cart position number")
 )
 
 type Meta struct {
 	NextOrderType *OrderType `json:"nextOrderType" bson:"nextOrderType"`
+	// 1-based number of cart position being edited, 0 if none
+	EditPositionN uint `json:"editPositionN" bson:"editPositionN"`
+	// Full name or phone number is changed from profile, not while making order
+	EditingProfile bool `json:"editingProfile" bson:"editingProfile"`
+	// Address being typed in step by step, Details are nil if none
+	PendingAddress *Address `json:"pendingAddress,omitempty" bson:"pendingAddress,omitempty"`
 }
 
 type CalculatorMeta struct {
@@ -52,6 +68,24 @@ type Customer struct {
 	CalculatorMeta   CalculatorMeta     `json:"calculatorMeta" bson:"calculatorMeta"`
 	CatalogOffset    uint               `json:"catalogOffset" bson:"catalogOffset"`
 	LastEditPosition *Position          `json:"lastEditPosition,omitempty" bson:"lastEditPosition"`
+	// Customer gets notified when catalog drop is published
+	SubscribedToDrops bool            `json:"subscribedToDrops" bson:"subscribedToDrops"`
+	Favourites        []FavouriteItem `json:"favourites" bson:"favourites"`
+	// Updated on every update from customer
+	LastActivityAt *time.Time `json:"lastActivityAt,omitempty" bson:"lastActivityAt,omitempty"`
+	// Set when telegram refuses delivery, reset once customer writes again
+	BlockedBot    bool          `json:"blockedBot" bson:"blockedBot"`
+	CartReminders CartReminders `json:"cartReminders" bson:"cartReminders"`
+	// Address book, see MaxAddresses
+	Addresses []Address `json:"addresses" bson:"addresses"`
+	// Language of bot texts. Taken from Telegram app on start and changed in menu, empty for older customers
+	Language string `json:"language,omitempty" bson:"language,omitempty"`
+}
+
+type CartReminders struct {
+	// Reminders sent since last activity
+	Sent     uint `json:"sent" bson:"sent"`
+	OptedOut bool `json:"optedOut" bson:"optedOut"`
 }
 
 func NewCustomer(telegramID int64, username string) Customer {
@@ -89,6 +123,22 @@ func (c *Customer) UpdateLastEditPositionPrice(priceRub uint64, priceYuan uint64
 	c.LastEditPosition.PriceYUAN = priceYuan
 }
 
+func (c *Customer) UpdateLastEditPositionOrderType(typ OrderType) {
+	c.LastEditPosition.OrderType = typ
+}
+
+// PositionOrderType is delivery type for position being added or edited.
+// Edited position keeps its own type
+func (c *Customer) PositionOrderType() (OrderType, bool) {
+	if c.IsEditingPosition() && c.LastEditPosition != nil && c.LastEditPosition.OrderType != 0 {
+		return c.LastEditPosition.OrderType, true
+	}
+	if c.Meta.NextOrderType == nil {
+		return 0, false
+	}
+	return *c.Meta.NextOrderType, true
+}
+
 func (c *Customer) UpdateLastEditPositionLink(link string) {
 	c.LastEditPosition.ShopLink = link
 }
@@ -97,6 +147,32 @@ func (c *Customer) UpdateLastEditPositionButtonColor(button Button) {
 	c.LastEditPosition.Button = button
 }
 
+// StartPositionEdit copies n-th (1-based) cart position to LastEditPosition
+// so existing input handlers can change it
+func (c *Customer) StartPositionEdit(n uint) error {
+	if n == 0 || int(n) > len(c.Cart) {
+		return ErrInvalidPositionN
+	}
+	c.SetLastEditPosition(c.Cart[n-1])
+	c.Meta.EditPositionN = n
+	return nil
+}
+
+func (c *Customer) IsEditingPosition() bool {
+	return c.Meta.EditPositionN != 0
+}
+
+// FinishPositionEdit writes LastEditPosition back to the cart
+func (c *Customer) FinishPositionEdit() (n uint, err error) {
+	n = c.Meta.EditPositionN
+	c.Meta.EditPositionN = 0
+	if n == 0 || int(n) > len(c.Cart) || c.LastEditPosition == nil {
+		return 0, ErrInvalidPositionN
+	}
+	c.Cart[n-1] = *c.LastEditPosition
+	return n, nil
+}
+
 func (c *Customer) UpdateMetaOrderType(typ OrderType) {
 	c.Meta.NextOrderType = &typ
 }
@@ -108,6 +184,31 @@ func (c *Customer) UpdateCalculatorMetaOrderType(typ OrderType) {
 	c.CalculatorMeta.NextOrderType = &typ
 }
 
+func (c *Customer) ToggleDropsSubscription() {
+	c.SubscribedToDrops = !c.SubscribedToDrops
+}
+
+// AddFavourite returns false if item is already in favourites
+func (c *Customer) AddFavourite(item CatalogItem) bool {
+	for _, f := range c.Favourites {
+		if f.ItemID == item.ItemID {
+			return false
+		}
+	}
+	c.Favourites = append(c.Favourites, NewFavouriteItem(item))
+	return true
+}
+
+func (c *Customer) RemoveFavourite(itemID primitive.ObjectID) bool {
+	for i, f := range c.Favourites {
+		if f.ItemID == itemID {
+			c.Favourites = append(c.Favourites[:i], c.Favourites[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Customer) IncrementCatalogOffset() {
 	c.CatalogOffset++
 }
@@ -135,6 +236,7 @@ func IsValidFullName(fullName string) bool {
 
 var r = regexp.MustCompile(`^(8|7)((\d{10})|(\s\(\d{3}\)\s\d{3}\s\d{2}\s\d{2}))`)
 
+// Deprecated: accepts only 11 digits without separators, use NormalizePhoneNumber
 func IsValidPhoneNumber(phoneNumber string) bool {
 	if len(phoneNumber) != 11 {
 		return false
@@ -66,3 +66,53 @@ func TestIsValidPhoneNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestPositionEdit(t *testing.T) {
+	customer := Customer{
+		Cart: Cart{
+			{ShopLink: "https://dw4.co/t/A/1", Size: "L", Button: ButtonGrey, PriceYUAN: 100, PriceRUB: 2000},
+			{ShopLink: "https://dw4.co/t/A/2", Size: "42", Button: ButtonTorqoise, PriceYUAN: 300, PriceRUB: 5000},
+		},
+	}
+
+	require.ErrorIs(t, customer.StartPositionEdit(0), ErrInvalidPositionN)
+	require.ErrorIs(t, customer.StartPositionEdit(3), ErrInvalidPositionN)
+	require.False(t, customer.IsEditingPosition())
+
+	require.NoError(t, customer.StartPositionEdit(2))
+	require.True(t, customer.IsEditingPosition())
+	require.Equal(t, customer.Cart[1], *customer.LastEditPosition)
+
+	customer.UpdateLastEditPositionSize("43")
+	// Cart is untouched until edit is finished
+	require.Equal(t, "42", customer.Cart[1].Size)
+
+	n, err := customer.FinishPositionEdit()
+	require.NoError(t, err)
+	require.Equal(t, uint(2), n)
+	require.False(t, customer.IsEditingPosition())
+	require.Equal(t, "43", customer.Cart[1].Size)
+	require.Equal(t, uint64(5000), customer.Cart[1].PriceRUB)
+	require.Equal(t, "L", customer.Cart[0].Size)
+
+	_, err = customer.FinishPositionEdit()
+	require.ErrorIs(t, err, ErrInvalidPositionN)
+}
+
+func TestPositionOrderType(t *testing.T) {
+	var customer Customer
+	_, ok := customer.PositionOrderType()
+	require.False(t, ok)
+
+	customer.UpdateMetaOrderType(OrderTypeNormal)
+	typ, ok := customer.PositionOrderType()
+	require.True(t, ok)
+	require.Equal(t, OrderTypeNormal, typ)
+
+	// Edited position keeps its own delivery type
+	customer.Cart = Cart{{ShopLink: "a", OrderType: OrderTypeExpress}}
+	require.NoError(t, customer.StartPositionEdit(1))
+	typ, ok = customer.PositionOrderType()
+	require.True(t, ok)
+	require.Equal(t, OrderTypeExpress, typ)
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type PickupProvider int
+
+const (
+	PickupProviderCDEK PickupProvider = iota + 1
+	PickupProviderPickPoint
+)
+
+var PickupProviderTexts = map[PickupProvider]string{
+	PickupProviderCDEK:      "СДЭК",
+	PickupProviderPickPoint: "PickPoint",
+}
+
+func (p PickupProvider) IsValid() bool {
+	_, ok := PickupProviderTexts[p]
+	return ok
+}
+
+func (p PickupProvider) String() string {
+	return PickupProviderTexts[p]
+}
+
+var (
+	ErrInvalidAddress       = errors.New("invalid address")
+	ErrPickupPointNotFound  = errors.New("pickup point not found")
+	ErrPickupPointWrongCity = errors.New("pickup point is in another city")
+)
+
+const (
+	maxAddressFieldLen    = 128
+	maxPickupPointCodeLen = 32
+	// Allowed in pickup point code besides letters and digits
+	pickupPointCodeExtraRunes = "-_"
+)
+
+// AddressField is a step of structured address input
+type AddressField int
+
+const (
+	AddressFieldCountry AddressField = iota + 1
+	AddressFieldRegion
+	AddressFieldCity
+	AddressFieldStreet
+	AddressFieldHouse
+	AddressFieldPickupPointCode
+)
+
+// StructuredAddress is delivery address with pickup point it's shipped to
+type StructuredAddress struct {
+	Country         string         `json:"country" bson:"country"`
+	Region          string         `json:"region" bson:"region"`
+	City            string         `json:"city" bson:"city"`
+	Street          string         `json:"street" bson:"street"`
+	House           string         `json:"house" bson:"house"`
+	Provider        PickupProvider `json:"provider" bson:"provider"`
+	PickupPointCode string         `json:"pickupPointCode" bson:"pickupPointCode"`
+}
+
+// PickupPoint is CDEK or PickPoint office, see PickupPointDirectory
+type PickupPoint struct {
+	Provider PickupProvider `json:"provider"`
+	Code     string         `json:"code"`
+	City     string         `json:"city"`
+	Address  string         `json:"address"`
+}
+
+// Set validates and sets single field
+func (a *StructuredAddress) Set(field AddressField, value string) error {
+	value = strings.TrimSpace(value)
+	if err := validateAddressField(field, value); err != nil {
+		return err
+	}
+	switch field {
+	case AddressFieldCountry:
+		a.Country = value
+	case AddressFieldRegion:
+		a.Region = value
+	case AddressFieldCity:
+		a.City = value
+	case AddressFieldStreet:
+		a.Street = value
+	case AddressFieldHouse:
+		a.House = value
+	case AddressFieldPickupPointCode:
+		a.PickupPointCode = strings.ToUpper(value)
+	}
+	return nil
+}
+
+// Get returns value of single field, empty if it's not set yet
+func (a StructuredAddress) Get(field AddressField) string {
+	switch field {
+	case AddressFieldCountry:
+		return a.Country
+	case AddressFieldRegion:
+		return a.Region
+	case AddressFieldCity:
+		return a.City
+	case AddressFieldStreet:
+		return a.Street
+	case AddressFieldHouse:
+		return a.House
+	case AddressFieldPickupPointCode:
+		return a.PickupPointCode
+	default:
+		return ""
+	}
+}
+
+// SetPickupPoint checks that point is in the same city as address
+func (a *StructuredAddress) SetPickupPoint(p PickupPoint) error {
+	if !strings.EqualFold(strings.TrimSpace(p.City), a.City) {
+		return ErrPickupPointWrongCity
+	}
+	a.Provider = p.Provider
+	a.PickupPointCode = p.Code
+	return nil
+}
+
+func (a StructuredAddress) Validate() error {
+	fields := []struct {
+		field AddressField
+		value string
+	}{
+		{AddressFieldCountry, a.Country},
+		{AddressFieldRegion, a.Region},
+		{AddressFieldCity, a.City},
+		{AddressFieldStreet, a.Street},
+		{AddressFieldHouse, a.House},
+		{AddressFieldPickupPointCode, a.PickupPointCode},
+	}
+	for _, f := range fields {
+		if err := validateAddressField(f.field, f.value); err != nil {
+			return err
+		}
+	}
+	if !a.Provider.IsValid() {
+		return fmt.Errorf("%w: unknown pickup provider %d", ErrInvalidAddress, a.Provider)
+	}
+	return nil
+}
+
+func (a StructuredAddress) String() string {
+	return fmt.Sprintf("%s, %s, %s, %s, %s (%s %s)",
+		a.Country, a.Region, a.City, a.Street, a.House, a.Provider, a.PickupPointCode)
+}
+
+func validateAddressField(field AddressField, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: field %d is empty", ErrInvalidAddress, field)
+	}
+	switch field {
+	case AddressFieldHouse:
+		if strings.IndexFunc(value, unicode.IsDigit) < 0 {
+			return fmt.Errorf("%w: house must contain number", ErrInvalidAddress)
+		}
+	case AddressFieldPickupPointCode:
+		if utf8.RuneCountInString(value) > maxPickupPointCodeLen {
+			return fmt.Errorf("%w: pickup point code is too long", ErrInvalidAddress)
+		}
+		for _, r := range value {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(pickupPointCodeExtraRunes, r) {
+				return fmt.Errorf("%w: pickup point code contains %q", ErrInvalidAddress, r)
+			}
+		}
+		return nil
+	}
+	if utf8.RuneCountInString(value) > maxAddressFieldLen {
+		return fmt.Errorf("%w: field %d is too long", ErrInvalidAddress, field)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredAddressSet(t *testing.T) {
+	tests := []struct {
+		description string
+		field       AddressField
+		value       string
+		wantErr     bool
+	}{
+		{description: "country", field: AddressFieldCountry, value: " Россия "},
+		{description: "empty city", field: AddressFieldCity, value: "  ", wantErr: true},
+		{description: "house with number", field: AddressFieldHouse, value: "12к1"},
+		{description: "house without number", field: AddressFieldHouse, value: "двенадцать", wantErr: true},
+		{description: "pickup point code", field: AddressFieldPickupPointCode, value: "msk-123"},
+		{description: "pickup point code with spaces", field: AddressFieldPickupPointCode, value: "MSK 123", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var a StructuredAddress
+			err := a.Set(tc.field, tc.value)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrInvalidAddress)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	var a StructuredAddress
+	require.NoError(t, a.Set(AddressFieldCountry, " Россия "))
+	require.NoError(t, a.Set(AddressFieldPickupPointCode, "msk123"))
+	require.Equal(t, "Россия", a.Country)
+	require.Equal(t, "MSK123", a.PickupPointCode)
+	require.Equal(t, "Россия", a.Get(AddressFieldCountry))
+	require.Empty(t, a.Get(AddressFieldCity))
+}
+
+func TestStructuredAddressValidate(t *testing.T) {
+	a := StructuredAddress{
+		Country:         "Россия",
+		Region:          "Московская область",
+		City:            "Москва",
+		Street:          "Тверская",
+		House:           "1",
+		PickupPointCode: "MSK123",
+	}
+	// Provider is not selected
+	require.ErrorIs(t, a.Validate(), ErrInvalidAddress)
+
+	require.ErrorIs(t, a.SetPickupPoint(PickupPoint{Provider: PickupProviderCDEK, Code: "SPB45", City: "Санкт-Петербург"}), ErrPickupPointWrongCity)
+	require.NoError(t, a.SetPickupPoint(PickupPoint{Provider: PickupProviderCDEK, Code: "MSK123", City: "москва"}))
+	require.NoError(t, a.Validate())
+	require.Equal(t, "Россия, Московская область, Москва, Тверская, 1 (СДЭК MSK123)", a.String())
+
+	a.Street = ""
+	require.ErrorIs(t, a.Validate(), ErrInvalidAddress)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// FavouriteItem is catalog item saved by customer.
+// Keeps price and stock seen last time in order to detect changes
+type FavouriteItem struct {
+	ItemID   primitive.ObjectID `json:"itemId" bson:"itemId"`
+	PriceRUB uint64             `json:"priceRub" bson:"priceRub"`
+	InStock  bool               `json:"inStock" bson:"inStock"`
+}
+
+func NewFavouriteItem(item CatalogItem) FavouriteItem {
+	return FavouriteItem{
+		ItemID:   item.ItemID,
+		PriceRUB: item.PriceRUB,
+		InStock:  item.Quantity > 0,
+	}
+}
+
+type FavouriteChange struct {
+	Item         CatalogItem
+	OldPriceRUB  uint64
+	PriceDropped bool
+	BackInStock  bool
+}
+
+// DetectChange compares saved state of favourite with current item
+func (f FavouriteItem) DetectChange(item CatalogItem) (FavouriteChange, bool) {
+	change := FavouriteChange{
+		Item:         item,
+		OldPriceRUB:  f.PriceRUB,
+		PriceDropped: item.PriceRUB < f.PriceRUB,
+		BackInStock:  !f.InStock && item.Quantity > 0,
+	}
+	return change, change.PriceDropped || change.BackInStock
+}
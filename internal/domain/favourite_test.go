@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAddRemoveFavourite(t *testing.T) {
+	var (
+		customer Customer
+		item     = CatalogItem{ItemID: primitive.NewObjectID(), PriceRUB: 1000, Quantity: 2}
+	)
+
+	require.True(t, customer.AddFavourite(item))
+	require.False(t, customer.AddFavourite(item))
+	require.Len(t, customer.Favourites, 1)
+	require.Equal(t, FavouriteItem{ItemID: item.ItemID, PriceRUB: 1000, InStock: true}, customer.Favourites[0])
+
+	require.False(t, customer.RemoveFavourite(primitive.NewObjectID()))
+	require.True(t, customer.RemoveFavourite(item.ItemID))
+	require.Empty(t, customer.Favourites)
+}
+
+func TestDetectFavouriteChange(t *testing.T) {
+	itemID := primitive.NewObjectID()
+	tests := []struct {
+		description  string
+		saved        FavouriteItem
+		current      CatalogItem
+		changed      bool
+		priceDropped bool
+		backInStock  bool
+	}{
+		{
+			description: "nothing changed",
+			saved:       FavouriteItem{ItemID: itemID, PriceRUB: 1000, InStock: true},
+			current:     CatalogItem{ItemID: itemID, PriceRUB: 1000, Quantity: 1},
+		},
+		{
+			description: "price went up",
+			saved:       FavouriteItem{ItemID: itemID, PriceRUB: 1000, InStock: true},
+			current:     CatalogItem{ItemID: itemID, PriceRUB: 1200, Quantity: 1},
+		},
+		{
+			description: "went out of stock",
+			saved:       FavouriteItem{ItemID: itemID, PriceRUB: 1000, InStock: true},
+			current:     CatalogItem{ItemID: itemID, PriceRUB: 1000, Quantity: 0},
+		},
+		{
+			description:  "price dropped",
+			saved:        FavouriteItem{ItemID: itemID, PriceRUB: 1000, InStock: true},
+			current:      CatalogItem{ItemID: itemID, PriceRUB: 800, Quantity: 1},
+			changed:      true,
+			priceDropped: true,
+		},
+		{
+			description: "back in stock",
+			saved:       FavouriteItem{ItemID: itemID, PriceRUB: 1000, InStock: false},
+			current:     CatalogItem{ItemID: itemID, PriceRUB: 1000, Quantity: 3},
+			changed:     true,
+			backInStock: true,
+		},
+		{
+			description:  "back in stock and cheaper",
+			saved:        FavouriteItem{ItemID: itemID, PriceRUB: 1000, InStock: false},
+			current:      CatalogItem{ItemID: itemID, PriceRUB: 900, Quantity: 3},
+			changed:      true,
+			priceDropped: true,
+			backInStock:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			change, changed := tc.saved.DetectChange(tc.current)
+			require.Equal(t, tc.changed, changed)
+			require.Equal(t, tc.priceDropped, change.PriceDropped)
+			require.Equal(t, tc.backInStock, change.BackInStock)
+			require.Equal(t, tc.saved.PriceRUB, change.OldPriceRUB)
+		})
+	}
+}
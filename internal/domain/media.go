@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+var ErrMediaNotFound = errors.New("media not found")
+
+// MediaFile binds public url of media to file id telegram assigned to it after upload
+type MediaFile struct {
+	URL    string `json:"url" bson:"url"`
+	FileID string `json:"fileId" bson:"fileId"`
+}
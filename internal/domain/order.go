@@ -39,9 +39,27 @@ var StatusTexts = map[Status]string{
 	StatusGotToOrdererCity: "Пришел в город назначения",
 }
 
+// Order is delivered, nothing else happens to it
+var CompletedStatuses = []Status{StatusGotToOrdererCity}
+
+// OrdersFilter selects orders shown to customer
+type OrdersFilter int
+
+const (
+	OrdersFilterAll OrdersFilter = iota
+	OrdersFilterActive
+	OrdersFilterCompleted
+)
+
+func (f OrdersFilter) IsValid() bool {
+	return f >= OrdersFilterAll && f <= OrdersFilterCompleted
+}
+
 var (
 	ErrOrderNotFound = errors.New("order not found")
 	ErrNoOrders      = errors.New("no orders")
+	// Number of short ids does not match number of orders
+	ErrInvalidShortIDs = errors.New("invalid short ids")
 )
 
 type Order struct {
@@ -57,6 +75,10 @@ type Order struct {
 	IsApproved      bool               `json:"isApproved" bson:"isApproved"`
 	IsExpress       bool               `json:"isExpress" bson:"isExpress"`
 	Status          Status             `json:"status" bson:"status"`
+	// Orders placed at the same checkout but with other delivery type
+	LinkedShortIDs []string `json:"linkedShortIds,omitempty" bson:"linkedShortIds,omitempty"`
+	// Nil for orders placed with free text address
+	Address *StructuredAddress `json:"address,omitempty" bson:"address,omitempty"`
 }
 
 func NewOrder(customer Customer, deliveryAddress string, isExpress bool, shortID string) Order {
@@ -65,8 +87,8 @@ func NewOrder(customer Customer, deliveryAddress string, isExpress bool, shortID
 	}
 
 	totals := functools.Reduce(func(t total, cartItem Position) total {
-		t.yuan += cartItem.PriceYUAN
-		t.rub += cartItem.PriceRUB
+		t.yuan += cartItem.TotalYUAN()
+		t.rub += cartItem.TotalRUB()
 		return t
 	}, customer.Cart, total{})
 
@@ -84,6 +106,44 @@ func NewOrder(customer Customer, deliveryAddress string, isExpress bool, shortID
 	}
 }
 
+// NewLinkedOrders places separate order for each delivery type in customer's cart.
+// shortIDs must have an id per order, see Cart.SplitByOrderType
+func NewLinkedOrders(customer Customer, deliveryAddress string, fallback OrderType, shortIDs []string) ([]Order, error) {
+	groups := customer.Cart.SplitByOrderType(fallback)
+	if len(shortIDs) != len(groups) {
+		return nil, ErrInvalidShortIDs
+	}
+
+	orders := make([]Order, 0, len(groups))
+	for i, cart := range groups {
+		c := customer
+		c.Cart = cart
+		isExpress := cart[0].OrderType == OrderTypeExpress
+		orders = append(orders, NewOrder(c, deliveryAddress, isExpress, shortIDs[i]))
+	}
+
+	if len(orders) < 2 {
+		return orders, nil
+	}
+	for i := range orders {
+		for j, other := range orders {
+			if i != j {
+				orders[i].LinkedShortIDs = append(orders[i].LinkedShortIDs, other.ShortID)
+			}
+		}
+	}
+	return orders, nil
+}
+
+func (o Order) IsCompleted() bool {
+	for _, s := range CompletedStatuses {
+		if o.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
 func IsValidOrderStatus(s Status) bool {
 	_, ok := StatusTexts[s]
 	return ok
@@ -136,3 +196,43 @@ type ConvertYuanArgs struct {
 func ConvertYuan(args ConvertYuanArgs) (rub uint64) {
 	return formulas[args.OrderType][args.Category](args.X, args.Rate)
 }
+
+// PriceChange is difference in price of repeated position
+type PriceChange struct {
+	// 1-based position number in repeated cart, Cart.AddRepeated numbers it within cart it adds to
+	N           int
+	Position    Position
+	OldPriceRUB uint64
+}
+
+// RepeatCart copies order positions re-priced with current rate.
+// Returns positions whose price has changed
+func (o Order) RepeatCart(rate float64) (Cart, []PriceChange) {
+	var (
+		cart    = make(Cart, 0, len(o.Cart))
+		changes []PriceChange
+	)
+	for i, p := range o.Cart {
+		p.PositionID = primitive.NewObjectID()
+		if p.OrderType == 0 {
+			p.OrderType = o.orderType()
+		}
+
+		if f, ok := formulas[p.OrderType][p.Category]; ok {
+			oldPriceRUB := p.PriceRUB
+			p.PriceRUB = f(p.PriceYUAN, rate)
+			if p.PriceRUB != oldPriceRUB {
+				changes = append(changes, PriceChange{N: i + 1, Position: p, OldPriceRUB: oldPriceRUB})
+			}
+		}
+		cart = append(cart, p)
+	}
+	return cart, changes
+}
+
+func (o Order) orderType() OrderType {
+	if o.IsExpress {
+		return OrderTypeExpress
+	}
+	return OrderTypeNormal
+}
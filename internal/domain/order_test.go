@@ -35,6 +35,11 @@ func TestNewOrder(t *testing.T) {
 		},
 	}
 
+	// First position is taken 3 times
+	customer2 := customer1
+	customer2.Cart = Cart{customer1.Cart[0], customer1.Cart[1]}
+	customer2.Cart[0].Quantity = 3
+
 	tests := []struct {
 		description     string
 		customer        Customer
@@ -71,6 +76,21 @@ func TestNewOrder(t *testing.T) {
 				Status:          StatusNotApproved,
 			},
 		},
+		{
+			description:     "test with position quantity",
+			customer:        customer2,
+			deliveryAddress: "123 Main St., Anytown, USA",
+			expectedOrder: Order{
+				Customer:        customer2,
+				Cart:            customer2.Cart,
+				AmountRUB:       500,
+				AmountYUAN:      50,
+				DeliveryAddress: "123 Main St., Anytown, USA",
+				IsPaid:          false,
+				IsApproved:      false,
+				Status:          StatusNotApproved,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -86,3 +106,85 @@ func TestNewOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestNewLinkedOrders(t *testing.T) {
+	customer := Customer{
+		FullName:    stringPtr("John Doe"),
+		PhoneNumber: stringPtr("123456789"),
+		Cart: Cart{
+			{ShopLink: "a", PriceRUB: 100, PriceYUAN: 10, OrderType: OrderTypeExpress},
+			{ShopLink: "b", PriceRUB: 200, PriceYUAN: 20, OrderType: OrderTypeNormal, Quantity: 2},
+			{ShopLink: "c", PriceRUB: 300, PriceYUAN: 30, OrderType: OrderTypeExpress},
+		},
+	}
+
+	_, err := NewLinkedOrders(customer, "Moscow", OrderTypeNormal, []string{"A"})
+	require.ErrorIs(t, err, ErrInvalidShortIDs)
+
+	orders, err := NewLinkedOrders(customer, "Moscow", OrderTypeNormal, []string{"A", "B"})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	require.Equal(t, "A", orders[0].ShortID)
+	require.True(t, orders[0].IsExpress)
+	require.Len(t, orders[0].Cart, 2)
+	require.Equal(t, uint64(400), orders[0].AmountRUB)
+	require.Equal(t, []string{"B"}, orders[0].LinkedShortIDs)
+
+	require.Equal(t, "B", orders[1].ShortID)
+	require.False(t, orders[1].IsExpress)
+	require.Equal(t, uint64(400), orders[1].AmountRUB)
+	require.Equal(t, uint64(40), orders[1].AmountYUAN)
+	require.Equal(t, []string{"A"}, orders[1].LinkedShortIDs)
+
+	// Single delivery type is not linked
+	customer.Cart = customer.Cart[:1]
+	orders, err = NewLinkedOrders(customer, "Moscow", OrderTypeNormal, []string{"A"})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	require.Nil(t, orders[0].LinkedShortIDs)
+}
+
+func TestRepeatCart(t *testing.T) {
+	const rate = 12.5
+
+	order := Order{
+		IsExpress: true,
+		Cart: Cart{
+			// Priced with other rate
+			{ShopLink: "a", PriceYUAN: 100, PriceRUB: 1, Category: CategoryLight, OrderType: OrderTypeNormal, Quantity: 2},
+			// Legacy position gets order's delivery type
+			{ShopLink: "b", PriceYUAN: 200, Category: CategoryHeavy},
+			// Unknown category keeps its price
+			{ShopLink: "c", PriceYUAN: 300, PriceRUB: 5000},
+		},
+	}
+	order.Cart[1].PriceRUB = ConvertYuan(ConvertYuanArgs{X: 200, Rate: rate, OrderType: OrderTypeExpress, Category: CategoryHeavy})
+
+	cart, changes := order.RepeatCart(rate)
+	require.Len(t, cart, 3)
+
+	expectedA := ConvertYuan(ConvertYuanArgs{X: 100, Rate: rate, OrderType: OrderTypeNormal, Category: CategoryLight})
+	require.Equal(t, expectedA, cart[0].PriceRUB)
+	require.Equal(t, uint(2), cart[0].Quantity)
+	require.Equal(t, OrderTypeExpress, cart[1].OrderType)
+	require.Equal(t, uint64(5000), cart[2].PriceRUB)
+
+	require.Len(t, changes, 1)
+	require.Equal(t, 1, changes[0].N)
+	require.Equal(t, uint64(1), changes[0].OldPriceRUB)
+	require.Equal(t, expectedA, changes[0].Position.PriceRUB)
+
+	// Positions are copies
+	require.False(t, cart[0].PositionID.IsZero())
+	require.Equal(t, uint64(1), order.Cart[0].PriceRUB)
+
+	t.Run("changes are numbered as in cart", func(t *testing.T) {
+		cart := Cart{{ShopLink: "x", PriceRUB: 100}, {ShopLink: "y", PriceRUB: 200}}
+		changes := cart.AddRepeated(order, rate)
+		require.Len(t, cart, 5)
+		require.Len(t, changes, 1)
+		require.Equal(t, 3, changes[0].N)
+		require.Equal(t, "a", cart[changes[0].N-1].ShopLink)
+	})
+}
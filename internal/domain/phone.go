@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// Dropped while normalising phone number
+const phoneNumberSeparators = " -()."
+
+// NormalizePhoneNumber accepts common russian formats, e.g. 8 (926) 123-45-67, +7 926 1234567 or 9261234567,
+// and returns number in E.164: +79261234567
+func NormalizePhoneNumber(phoneNumber string) (string, error) {
+	phoneNumber = strings.TrimSpace(phoneNumber)
+	hasPlus := strings.HasPrefix(phoneNumber, "+")
+
+	digits := make([]byte, 0, 11)
+	for i, r := range phoneNumber {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == '+' && i == 0:
+		case strings.ContainsRune(phoneNumberSeparators, r):
+		default:
+			return "", ErrInvalidPhoneNumber
+		}
+	}
+
+	switch {
+	case len(digits) == 11 && digits[0] == '7':
+	case len(digits) == 11 && digits[0] == '8' && !hasPlus:
+		digits[0] = '7'
+	case len(digits) == 10 && digits[0] == '9' && !hasPlus:
+		digits = append([]byte{'7'}, digits...)
+	default:
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return "+" + string(digits), nil
+}
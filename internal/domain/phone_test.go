@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		description string
+		phoneNumber string
+		expected    string
+		wantErr     bool
+	}{
+		{description: "11 digits starting with 7", phoneNumber: "79261234567", expected: "+79261234567"},
+		{description: "11 digits starting with 8", phoneNumber: "89261234567", expected: "+79261234567"},
+		{description: "e.164", phoneNumber: "+79261234567", expected: "+79261234567"},
+		{description: "spaces and dashes", phoneNumber: " 8 926-123-45-67 ", expected: "+79261234567"},
+		{description: "parentheses", phoneNumber: "+7 (926) 123-45-67", expected: "+79261234567"},
+		{description: "without country code", phoneNumber: "926 123 45 67", expected: "+79261234567"},
+		{description: "+8 is not russian", phoneNumber: "+89261234567", wantErr: true},
+		{description: "less than 10 digits", phoneNumber: "926123456", wantErr: true},
+		{description: "more than 11 digits", phoneNumber: "792612345678", wantErr: true},
+		{description: "starts with 6", phoneNumber: "69261234567", wantErr: true},
+		{description: "letters", phoneNumber: "8a261234567", wantErr: true},
+		{description: "plus in the middle", phoneNumber: "7926+1234567", wantErr: true},
+		{description: "empty", phoneNumber: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			actual, err := NormalizePhoneNumber(tc.phoneNumber)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrInvalidPhoneNumber)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
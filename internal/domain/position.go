@@ -26,4 +26,25 @@ type Position struct {
 	Button     Button             `json:"button" bson:"button"`
 	Size       string             `json:"size" bson:"size"`
 	Category   Category           `json:"category" bson:"category"`
+	// Prices are per unit. Positions saved before quantity support have 0, see GetQuantity
+	Quantity uint `json:"quantity" bson:"quantity"`
+	// Delivery type. Positions saved before per-position delivery have 0
+	OrderType OrderType `json:"orderType" bson:"orderType"`
+}
+
+const MaxPositionQuantity = 20
+
+func (p Position) GetQuantity() uint {
+	if p.Quantity == 0 {
+		return 1
+	}
+	return p.Quantity
+}
+
+func (p Position) TotalRUB() uint64 {
+	return p.PriceRUB * uint64(p.GetQuantity())
+}
+
+func (p Position) TotalYUAN() uint64 {
+	return p.PriceYUAN * uint64(p.GetQuantity())
 }
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type broadcastRepo struct {
+	broadcasts *mongo.Collection
+}
+
+func NewBroadcastRepo(broadcasts *mongo.Collection) *broadcastRepo {
+	return &broadcastRepo{
+		broadcasts: broadcasts,
+	}
+}
+
+func (b *broadcastRepo) Save(ctx context.Context, broadcast domain.Broadcast) (domain.Broadcast, error) {
+	res, err := b.broadcasts.InsertOne(ctx, broadcast)
+	if err != nil {
+		return domain.Broadcast{}, err
+	}
+	broadcast.BroadcastID = res.InsertedID.(primitive.ObjectID)
+	return broadcast, nil
+}
+
+func (b *broadcastRepo) GetByID(ctx context.Context, broadcastID primitive.ObjectID) (domain.Broadcast, error) {
+	res := b.broadcasts.FindOne(ctx, bson.M{"_id": broadcastID})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Broadcast{}, domain.ErrBroadcastNotFound
+		}
+		return domain.Broadcast{}, err
+	}
+	var broadcast domain.Broadcast
+	if err := res.Decode(&broadcast); err != nil {
+		return domain.Broadcast{}, err
+	}
+	return broadcast, nil
+}
+
+func (b *broadcastRepo) GetAll(ctx context.Context) ([]domain.Broadcast, error) {
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	return b.find(ctx, bson.M{}, opts)
+}
+
+func (b *broadcastRepo) GetDue(ctx context.Context, now time.Time) ([]domain.Broadcast, error) {
+	filter := bson.M{
+		"status":      domain.BroadcastPending,
+		"scheduledAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.M{"scheduledAt": 1})
+	return b.find(ctx, filter, opts)
+}
+
+func (b *broadcastRepo) GetSending(ctx context.Context) ([]domain.Broadcast, error) {
+	opts := options.Find().SetSort(bson.M{"scheduledAt": 1})
+	return b.find(ctx, bson.M{"status": domain.BroadcastSending}, opts)
+}
+
+func (b *broadcastRepo) Start(ctx context.Context, broadcastID primitive.ObjectID) error {
+	return b.transit(ctx, broadcastID, domain.BroadcastPending, bson.M{"status": domain.BroadcastSending})
+}
+
+func (b *broadcastRepo) UpdateProgress(ctx context.Context, broadcastID primitive.ObjectID, stats domain.BroadcastStats, cursor *primitive.ObjectID) error {
+	set := bson.M{"stats": stats}
+	if cursor != nil {
+		set["cursor"] = *cursor
+	}
+	_, err := b.broadcasts.UpdateByID(ctx, broadcastID, bson.M{"$set": set})
+	return err
+}
+
+func (b *broadcastRepo) Finish(ctx context.Context, broadcastID primitive.ObjectID, stats domain.BroadcastStats, at time.Time) error {
+	return b.transit(ctx, broadcastID, domain.BroadcastSending, bson.M{
+		"status":     domain.BroadcastDone,
+		"stats":      stats,
+		"finishedAt": at,
+	})
+}
+
+func (b *broadcastRepo) Cancel(ctx context.Context, broadcastID primitive.ObjectID) error {
+	return b.transit(ctx, broadcastID, domain.BroadcastPending, bson.M{"status": domain.BroadcastCancelled})
+}
+
+// transit applies update only if broadcast is in from status
+func (b *broadcastRepo) transit(ctx context.Context, broadcastID primitive.ObjectID, from domain.BroadcastStatus, set bson.M) error {
+	res, err := b.broadcasts.UpdateOne(ctx, bson.M{"_id": broadcastID, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := b.GetByID(ctx, broadcastID); err != nil {
+			return err
+		}
+		return domain.ErrBroadcastStatus
+	}
+	return nil
+}
+
+func (b *broadcastRepo) find(ctx context.Context, filter any, opts *options.FindOptions) ([]domain.Broadcast, error) {
+	res, err := b.broadcasts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	broadcasts := make([]domain.Broadcast, 0)
+	if err := res.All(ctx, &broadcasts); err != nil {
+		return nil, err
+	}
+	return broadcasts, nil
+}
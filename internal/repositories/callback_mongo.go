@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type callbackRepo struct {
+	callbacks *mongo.Collection
+}
+
+func NewCallbackRepo(callbacks *mongo.Collection) *callbackRepo {
+	return &callbackRepo{
+		callbacks: callbacks,
+	}
+}
+
+func (c *callbackRepo) Save(ctx context.Context, callback domain.StoredCallback) error {
+	_, err := c.callbacks.InsertOne(ctx, callback)
+	return err
+}
+
+func (c *callbackRepo) Get(ctx context.Context, key string) (domain.StoredCallback, error) {
+	res := c.callbacks.FindOne(ctx, bson.M{"_id": key})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.StoredCallback{}, domain.ErrCallbackNotFound
+		}
+		return domain.StoredCallback{}, err
+	}
+	var callback domain.StoredCallback
+	if err := res.Decode(&callback); err != nil {
+		return domain.StoredCallback{}, err
+	}
+	return callback, nil
+}
@@ -192,3 +192,80 @@ func (c *catalogRepo) UpdateRanks(ctx context.Context, dto dto.UpdateItemDTO) er
 	}()
 	return nil
 }
+
+func (c *catalogRepo) UpdateItem(ctx context.Context, dto dto.UpdateCatalogItemDTO) error {
+	update := bson.M{}
+	if dto.PriceRUB != nil {
+		update["priceRub"] = *dto.PriceRUB
+	}
+	if dto.Quantity != nil {
+		update["quantity"] = *dto.Quantity
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	res, err := c.catalog.UpdateByID(ctx, dto.ItemID, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrItemNotFound
+	}
+
+	defer func() {
+		// Notify on change
+		newCatalog, err := c.GetCatalog(ctx)
+		if err != nil {
+			logger.Get().Error("deferred catalog notify", zap.Error(err))
+			return
+		}
+		c.onChange(newCatalog)
+	}()
+	return nil
+}
+
+// Reorder reads catalog, gets new ranks from reorder and sets them in single transaction
+func (c *catalogRepo) Reorder(ctx context.Context, reorder func([]domain.CatalogItem) ([]domain.CatalogItem, error)) error {
+	client := c.catalog.Database().Client()
+
+	if err := client.UseSession(ctx, func(tx mongo.SessionContext) error {
+		if err := tx.StartTransaction(); err != nil {
+			return err
+		}
+
+		catalog, err := c.GetCatalog(tx)
+		if err != nil {
+			tx.AbortTransaction(ctx)
+			return err
+		}
+		items, err := reorder(catalog)
+		if err != nil {
+			tx.AbortTransaction(ctx)
+			return err
+		}
+
+		for _, item := range items {
+			setQuery := bson.M{"$set": bson.M{"rank": item.Rank}}
+			if _, err := c.catalog.UpdateOne(tx, bson.M{"_id": item.ItemID}, setQuery); err != nil {
+				tx.AbortTransaction(ctx)
+				return err
+			}
+		}
+
+		return tx.CommitTransaction(ctx)
+	}); err != nil {
+		return err
+	}
+
+	defer func() {
+		// Notify on change
+		newCatalog, err := c.GetCatalog(ctx)
+		if err != nil {
+			logger.Get().Error("deferred catalog notify", zap.Error(err))
+			return
+		}
+		c.onChange(newCatalog)
+	}()
+	return nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// There's single catalog, so is its state
+const catalogStateID = "catalog"
+
+type catalogStateRepo struct {
+	state *mongo.Collection
+}
+
+func NewCatalogStateRepo(state *mongo.Collection) *catalogStateRepo {
+	return &catalogStateRepo{
+		state: state,
+	}
+}
+
+func (c *catalogStateRepo) GetLastRefresh(ctx context.Context) (time.Time, error) {
+	res := c.state.FindOne(ctx, bson.M{"_id": catalogStateID})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return time.Time{}, domain.ErrCatalogStateNotFound
+		}
+		return time.Time{}, err
+	}
+	var state domain.CatalogState
+	if err := res.Decode(&state); err != nil {
+		return time.Time{}, err
+	}
+	return state.LastRefresh, nil
+}
+
+func (c *catalogStateRepo) SaveLastRefresh(ctx context.Context, at time.Time) error {
+	opts := options.Update().SetUpsert(true)
+	update := bson.M{"$set": domain.CatalogState{LastRefresh: at}}
+	_, err := c.state.UpdateOne(ctx, bson.M{"_id": catalogStateID}, update, opts)
+	return err
+}
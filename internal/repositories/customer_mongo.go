@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type customerRepo struct {
@@ -87,10 +89,42 @@ func (c *customerRepo) Update(ctx context.Context, customerID primitive.ObjectID
 		}
 	}
 
+	if dto.EditPositionN != nil {
+		update["meta.editPositionN"] = *dto.EditPositionN
+	}
+
 	if dto.CatalogOffset != nil {
 		update["catalogOffset"] = *dto.CatalogOffset
 	}
 
+	if dto.SubscribedToDrops != nil {
+		update["subscribedToDrops"] = *dto.SubscribedToDrops
+	}
+
+	if dto.Favourites != nil {
+		update["favourites"] = *dto.Favourites
+	}
+
+	if dto.CartRemindersOptedOut != nil {
+		update["cartReminders.optedOut"] = *dto.CartRemindersOptedOut
+	}
+
+	if dto.Addresses != nil {
+		update["addresses"] = *dto.Addresses
+	}
+
+	if dto.EditingProfile != nil {
+		update["meta.editingProfile"] = *dto.EditingProfile
+	}
+
+	if dto.PendingAddress != nil {
+		update["meta.pendingAddress"] = *dto.PendingAddress
+	}
+
+	if dto.Language != nil {
+		update["language"] = *dto.Language
+	}
+
 	_, err := c.customers.UpdateByID(ctx, customerID, bson.M{"$set": update})
 	if err != nil {
 		return err
@@ -152,3 +186,145 @@ func (c *customerRepo) All(ctx context.Context) ([]domain.Customer, error) {
 	}
 	return customers, nil
 }
+
+func (c *customerRepo) GetDropSubscribers(ctx context.Context) ([]domain.Customer, error) {
+	res, err := c.customers.Find(ctx, bson.M{"subscribedToDrops": true})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrNoCustomers
+		}
+		return nil, err
+	}
+	var customers []domain.Customer
+	if err := res.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (c *customerRepo) GetWithFavourites(ctx context.Context) ([]domain.Customer, error) {
+	res, err := c.customers.Find(ctx, bson.M{"favourites.0": bson.M{"$exists": true}})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrNoCustomers
+		}
+		return nil, err
+	}
+	var customers []domain.Customer
+	if err := res.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (c *customerRepo) SetFavouriteSeen(ctx context.Context, customerID primitive.ObjectID, f domain.FavouriteItem) error {
+	// Favourite removed meanwhile is not matched and is not brought back
+	filter := bson.M{"_id": customerID, "favourites.itemId": f.ItemID}
+	update := bson.M{"$set": bson.M{
+		"favourites.$.priceRub": f.PriceRUB,
+		"favourites.$.inStock":  f.InStock,
+	}}
+	_, err := c.customers.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// TouchActivity records customer activity. Customer who writes to bot can't be blocking it.
+// Abandoned cart reminders start over. Returned customer has only language set, it's read on every update
+func (c *customerRepo) TouchActivity(ctx context.Context, telegramID int64, at time.Time) (domain.Customer, error) {
+	update := bson.M{"$set": bson.M{
+		"lastActivityAt":     at,
+		"blockedBot":         false,
+		"cartReminders.sent": 0,
+	}}
+	opts := options.FindOneAndUpdate()
+	opts.SetProjection(bson.M{"language": 1})
+	res := c.customers.FindOneAndUpdate(ctx, bson.M{"telegramId": telegramID}, update, opts)
+	if res.Err() != nil {
+		if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+			return domain.Customer{}, domain.ErrCustomerNotFound
+		}
+		return domain.Customer{}, res.Err()
+	}
+	var customer domain.Customer
+	if err := res.Decode(&customer); err != nil {
+		return domain.Customer{}, err
+	}
+	return customer, nil
+}
+
+func (c *customerRepo) SetBlockedBot(ctx context.Context, telegramID int64, blocked bool) error {
+	_, err := c.customers.UpdateOne(ctx, bson.M{"telegramId": telegramID}, bson.M{"$set": bson.M{"blockedBot": blocked}})
+	return err
+}
+
+// GetBroadcastAudience never returns customers who blocked the bot
+func (c *customerRepo) GetBroadcastAudience(ctx context.Context, dto dto.BroadcastAudienceDTO) ([]domain.Customer, error) {
+	var (
+		filter   = bson.M{"blockedBot": bson.M{"$ne": true}}
+		idFilter = bson.M{}
+	)
+	if dto.AfterCustomerID != nil {
+		idFilter["$gt"] = *dto.AfterCustomerID
+	}
+	switch dto.Audience {
+	case domain.AudienceAll:
+	case domain.AudienceWithOrders:
+		idFilter["$in"] = dto.CustomerIDs
+	case domain.AudienceWithCart:
+		filter["cart.0"] = bson.M{"$exists": true}
+	case domain.AudienceInactive:
+		// Customers registered before activity tracking have no lastActivityAt
+		filter["$or"] = bson.A{
+			bson.M{"lastActivityAt": bson.M{"$lt": dto.InactiveSince}},
+			bson.M{"lastActivityAt": bson.M{"$exists": false}},
+		}
+	default:
+		return nil, domain.ErrInvalidBroadcast
+	}
+	if len(idFilter) > 0 {
+		filter["_id"] = idFilter
+	}
+
+	// Broadcast cursor relies on order
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+	res, err := c.customers.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var customers []domain.Customer
+	if err := res.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+// GetAbandonedCarts returns customers with non-empty cart inactive since inactiveSince
+// who've got less than remindersSentBelow reminders
+func (c *customerRepo) GetAbandonedCarts(ctx context.Context, inactiveSince time.Time, remindersSentBelow uint) ([]domain.Customer, error) {
+	filter := bson.M{
+		"cart.0":                 bson.M{"$exists": true},
+		"lastActivityAt":         bson.M{"$lt": inactiveSince},
+		"blockedBot":             bson.M{"$ne": true},
+		"cartReminders.optedOut": bson.M{"$ne": true},
+		// Field is missing for customers without reminders
+		"$or": bson.A{
+			bson.M{"cartReminders.sent": bson.M{"$lt": remindersSentBelow}},
+			bson.M{"cartReminders.sent": bson.M{"$exists": false}},
+		},
+	}
+
+	res, err := c.customers.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var customers []domain.Customer
+	if err := res.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (c *customerRepo) SetCartRemindersSent(ctx context.Context, telegramID int64, sent uint) error {
+	_, err := c.customers.UpdateOne(ctx, bson.M{"telegramId": telegramID}, bson.M{"$set": bson.M{"cartReminders.sent": sent}})
+	return err
+}
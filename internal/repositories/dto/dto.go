@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -15,6 +17,30 @@ type UpdateCustomerDTO struct {
 	Cart           *domain.Cart
 	State          *domain.State
 	CatalogOffset  *uint
+	// Drops subscription
+	SubscribedToDrops *bool
+	Favourites        *[]domain.FavouriteItem
+	// Cart position being edited, 0 to stop editing
+	EditPositionN *uint
+	// Abandoned cart reminders
+	CartRemindersOptedOut *bool
+	Addresses             *[]domain.Address
+	// Full name or phone number is changed from profile
+	EditingProfile *bool
+	// Address being typed in, empty address to reset
+	PendingAddress *domain.Address
+	// Language of bot texts
+	Language *string
+}
+
+type BroadcastAudienceDTO struct {
+	Audience domain.BroadcastAudience
+	// For domain.AudienceInactive
+	InactiveSince time.Time
+	// For domain.AudienceWithOrders
+	CustomerIDs []primitive.ObjectID
+	// Skips customers who got resumed broadcast already
+	AfterCustomerID *primitive.ObjectID
 }
 
 type UpdateItemDTO struct {
@@ -22,11 +48,24 @@ type UpdateItemDTO struct {
 	RankDownItemID primitive.ObjectID
 }
 
+type UpdateCatalogItemDTO struct {
+	ItemID   primitive.ObjectID
+	PriceRUB *uint64
+	Quantity *int
+}
+
 type AddCommentDTO struct {
 	OrderID primitive.ObjectID
 	Comment string
 }
 
+type CustomerOrdersPageDTO struct {
+	CustomerID primitive.ObjectID
+	Filter     domain.OrdersFilter
+	Offset     int
+	Limit      int
+}
+
 type ChangeOrderStatusDTO struct {
 	OrderID   primitive.ObjectID
 	NewStatus domain.Status
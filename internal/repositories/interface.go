@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
@@ -16,6 +17,15 @@ type Customer interface {
 	NullifyCatalogOffsets(ctx context.Context) error
 	Update(ctx context.Context, customerID primitive.ObjectID, dto dto.UpdateCustomerDTO) error
 	All(ctx context.Context) ([]domain.Customer, error)
+	GetDropSubscribers(ctx context.Context) ([]domain.Customer, error)
+	GetWithFavourites(ctx context.Context) ([]domain.Customer, error)
+	// SetFavouriteSeen updates price and stock of single favourite, other favourites are untouched
+	SetFavouriteSeen(ctx context.Context, customerID primitive.ObjectID, f domain.FavouriteItem) error
+	GetBroadcastAudience(ctx context.Context, dto dto.BroadcastAudienceDTO) ([]domain.Customer, error)
+	TouchActivity(ctx context.Context, telegramID int64, at time.Time) (domain.Customer, error)
+	SetBlockedBot(ctx context.Context, telegramID int64, blocked bool) error
+	GetAbandonedCarts(ctx context.Context, inactiveSince time.Time, remindersSentBelow uint) ([]domain.Customer, error)
+	SetCartRemindersSent(ctx context.Context, telegramID int64, sent uint) error
 }
 
 type Order interface {
@@ -26,9 +36,14 @@ type Order interface {
 	Delete(ctx context.Context, orderID primitive.ObjectID) error
 	ChangeStatus(ctx context.Context, dto dto.ChangeOrderStatusDTO) (domain.Order, error)
 	GetAllForCustomer(ctx context.Context, customerID primitive.ObjectID) ([]domain.Order, error)
+	// GetPageForCustomer returns newest orders first and total number of orders matching filter
+	GetPageForCustomer(ctx context.Context, dto dto.CustomerOrdersPageDTO) ([]domain.Order, int, error)
 	GetAll(ctx context.Context) ([]domain.Order, error)
 	UpdateToPaid(ctx context.Context, customerID primitive.ObjectID, shortID string) error
 	Save(ctx context.Context, o domain.Order) error
+	// SaveLinked saves orders split from single cart, either all of them or none
+	SaveLinked(ctx context.Context, orders []domain.Order) error
+	GetCustomerIDs(ctx context.Context) ([]primitive.ObjectID, error)
 }
 
 type Catalog interface {
@@ -36,7 +51,43 @@ type Catalog interface {
 	AddItem(ctx context.Context, item domain.CatalogItem) error
 	RemoveItem(ctx context.Context, itemID primitive.ObjectID) error
 	UpdateRanks(ctx context.Context, dto dto.UpdateItemDTO) error
+	Reorder(ctx context.Context, reorder func([]domain.CatalogItem) ([]domain.CatalogItem, error)) error
+	UpdateItem(ctx context.Context, dto dto.UpdateCatalogItemDTO) error
 	GetIDByRank(ctx context.Context, rank uint) (primitive.ObjectID, error)
 	GetRankByID(ctx context.Context, itemID primitive.ObjectID) (uint, error)
 	GetLastRank(ctx context.Context) (uint, error)
 }
+
+type CatalogState interface {
+	// GetLastRefresh returns domain.ErrCatalogStateNotFound before first refresh is saved
+	GetLastRefresh(ctx context.Context) (time.Time, error)
+	SaveLastRefresh(ctx context.Context, at time.Time) error
+}
+
+type Media interface {
+	GetFileID(ctx context.Context, url string) (string, error)
+	SaveFileID(ctx context.Context, url string, fileID string) error
+	Delete(ctx context.Context, url string) error
+}
+
+type Broadcast interface {
+	Save(ctx context.Context, b domain.Broadcast) (domain.Broadcast, error)
+	GetByID(ctx context.Context, broadcastID primitive.ObjectID) (domain.Broadcast, error)
+	GetAll(ctx context.Context) ([]domain.Broadcast, error)
+	// GetDue returns pending broadcasts scheduled not later than now
+	GetDue(ctx context.Context, now time.Time) ([]domain.Broadcast, error)
+	// GetSending returns broadcasts interrupted by shutdown
+	GetSending(ctx context.Context) ([]domain.Broadcast, error)
+	// Start moves pending broadcast to sending. Returns domain.ErrBroadcastStatus if it is not pending
+	Start(ctx context.Context, broadcastID primitive.ObjectID) error
+	// UpdateProgress saves stats and cursor of sending broadcast, nil cursor is left as is
+	UpdateProgress(ctx context.Context, broadcastID primitive.ObjectID, stats domain.BroadcastStats, cursor *primitive.ObjectID) error
+	Finish(ctx context.Context, broadcastID primitive.ObjectID, stats domain.BroadcastStats, at time.Time) error
+	Cancel(ctx context.Context, broadcastID primitive.ObjectID) error
+}
+
+type Callback interface {
+	Save(ctx context.Context, callback domain.StoredCallback) error
+	// Get returns domain.ErrCallbackNotFound for unknown key
+	Get(ctx context.Context, key string) (domain.StoredCallback, error)
+}
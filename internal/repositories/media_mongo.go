@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mediaRepo struct {
+	media *mongo.Collection
+}
+
+func NewMediaRepo(media *mongo.Collection) *mediaRepo {
+	return &mediaRepo{
+		media: media,
+	}
+}
+
+func (m *mediaRepo) GetFileID(ctx context.Context, url string) (string, error) {
+	res := m.media.FindOne(ctx, bson.M{"url": url})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", domain.ErrMediaNotFound
+		}
+		return "", err
+	}
+	var file domain.MediaFile
+	if err := res.Decode(&file); err != nil {
+		return "", err
+	}
+	return file.FileID, nil
+}
+
+func (m *mediaRepo) SaveFileID(ctx context.Context, url string, fileID string) error {
+	opts := options.Update().SetUpsert(true)
+	update := bson.M{"$set": domain.MediaFile{URL: url, FileID: fileID}}
+	_, err := m.media.UpdateOne(ctx, bson.M{"url": url}, update, opts)
+	return err
+}
+
+func (m *mediaRepo) Delete(ctx context.Context, url string) error {
+	_, err := m.media.DeleteOne(ctx, bson.M{"url": url})
+	return err
+}
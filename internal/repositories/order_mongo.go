@@ -87,6 +87,27 @@ func (o *orderRepo) Save(ctx context.Context, order domain.Order) error {
 	return nil
 }
 
+func (o *orderRepo) SaveLinked(ctx context.Context, orders []domain.Order) error {
+	docs := make([]interface{}, 0, len(orders))
+	for _, order := range orders {
+		docs = append(docs, order)
+	}
+
+	client := o.orders.Database().Client()
+	return client.UseSession(ctx, func(tx mongo.SessionContext) error {
+		if err := tx.StartTransaction(); err != nil {
+			return err
+		}
+
+		if _, err := o.orders.InsertMany(tx, docs); err != nil {
+			tx.AbortTransaction(ctx)
+			return err
+		}
+
+		return tx.CommitTransaction(ctx)
+	})
+}
+
 func (o *orderRepo) GetFreeShortID(ctx context.Context) (string, error) {
 	for {
 		shortID := nanoid.GenerateNanoID()
@@ -137,6 +158,53 @@ func (o *orderRepo) GetAllForCustomer(ctx context.Context, customerID primitive.
 	return orders, nil
 }
 
+func (o *orderRepo) GetPageForCustomer(ctx context.Context, dto dto.CustomerOrdersPageDTO) ([]domain.Order, int, error) {
+	filter := bson.M{"customer._id": dto.CustomerID}
+	switch dto.Filter {
+	case domain.OrdersFilterActive:
+		filter["status"] = bson.M{"$nin": domain.CompletedStatuses}
+	case domain.OrdersFilterCompleted:
+		filter["status"] = bson.M{"$in": domain.CompletedStatuses}
+	}
+
+	total, err := o.orders.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetSkip(int64(dto.Offset)).
+		SetLimit(int64(dto.Limit))
+	res, err := o.orders.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var orders []domain.Order
+	if err := res.All(ctx, &orders); err != nil {
+		return nil, 0, err
+	}
+
+	return orders, int(total), nil
+}
+
+// GetCustomerIDs returns ids of customers who have at least one order
+func (o *orderRepo) GetCustomerIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	res, err := o.orders.Distinct(ctx, "customer._id", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(res))
+	for _, v := range res {
+		id, ok := v.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 func (o *orderRepo) findOneAndUpdate(ctx context.Context, filter, update any) (domain.Order, error) {
 	opts := options.FindOneAndUpdate()
 	opts.SetReturnDocument(options.After)
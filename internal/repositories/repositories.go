@@ -5,21 +5,33 @@ import (
 )
 
 type Repositories struct {
-	Customer *customerRepo
-	Order    *orderRepo
-	Catalog  *catalogRepo
+	Customer     *customerRepo
+	Order        *orderRepo
+	Catalog      *catalogRepo
+	CatalogState *catalogStateRepo
+	Media        *mediaRepo
+	Broadcast    *broadcastRepo
+	Callback     *callbackRepo
 }
 
 const (
-	customers = "customers"
-	orders    = "orders"
-	catalog   = "catalog"
+	customers    = "customers"
+	orders       = "orders"
+	catalog      = "catalog"
+	catalogState = "catalog_state"
+	media        = "media"
+	broadcasts   = "broadcasts"
+	callbacks    = "callbacks"
 )
 
 func NewRepositories(db *database.Mongo, catalogOnChangeFunc OnChangeFunc) Repositories {
 	return Repositories{
-		Customer: NewCustomerRepo(db.Collection(customers)),
-		Order:    NewOrderRepo(db.Collection(orders)),
-		Catalog:  NewCatalogRepo(db.Collection(catalog), catalogOnChangeFunc),
+		Customer:     NewCustomerRepo(db.Collection(customers)),
+		Order:        NewOrderRepo(db.Collection(orders)),
+		Catalog:      NewCatalogRepo(db.Collection(catalog), catalogOnChangeFunc),
+		CatalogState: NewCatalogStateRepo(db.Collection(catalogState)),
+		Media:        NewMediaRepo(db.Collection(media)),
+		Broadcast:    NewBroadcastRepo(db.Collection(broadcasts)),
+		Callback:     NewCallbackRepo(db.Collection(callbacks)),
 	}
 }
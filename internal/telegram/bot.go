@@ -31,6 +31,20 @@ func (b *bot) GetUpdates() tg.UpdatesChannel {
 	return b.client.GetUpdatesChan(tg.UpdateConfig{})
 }
 
+// SetWebhook makes Telegram post updates to url with secret in header, long polling stops working then
+func (b *bot) SetWebhook(url string, secret string) error {
+	// secret_token is not supported by tg.WebhookConfig
+	params := tg.Params{"url": url}
+	params.AddNonEmpty("secret_token", secret)
+	_, err := b.client.MakeRequest("setWebhook", params)
+	return err
+}
+
+// DeleteWebhook is required before long polling if webhook was set before
+func (b *bot) DeleteWebhook() error {
+	return b.CleanRequest(tg.DeleteWebhookConfig{})
+}
+
 func (b *bot) Send(c tg.Chattable) (tg.Message, error) {
 	return b.client.Send(c)
 }
@@ -0,0 +1,246 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+	"github.com/sonyamoonglade/poison-tg/pkg/functools"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBroadcastPoll = time.Second * 15
+	// Stats are saved every n customers so progress is visible while sending
+	broadcastStatsFlushEvery = 50
+	// Progress is saved after ctx is done on shutdown
+	broadcastSaveTimeout = time.Second * 5
+	// Delivery to a customer isn't cut by shutdown, so it's known whether customer got broadcast
+	broadcastDeliverTimeout = time.Second * 30
+	// Telegram limits caption length
+	maxCaptionLen = 1024
+)
+
+var errBotBlocked = errors.New("bot is blocked by customer")
+
+// Broadcaster delivers scheduled broadcasts to their audience.
+// Bot is expected to keep Telegram limits and retry, see Sender.Bulk
+type Broadcaster struct {
+	b             Bot
+	broadcastRepo repositories.Broadcast
+	customerRepo  repositories.Customer
+	orderRepo     repositories.Order
+	poll          time.Duration
+	now           func() time.Time
+}
+
+func NewBroadcaster(bot Bot,
+	broadcastRepo repositories.Broadcast,
+	customerRepo repositories.Customer,
+	orderRepo repositories.Order) *Broadcaster {
+	return &Broadcaster{
+		b:             bot,
+		broadcastRepo: broadcastRepo,
+		customerRepo:  customerRepo,
+		orderRepo:     orderRepo,
+		poll:          defaultBroadcastPoll,
+		now:           time.Now,
+	}
+}
+
+// Run blocks until ctx is done
+func (b *Broadcaster) Run(ctx context.Context) {
+	logger.Get().Info("broadcaster is running")
+	b.resumeInterrupted(ctx)
+	ticker := time.NewTicker(b.poll)
+	defer ticker.Stop()
+	for {
+		b.processDue(ctx)
+		select {
+		case <-ctx.Done():
+			logger.Get().Info("broadcaster is shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Broadcaster) processDue(ctx context.Context) {
+	due, err := b.broadcastRepo.GetDue(ctx, b.now())
+	if err != nil {
+		logger.Get().Error("can't get due broadcasts", zap.Error(err))
+		return
+	}
+	for _, broadcast := range due {
+		if err := b.send(ctx, broadcast); err != nil {
+			logger.Get().Error("broadcast failed",
+				zap.String("broadcastId", broadcast.BroadcastID.Hex()),
+				zap.Error(err))
+		}
+	}
+}
+
+// resumeInterrupted continues broadcasts that were sending on shutdown
+func (b *Broadcaster) resumeInterrupted(ctx context.Context) {
+	sending, err := b.broadcastRepo.GetSending(ctx)
+	if err != nil {
+		logger.Get().Error("can't get interrupted broadcasts", zap.Error(err))
+		return
+	}
+	for _, broadcast := range sending {
+		if err := b.sendAudience(ctx, broadcast); err != nil {
+			logger.Get().Error("resumed broadcast failed",
+				zap.String("broadcastId", broadcast.BroadcastID.Hex()),
+				zap.Error(err))
+		}
+	}
+}
+
+func (b *Broadcaster) send(ctx context.Context, broadcast domain.Broadcast) error {
+	if err := b.broadcastRepo.Start(ctx, broadcast.BroadcastID); err != nil {
+		if errors.Is(err, domain.ErrBroadcastStatus) {
+			// Cancelled meanwhile
+			return nil
+		}
+		return err
+	}
+	return b.sendAudience(ctx, broadcast)
+}
+
+// sendAudience sends broadcast to customers after its cursor
+func (b *Broadcaster) sendAudience(ctx context.Context, broadcast domain.Broadcast) error {
+	customers, err := b.resolveAudience(ctx, broadcast)
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info("sending broadcast",
+		zap.String("broadcastId", broadcast.BroadcastID.Hex()),
+		zap.Int("audience", len(customers)),
+		zap.Bool("resumed", broadcast.Cursor != nil))
+
+	stats, cursor := b.sendAll(ctx, broadcast, customers)
+
+	// ctx is done on shutdown, progress must be saved anyway
+	saveCtx, cancel := context.WithTimeout(context.Background(), broadcastSaveTimeout)
+	defer cancel()
+	if ctx.Err() != nil {
+		// Broadcast stays sending and is resumed on start
+		return b.broadcastRepo.UpdateProgress(saveCtx, broadcast.BroadcastID, stats, cursor)
+	}
+	return b.broadcastRepo.Finish(saveCtx, broadcast.BroadcastID, stats, b.now())
+}
+
+func (b *Broadcaster) resolveAudience(ctx context.Context, broadcast domain.Broadcast) ([]domain.Customer, error) {
+	audience := dto.BroadcastAudienceDTO{
+		Audience:        broadcast.Audience,
+		InactiveSince:   broadcast.InactiveSince(b.now()),
+		AfterCustomerID: broadcast.Cursor,
+	}
+	if broadcast.Audience == domain.AudienceWithOrders {
+		ids, err := b.orderRepo.GetCustomerIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		audience.CustomerIDs = ids
+	}
+	return b.customerRepo.GetBroadcastAudience(ctx, audience)
+}
+
+// sendAll continues stats of broadcast. Returns id of last customer done, nil if there's none
+func (b *Broadcaster) sendAll(ctx context.Context, broadcast domain.Broadcast, customers []domain.Customer) (domain.BroadcastStats, *primitive.ObjectID) {
+	var (
+		stats  = broadcast.Stats
+		cursor = broadcast.Cursor
+	)
+	stats.Total = stats.Sent + stats.Failed + stats.Blocked + len(customers)
+	for i, c := range customers {
+		if ctx.Err() != nil {
+			break
+		}
+		started, err := b.deliver(c.TelegramID, broadcast)
+		if !started && isNotSent(err) && ctx.Err() != nil {
+			// Nothing is sent because of shutdown, customer gets broadcast after resume
+			break
+		}
+		switch {
+		case err == nil:
+			stats.Sent++
+		case errors.Is(err, errBotBlocked):
+			stats.Blocked++
+			if err := b.customerRepo.SetBlockedBot(ctx, c.TelegramID, true); err != nil {
+				logger.Get().Error("can't flag blocked customer",
+					zap.Int64("telegramId", c.TelegramID),
+					zap.Error(err))
+			}
+		default:
+			stats.Failed++
+			logger.Get().Warn("can't deliver broadcast",
+				zap.Int64("telegramId", c.TelegramID),
+				zap.Error(err))
+		}
+
+		customerID := c.CustomerID
+		cursor = &customerID
+
+		if (i+1)%broadcastStatsFlushEvery == 0 {
+			if err := b.broadcastRepo.UpdateProgress(ctx, broadcast.BroadcastID, stats, cursor); err != nil {
+				logger.Get().Error("can't update broadcast progress", zap.Error(err))
+			}
+		}
+	}
+	return stats, cursor
+}
+
+// deliver sends broadcast to single chat. Returned is false if no message of broadcast got to telegram
+func (b *Broadcaster) deliver(chatID int64, broadcast domain.Broadcast) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastDeliverTimeout)
+	defer cancel()
+
+	started, err := b.deliverMessages(botWithContext(ctx, b.b), chatID, broadcast)
+	if isBlockedError(err) {
+		return started, errBotBlocked
+	}
+	return started, err
+}
+
+func (b *Broadcaster) deliverMessages(bot Bot, chatID int64, broadcast domain.Broadcast) (bool, error) {
+	var markup any
+	if broadcast.Button != nil {
+		markup = tg.NewInlineKeyboardMarkup(tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonURL(broadcast.Button.Text, broadcast.Button.URL),
+		))
+	}
+
+	// Single photo fits text into caption
+	if len(broadcast.ImageURLs) == 1 && len([]rune(broadcast.Text)) <= maxCaptionLen {
+		photo := tg.NewPhoto(chatID, tg.FileURL(broadcast.ImageURLs[0]))
+		photo.Caption = broadcast.Text
+		photo.ParseMode = parseModeHTML
+		photo.ReplyMarkup = markup
+		_, err := bot.Send(photo)
+		return !isNotSent(err), err
+	}
+
+	if len(broadcast.ImageURLs) > 0 {
+		media := functools.Map(func(url string, i int) interface{} {
+			return tg.NewInputMediaPhoto(tg.FileURL(url))
+		}, broadcast.ImageURLs)
+		if _, err := bot.SendMediaGroup(tg.NewMediaGroup(chatID, media)); err != nil {
+			return !isNotSent(err), err
+		}
+	}
+
+	msg := tg.NewMessage(chatID, broadcast.Text)
+	msg.ParseMode = parseModeHTML
+	msg.ReplyMarkup = markup
+	_, err := bot.Send(msg)
+	// Media group is sent already, so broadcast isn't sent to customer again even if text is not
+	return len(broadcast.ImageURLs) > 0 || !isNotSent(err), err
+}
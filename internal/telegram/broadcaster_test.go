@@ -0,0 +1,187 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBroadcasterSendAll(t *testing.T) {
+	bot := newFakeBot()
+	bot.fail(2, errBlockedByUser)
+	bot.fail(3, errors.New("network is down"))
+
+	customers := []domain.Customer{{TelegramID: 1}, {TelegramID: 2}, {TelegramID: 3}, {TelegramID: 4}}
+	customerRepo := newFakeCustomerRepo(customers...)
+	b := NewBroadcaster(bot, nil, customerRepo, nil)
+
+	stats, _ := b.sendAll(context.Background(), domain.Broadcast{Text: "hello"}, customers)
+
+	require.Equal(t, domain.BroadcastStats{Total: 4, Sent: 2, Failed: 1, Blocked: 1}, stats)
+	require.True(t, customerRepo.customer(2).BlockedBot)
+	require.False(t, customerRepo.customer(3).BlockedBot)
+}
+
+// progressBroadcastRepo keeps last saved progress of single broadcast
+type progressBroadcastRepo struct {
+	repositories.Broadcast
+	stats    domain.BroadcastStats
+	cursor   *primitive.ObjectID
+	finished bool
+}
+
+func (p *progressBroadcastRepo) UpdateProgress(ctx context.Context, broadcastID primitive.ObjectID, stats domain.BroadcastStats, cursor *primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.stats, p.cursor = stats, cursor
+	return nil
+}
+
+func (p *progressBroadcastRepo) Finish(ctx context.Context, broadcastID primitive.ObjectID, stats domain.BroadcastStats, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.stats, p.finished = stats, true
+	return nil
+}
+
+// newAudience is customers with telegram ids from 1 to n in order of their ids
+func newAudience(n int) []domain.Customer {
+	customers := make([]domain.Customer, 0, n)
+	for i := 1; i <= n; i++ {
+		customers = append(customers, domain.Customer{CustomerID: primitive.NewObjectID(), TelegramID: int64(i)})
+	}
+	return customers
+}
+
+// cancelOn cancels ctx on request to chat
+func cancelOn(chatID int64, cancel context.CancelFunc) func(int64) {
+	return func(id int64) {
+		if id == chatID {
+			cancel()
+		}
+	}
+}
+
+func TestBroadcasterResume(t *testing.T) {
+	logger.Get()
+	var (
+		broadcast = domain.Broadcast{BroadcastID: primitive.NewObjectID(), Text: "hello", Audience: domain.AudienceAll, Status: domain.BroadcastSending}
+		customers = newAudience(4)
+	)
+
+	// resume restarts broadcast with saved progress and expects it to reach only customers with chatIDs
+	resume := func(t *testing.T, broadcastRepo *progressBroadcastRepo, chatIDs ...int64) {
+		broadcast := broadcast
+		broadcast.Stats, broadcast.Cursor = broadcastRepo.stats, broadcastRepo.cursor
+		resumed := newFakeBot()
+		b := NewBroadcaster(resumed, broadcastRepo, newFakeCustomerRepo(customers...), nil)
+		require.NoError(t, b.sendAudience(context.Background(), broadcast))
+		require.True(t, broadcastRepo.finished)
+		require.Equal(t, domain.BroadcastStats{Total: 4, Sent: 4}, broadcastRepo.stats)
+		require.Len(t, resumed.sentTexts(), len(chatIDs))
+		for _, chatID := range chatIDs {
+			require.Len(t, resumed.sentTo(chatID), 1)
+		}
+	}
+
+	t.Run("customer not sent on shutdown gets broadcast after resume", func(t *testing.T) {
+		broadcastRepo := new(progressBroadcastRepo)
+		// Shutdown while request to third customer is queued
+		ctx, cancel := context.WithCancel(context.Background())
+		bot := newFakeBot()
+		bot.onSend = cancelOn(3, cancel)
+		bot.fail(3, notSentError{context.Canceled})
+		b := NewBroadcaster(bot, broadcastRepo, newFakeCustomerRepo(customers...), nil)
+		require.NoError(t, b.sendAudience(ctx, broadcast))
+		require.False(t, broadcastRepo.finished)
+		require.Equal(t, domain.BroadcastStats{Total: 4, Sent: 2}, broadcastRepo.stats)
+		require.Equal(t, customers[1].CustomerID, *broadcastRepo.cursor)
+
+		resume(t, broadcastRepo, 3, 4)
+	})
+
+	t.Run("customer sent on shutdown is counted", func(t *testing.T) {
+		broadcastRepo := new(progressBroadcastRepo)
+		// Shutdown while request to second customer is in flight, telegram gets it anyway
+		ctx, cancel := context.WithCancel(context.Background())
+		bot := newFakeBot()
+		bot.onSend = cancelOn(2, cancel)
+		b := NewBroadcaster(bot, broadcastRepo, newFakeCustomerRepo(customers...), nil)
+		require.NoError(t, b.sendAudience(ctx, broadcast))
+		require.False(t, broadcastRepo.finished)
+		require.Equal(t, domain.BroadcastStats{Total: 4, Sent: 2}, broadcastRepo.stats)
+		require.Equal(t, customers[1].CustomerID, *broadcastRepo.cursor)
+
+		resume(t, broadcastRepo, 3, 4)
+	})
+
+	t.Run("partially sent customer doesn't get broadcast again", func(t *testing.T) {
+		var (
+			broadcastRepo = new(progressBroadcastRepo)
+			broadcast     = broadcast
+		)
+		broadcast.ImageURLs = []string{"https://example.com/1.jpg", "https://example.com/2.jpg"}
+		// Shutdown after media group to first customer is sent, text is not
+		ctx, cancel := context.WithCancel(context.Background())
+		bot := newFakeBot()
+		bot.onSend = cancelOn(1, cancel)
+		bot.fail(1, nil, notSentError{context.Canceled})
+		b := NewBroadcaster(bot, broadcastRepo, newFakeCustomerRepo(customers[:2]...), nil)
+		require.NoError(t, b.sendAudience(ctx, broadcast))
+		require.False(t, broadcastRepo.finished)
+		require.Equal(t, domain.BroadcastStats{Total: 2, Failed: 1}, broadcastRepo.stats)
+		require.Equal(t, customers[0].CustomerID, *broadcastRepo.cursor)
+		require.Len(t, bot.sentTo(1), 1)
+	})
+}
+
+func TestBroadcasterDeliver(t *testing.T) {
+	button := &domain.BroadcastButton{Text: "Каталог", URL: "https://example.com"}
+
+	t.Run("single image goes to caption", func(t *testing.T) {
+		bot := newFakeBot()
+		b := NewBroadcaster(bot, nil, nil, nil)
+
+		started, err := b.deliver(1, domain.Broadcast{
+			Text:      "<b>new drop</b>",
+			ImageURLs: []string{"https://example.com/1.jpg"},
+			Button:    button,
+		})
+		require.NoError(t, err)
+		require.True(t, started)
+		sent := bot.sentTo(1)
+		require.Len(t, sent, 1)
+		photo := sent[0].(tg.PhotoConfig)
+		require.Equal(t, "<b>new drop</b>", photo.Caption)
+		require.NotNil(t, photo.ReplyMarkup)
+	})
+
+	t.Run("several images are sent as group followed by text", func(t *testing.T) {
+		bot := newFakeBot()
+		b := NewBroadcaster(bot, nil, nil, nil)
+
+		started, err := b.deliver(1, domain.Broadcast{
+			Text:      "new drop",
+			ImageURLs: []string{"https://example.com/1.jpg", "https://example.com/2.jpg"},
+			Button:    button,
+		})
+		require.NoError(t, err)
+		require.True(t, started)
+		sent := bot.sentTo(1)
+		require.Len(t, sent, 2)
+		require.IsType(t, tg.MediaGroupConfig{}, sent[0])
+		msg := sent[1].(tg.MessageConfig)
+		require.Equal(t, parseModeHTML, msg.ParseMode)
+		require.NotNil(t, msg.ReplyMarkup)
+	})
+}
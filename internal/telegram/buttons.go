@@ -1,12 +1,12 @@
 package telegram
 
 import (
-	"fmt"
+	"context"
 	"math"
 	"strconv"
-	"strings"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
 )
 
 const (
@@ -14,263 +14,234 @@ const (
 	arrRight = "➡"
 )
 
-// DO NOT CHANGE ORDER
-// LOGIC DEMANDS ON IOTA
-// todo: change from iota
+// Callback actions are stored in already sent buttons, so values must stay stable.
+// Action must not contain callbackPayloadSep or callbackStoredSep
 const (
-	noopCallback = iota
-	menuCatalogCallback
-	menuFaqCallback
-	menuMyOrdersCallback
-	menuCalculatorCallback
-	calculateMoreCallback
-	menuMakeOrderCallback
-	orderGuideStep0Callback
-	orderGuideStep1Callback
-	orderGuideStep2Callback
-	orderGuideStep3Callback
-	orderGuideStep4Callback
-	orderGuideStep5Callback
-	makeOrderCallback
-	buttonTorqoiseSelectCallback
-	buttonGreySelectCallback
-	button95SelectCallback
-	addPositionCallback
-	editCartCallback
-	orderTypeNormalCallback
-	orderTypeNormalCalculatorCallback
-	orderTypeExpressCallback
-	orderTypeExpressCalculatorCallback
-	categoryLightCallback
-	categoryLightCalculatorCallback
-	categoryHeavyCallback
-	categoryHeavyCalculatorCallback
-	categoryOtherCallback
-	categoryOtherCalculatorCallback
-	selectCategoryAgainCallback
-
-	paymentCallback
+	noopCallback                       callbackAction = "noop"
+	menuCatalogCallback                callbackAction = "m.cat"
+	menuFaqCallback                    callbackAction = "m.faq"
+	menuMyOrdersCallback               callbackAction = "m.ord"
+	menuCalculatorCallback             callbackAction = "m.calc"
+	menuMakeOrderCallback              callbackAction = "m.mko"
+	menuFavouritesCallback             callbackAction = "m.fav"
+	menuProfileCallback                callbackAction = "m.prof"
+	calculateMoreCallback              callbackAction = "calc.more"
+	selectCategoryAgainCallback        callbackAction = "calc.cat"
+	orderGuideStepCallback             callbackAction = "guide"
+	makeOrderCallback                  callbackAction = "mko"
+	buttonTorqoiseSelectCallback       callbackAction = "btn.torq"
+	buttonGreySelectCallback           callbackAction = "btn.grey"
+	button95SelectCallback             callbackAction = "btn.95"
+	addPositionCallback                callbackAction = "pos.add"
+	editCartCallback                   callbackAction = "cart.edit"
+	removeCartPositionCallback         callbackAction = "cart.rm"
+	orderTypeNormalCallback            callbackAction = "ot.norm"
+	orderTypeNormalCalculatorCallback  callbackAction = "ot.norm.calc"
+	orderTypeExpressCallback           callbackAction = "ot.exp"
+	orderTypeExpressCalculatorCallback callbackAction = "ot.exp.calc"
+	categoryLightCallback              callbackAction = "cat.light"
+	categoryLightCalculatorCallback    callbackAction = "cat.light.calc"
+	categoryHeavyCallback              callbackAction = "cat.heavy"
+	categoryHeavyCalculatorCallback    callbackAction = "cat.heavy.calc"
+	categoryOtherCallback              callbackAction = "cat.other"
+	categoryOtherCalculatorCallback    callbackAction = "cat.other.calc"
+	paymentCallback                    callbackAction = "pay"
+	toggleDropsSubscriptionCallback    callbackAction = "drops"
+	addToFavouritesCallback            callbackAction = "fav.add"
+	removeFromFavouritesCallback       callbackAction = "fav.rm"
+	cartRemindersOptOutCallback        callbackAction = "remind.off"
+	editPositionCallback               callbackAction = "pos.edit"
+	editPositionSizeCallback           callbackAction = "pos.size"
+	editPositionButtonCallback         callbackAction = "pos.btn"
+	editPositionPriceCallback          callbackAction = "pos.price"
+	editPositionLinkCallback           callbackAction = "pos.link"
+	increaseQuantityCallback           callbackAction = "pos.inc"
+	decreaseQuantityCallback           callbackAction = "pos.dec"
+	useSavedDetailsCallback            callbackAction = "det.saved"
+	enterNewDetailsCallback            callbackAction = "det.new"
+	useSavedAddressCallback            callbackAction = "addr.saved"
+	newDeliveryAddressCallback         callbackAction = "addr.new"
+	editProfileFIOCallback             callbackAction = "prof.fio"
+	editProfilePhoneNumberCallback     callbackAction = "prof.phone"
+	addProfileAddressCallback          callbackAction = "prof.addr.add"
+	removeProfileAddressCallback       callbackAction = "prof.addr.rm"
+	pickupProviderCDEKCallback         callbackAction = "pp.cdek"
+	pickupProviderPickPointCallback    callbackAction = "pp.pickpoint"
+	repeatOrderCallback                callbackAction = "ord.repeat"
+	myOrdersPageCallback               callbackAction = "ord.page"
+	orderDetailsCallback               callbackAction = "ord.details"
+	catalogPrevCallback                callbackAction = "catalog.prev"
+	catalogNextCallback                callbackAction = "catalog.next"
+	answerQuestionCallback             callbackAction = "faq.answer"
+	flowBackCallback                   callbackAction = "flow.back"
+	flowCancelCallback                 callbackAction = "flow.cancel"
+	menuLanguageCallback               callbackAction = "m.lang"
+	setLanguageCallback                callbackAction = "lang.set"
 )
 
+// Guide steps are 0-based, step is carried in guide callback along with guide message ids
 const (
-	editCartRemovePositionOffset = 1000
-	catalogOffset                = 1200
-	faqOffset                    = 1400
+	firstOrderGuideStep = 0
+	lastOrderGuideStep  = 5
 )
 
-const (
-	catalogPrevCallback = iota + 1
-	catalogNextCallback
-)
-
-var (
-	initialMenuKeyboard                 = initialBottomMenu()
-	menuButtons                         = menu()
-	selectColorButtons                  = selectButtonColor()
-	bottomMenuButtons                   = bottomMenu()
-	bottomMenuWithoutAddPositionButtons = bottomMenuWithoutAddPosition()
-	cartPreviewButtons                  = cartPreview()
-	addPositionButtons                  = addPos()
-	makeOrderButtons                    = makeOrder()
-	orderTypeButtons                    = orderType()
-	orderTypeCalculatorButtons          = orderTypeCalculator()
-	categoryButtons                     = category(false)
-	categoryCalculatorButtons           = category(true)
-	calculateMoreButtons                = calculateMore()
-	askMoreFaqButtons                   = askMoreFaq()
-)
-
-func injectMessageIDs(callback int, msgIDs ...int) string {
-	var msgIDstr string
-	for i, m := range msgIDs {
-		if i < len(msgIDs)-1 {
-			msgIDstr += strconv.Itoa(m) + ","
-		} else {
-			msgIDstr += strconv.Itoa(m)
-		}
-	}
-	return "m" + msgIDstr + ":" + strconv.Itoa(callback)
-}
-
-func injectStringData(callback int, str string) string {
-	return "s" + str + ":" + strconv.Itoa(callback)
-}
-
-func parseStringCallbackData(data string) (payload string, callback int, err error) {
-	data = data[1:]
-	var colonIdx int
-	for i, ch := range data {
-		if ch == ':' {
-			colonIdx = i
-			break
-		}
-	}
-	callback, err = strconv.Atoi(data[colonIdx+1:])
-	if err != nil {
-		return "", 0, err
-	}
-
-	return data[0:colonIdx], callback, nil
-}
-
-func parseCallbackData(data string) (injectedData any, callback int, err error) {
-	// raw callback
-	if !strings.ContainsRune(data, ':') {
-		callback, err := strconv.Atoi(data)
-		if err != nil {
-			return nil, 0, fmt.Errorf("strconv.Atoi: %w", err)
-		}
-		return nil, callback, nil
-	}
-
-	prefix := data[0]
-	// means message id's are injected
-	if prefix == 'm' {
-		var (
-			msgIDstrs []string
-			msgIDints []int
-		)
-		spl := strings.Split(data[1:], ":")
-		msgIDstrs = strings.Split(spl[0], ",")
-		cbStr := spl[1]
-
-		for _, m := range msgIDstrs {
-			mInt, err := strconv.Atoi(m)
-			if err != nil {
-				return nil, 0, fmt.Errorf("strconv.Atoi msgID: %w", err)
-			}
-			msgIDints = append(msgIDints, mInt)
-		}
-
-		cbInt, err := strconv.Atoi(cbStr)
-		if err != nil {
-			return nil, 0, fmt.Errorf("strconv.Atoi cb: %w", err)
-		}
+// shortIDsSeparator joins short ids of linked orders in a single payment callback
+const shortIDsSeparator = ","
 
-		return msgIDints, cbInt, nil
-	}
-
-	// string data encoded
-	if prefix == 's' {
-		return parseStringCallbackData(data)
-	}
-
-	return
-}
-
-func menu() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) menuButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Каталог", strconv.Itoa(menuCatalogCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.catalog"), c.callback(ctx, menuCatalogCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.make_order"), c.callback(ctx, menuMakeOrderCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Сделать заказ", strconv.Itoa(menuMakeOrderCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.calculator"), c.callback(ctx, menuCalculatorCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Калькулятор стоимости", strconv.Itoa(menuCalculatorCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.faq"), c.callback(ctx, menuFaqCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Вопросы", strconv.Itoa(menuFaqCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.my_orders"), c.callback(ctx, menuMyOrdersCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Мои заказы", strconv.Itoa(menuMyOrdersCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.favourites"), c.callback(ctx, menuFavouritesCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.profile"), c.callback(ctx, menuProfileCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.drops"), c.callback(ctx, toggleDropsSubscriptionCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.language"), c.callback(ctx, menuLanguageCallback)),
 		),
 	)
 }
 
-func prepareOrderGuideButtons(step int, msgIDs ...int) tg.InlineKeyboardMarkup {
-	if step == orderGuideStep5Callback {
+func (c *CallbackCodec) prepareOrderGuideButtons(ctx context.Context, step int, msgIDs ...int) tg.InlineKeyboardMarkup {
+	if step == lastOrderGuideStep {
 		return tg.NewInlineKeyboardMarkup(
 			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData(arrLeft, injectMessageIDs(step-1, msgIDs...)),
+				tg.NewInlineKeyboardButtonData(arrLeft, c.orderGuideCallback(ctx, step-1, msgIDs)),
 			),
 		)
-	} else if step == orderGuideStep0Callback {
+	} else if step == firstOrderGuideStep {
 		return tg.NewInlineKeyboardMarkup(
 			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData(arrRight, injectMessageIDs(step+1, msgIDs...)),
+				tg.NewInlineKeyboardButtonData(arrRight, c.orderGuideCallback(ctx, step+1, msgIDs)),
 			),
 		)
 	}
 
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData(arrLeft, injectMessageIDs(step-1, msgIDs...)),
-			tg.NewInlineKeyboardButtonData(arrRight, injectMessageIDs(step+1, msgIDs...)),
+			tg.NewInlineKeyboardButtonData(arrLeft, c.orderGuideCallback(ctx, step-1, msgIDs)),
+			tg.NewInlineKeyboardButtonData(arrRight, c.orderGuideCallback(ctx, step+1, msgIDs)),
 		),
 	)
 }
 
-func selectButtonColor() tg.InlineKeyboardMarkup {
+// orderGuideCallback carries step to show and guide message ids
+func (c *CallbackCodec) orderGuideCallback(ctx context.Context, step int, msgIDs []int) string {
+	return c.callbackWithInts(ctx, orderGuideStepCallback, append([]int{step}, msgIDs...)...)
+}
+
+func (c *CallbackCodec) selectColorButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Бирюзовый", strconv.Itoa(buttonTorqoiseSelectCallback)),
-			tg.NewInlineKeyboardButtonData("Серый", strconv.Itoa(buttonGreySelectCallback)),
-			tg.NewInlineKeyboardButtonData("95% БУ", strconv.Itoa(button95SelectCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button_color.torquoise"), c.callback(ctx, buttonTorqoiseSelectCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button_color.grey"), c.callback(ctx, buttonGreySelectCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button_color.95_select"), c.callback(ctx, button95SelectCallback)),
 		),
 	)
 }
 
-func bottomMenu() tg.ReplyKeyboardMarkup {
+// Reply keyboard buttons send their text, router recognizes it in any language, see Router.command
+func bottomMenuButtons(ctx context.Context) tg.ReplyKeyboardMarkup {
 	return tg.NewReplyKeyboard(
 		tg.NewKeyboardButtonRow(
-			tg.NewKeyboardButton(menuCommand),
+			tg.NewKeyboardButton(tr(ctx, menuCommand)),
 		),
 		tg.NewKeyboardButtonRow(
-			tg.NewKeyboardButton(getCartCommand),
+			tg.NewKeyboardButton(tr(ctx, getCartCommand)),
 		),
 		tg.NewKeyboardButtonRow(
-			tg.NewKeyboardButton(addPositionCommand),
+			tg.NewKeyboardButton(tr(ctx, addPositionCommand)),
 		),
 	)
 }
 
-func bottomMenuWithoutAddPosition() tg.ReplyKeyboardMarkup {
+func bottomMenuWithoutAddPositionButtons(ctx context.Context) tg.ReplyKeyboardMarkup {
 	return tg.NewReplyKeyboard(
 		tg.NewKeyboardButtonRow(
-			tg.NewKeyboardButton(menuCommand),
+			tg.NewKeyboardButton(tr(ctx, menuCommand)),
 		),
 		tg.NewKeyboardButtonRow(
-			tg.NewKeyboardButton(getCartCommand),
+			tg.NewKeyboardButton(tr(ctx, getCartCommand)),
 		),
 	)
 }
 
-func initialBottomMenu() tg.ReplyKeyboardMarkup {
+func initialMenuKeyboard(ctx context.Context) tg.ReplyKeyboardMarkup {
 	return tg.NewReplyKeyboard(
 		tg.NewKeyboardButtonRow(
-			tg.NewKeyboardButton(menuCommand),
+			tg.NewKeyboardButton(tr(ctx, menuCommand)),
 		),
 	)
 }
 
-func cartPreview() tg.InlineKeyboardMarkup {
-	return tg.NewInlineKeyboardMarkup(
+func shareContactKeyboard(ctx context.Context) tg.ReplyKeyboardMarkup {
+	keyboard := tg.NewOneTimeReplyKeyboard(
+		tg.NewKeyboardButtonRow(
+			tg.NewKeyboardButtonContact(tr(ctx, "button.share_contact")),
+		),
+		tg.NewKeyboardButtonRow(
+			tg.NewKeyboardButton(tr(ctx, menuCommand)),
+		),
+	)
+	keyboard.ResizeKeyboard = true
+	return keyboard
+}
+
+// prepareCartPreviewButtons has quantity controls for every position
+func (c *CallbackCodec) prepareCartPreviewButtons(ctx context.Context, cart domain.Cart) tg.InlineKeyboardMarkup {
+	rows := make([][]tg.InlineKeyboardButton, 0, len(cart)+2)
+	for i, p := range cart {
+		positionN := strconv.Itoa(i + 1)
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData("➖", c.callbackWithString(ctx, decreaseQuantityCallback, positionN)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.quantity", positionN, p.GetQuantity()), c.callback(ctx, noopCallback)),
+			tg.NewInlineKeyboardButtonData("➕", c.callbackWithString(ctx, increaseQuantityCallback, positionN)),
+		))
+	}
+	return tg.NewInlineKeyboardMarkup(append(rows,
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Оформить заказ", strconv.Itoa(makeOrderCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.checkout"), c.callback(ctx, makeOrderCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Редактировать корзину", strconv.Itoa(editCartCallback)),
-			tg.NewInlineKeyboardButtonData("Добавить позицию", strconv.Itoa(addPositionCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_cart"), c.callback(ctx, editCartCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.add_position"), c.callback(ctx, addPositionCallback)),
 		),
-	)
+	)...)
 }
 
-func addPos() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) addPositionButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Добавить позицию", strconv.Itoa(addPositionCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.add_position"), c.callback(ctx, addPositionCallback)),
 		))
 }
 
-func makeOrder() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) makeOrderButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Сделать заказ", strconv.Itoa(addPositionCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "menu.make_order"), c.callback(ctx, addPositionCallback)),
 		))
 }
 
-func prepareEditCartButtons(n int, previewCartMsgID int) tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) prepareEditCartButtons(ctx context.Context, n int, previewCartMsgID int) tg.InlineKeyboardMarkup {
 	keyboard := make([][]tg.InlineKeyboardButton, 0)
 
 	var (
@@ -281,27 +252,53 @@ func prepareEditCartButtons(n int, previewCartMsgID int) tg.InlineKeyboardMarkup
 	for row := 0; row < numRows; row++ {
 		keyboard = append(keyboard, tg.NewInlineKeyboardRow())
 		for col := 0; col < 3 && current < n; col++ {
-			button := tg.NewInlineKeyboardButtonData(strconv.Itoa(current+1), injectMessageIDs(editCartRemovePositionOffset+current+1, previewCartMsgID))
+			button := tg.NewInlineKeyboardButtonData(strconv.Itoa(current+1), c.callbackWithInts(ctx, removeCartPositionCallback, current+1, previewCartMsgID))
 			keyboard[row] = append(keyboard[row], button)
 			current++
 		}
 	}
 
+	// Same grid for editing
+	current = 0
+	for row := 0; row < numRows; row++ {
+		editRow := tg.NewInlineKeyboardRow()
+		for col := 0; col < 3 && current < n; col++ {
+			button := tg.NewInlineKeyboardButtonData("✏️ "+strconv.Itoa(current+1), c.callbackWithString(ctx, editPositionCallback, strconv.Itoa(current+1)))
+			editRow = append(editRow, button)
+			current++
+		}
+		keyboard = append(keyboard, editRow)
+	}
+
 	return tg.NewInlineKeyboardMarkup(keyboard...)
 }
 
-func orderType() tg.InlineKeyboardMarkup {
+// preparePositionEditButtons allows to change single field of n-th position
+func (c *CallbackCodec) preparePositionEditButtons(ctx context.Context, n int) tg.InlineKeyboardMarkup {
+	positionN := strconv.Itoa(n)
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_size"), c.callbackWithString(ctx, editPositionSizeCallback, positionN)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_button_color"), c.callbackWithString(ctx, editPositionButtonCallback, positionN)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_price"), c.callbackWithString(ctx, editPositionPriceCallback, positionN)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_link"), c.callbackWithString(ctx, editPositionLinkCallback, positionN)),
+		))
+}
+
+func (c *CallbackCodec) orderTypeButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Экспресс ~ 4 дня", strconv.Itoa(orderTypeExpressCallback)),
-			tg.NewInlineKeyboardButtonData("Обычный 8~15 дней", strconv.Itoa(orderTypeNormalCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.order_type_express"), c.callback(ctx, orderTypeExpressCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.order_type_normal"), c.callback(ctx, orderTypeNormalCallback)),
 		))
 }
 
-func preparePaymentButton(orderShortID string) tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) preparePaymentButton(ctx context.Context, orderShortID string) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Оплачено", injectStringData(paymentCallback, orderShortID)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.paid"), c.callbackWithString(ctx, paymentCallback, orderShortID)),
 		))
 }
 
@@ -309,132 +306,312 @@ type catalogButtonsArgs struct {
 	hasNext, hasPrev     bool
 	nextTitle, prevTitle string
 	msgIDs               []int
+	// Item currently shown, hex
+	itemID string
 }
 
-func prepareCatalogButtons(args catalogButtonsArgs) tg.InlineKeyboardMarkup {
-	if args.hasNext && args.hasPrev {
-		return tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData(arrLeft+" "+args.prevTitle, injectMessageIDs(catalogOffset+catalogPrevCallback, args.msgIDs...)),
-				tg.NewInlineKeyboardButtonData(args.nextTitle+" "+arrRight, injectMessageIDs(catalogOffset+catalogNextCallback, args.msgIDs...)),
-			))
-	} else if args.hasNext {
-		return tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData(args.nextTitle+" "+arrRight, injectMessageIDs(catalogOffset+catalogNextCallback, args.msgIDs...)),
-			))
+func (c *CallbackCodec) prepareCatalogButtons(ctx context.Context, args catalogButtonsArgs) tg.InlineKeyboardMarkup {
+	var controls []tg.InlineKeyboardButton
+	if args.hasPrev {
+		controls = append(controls, tg.NewInlineKeyboardButtonData(arrLeft+" "+args.prevTitle, c.callbackWithInts(ctx, catalogPrevCallback, args.msgIDs...)))
+	}
+	if args.hasNext {
+		controls = append(controls, tg.NewInlineKeyboardButtonData(args.nextTitle+" "+arrRight, c.callbackWithInts(ctx, catalogNextCallback, args.msgIDs...)))
+	}
+
+	var rows [][]tg.InlineKeyboardButton
+	if len(controls) > 0 {
+		rows = append(rows, tg.NewInlineKeyboardRow(controls...))
+	}
+	rows = append(rows, tg.NewInlineKeyboardRow(
+		tg.NewInlineKeyboardButtonData(tr(ctx, "button.add_to_favourites"), c.callbackWithString(ctx, addToFavouritesCallback, args.itemID)),
+	))
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
+
+func (c *CallbackCodec) prepareFavouritesButtons(ctx context.Context, items []domain.CatalogItem) tg.InlineKeyboardMarkup {
+	rows := make([][]tg.InlineKeyboardButton, 0, len(items)+1)
+	for _, item := range items {
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData("✖ "+item.Title, c.callbackWithString(ctx, removeFromFavouritesCallback, item.ItemID.Hex())),
+		))
+	}
+	rows = append(rows, tg.NewInlineKeyboardRow(
+		tg.NewInlineKeyboardButtonData(tr(ctx, "button.open_catalog"), c.callback(ctx, menuCatalogCallback)),
+	))
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
+
+// prepareFlowNavigationButtons is added to every step prompt, there's nothing to go back to from the first step
+func (c *CallbackCodec) prepareFlowNavigationButtons(ctx context.Context, hasBack bool) tg.InlineKeyboardMarkup {
+	row := make([]tg.InlineKeyboardButton, 0, 2)
+	if hasBack {
+		row = append(row, tg.NewInlineKeyboardButtonData(tr(ctx, "button.flow_back"), c.callback(ctx, flowBackCallback)))
+	}
+	row = append(row, tg.NewInlineKeyboardButtonData(tr(ctx, "button.flow_cancel"), c.callback(ctx, flowCancelCallback)))
+	return tg.NewInlineKeyboardMarkup(row)
+}
+
+func (c *CallbackCodec) savedDetailsButtons(ctx context.Context) tg.InlineKeyboardMarkup {
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.use_saved_details"), c.callback(ctx, useSavedDetailsCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.enter_new_details"), c.callback(ctx, enterNewDetailsCallback)),
+		),
+	)
+}
+
+func (c *CallbackCodec) pickupProviderButtons(ctx context.Context) tg.InlineKeyboardMarkup {
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(getPickupProviderText(ctx, domain.PickupProviderCDEK), c.callback(ctx, pickupProviderCDEKCallback)),
+			tg.NewInlineKeyboardButtonData(getPickupProviderText(ctx, domain.PickupProviderPickPoint), c.callback(ctx, pickupProviderPickPointCallback)),
+		),
+	)
+}
+
+// hasDeliveryAddresses reports whether any of addresses is usable for delivery
+func hasDeliveryAddresses(addresses []domain.Address) bool {
+	for _, a := range addresses {
+		if a.IsStructured() {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareSavedAddressesButtons returns false if there're no addresses usable for delivery
+func (c *CallbackCodec) prepareSavedAddressesButtons(ctx context.Context, addresses []domain.Address) (tg.InlineKeyboardMarkup, bool) {
+	rows := make([][]tg.InlineKeyboardButton, 0, len(addresses)+1)
+	for i, a := range addresses {
+		if !a.IsStructured() {
+			continue
+		}
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData("📍 "+a.Name, c.callbackWithString(ctx, useSavedAddressCallback, strconv.Itoa(i+1))),
+		))
+	}
+	if len(rows) == 0 {
+		return tg.InlineKeyboardMarkup{}, false
+	}
+	rows = append(rows, tg.NewInlineKeyboardRow(
+		tg.NewInlineKeyboardButtonData(tr(ctx, "button.new_address"), c.callback(ctx, newDeliveryAddressCallback)),
+	))
+	return tg.NewInlineKeyboardMarkup(rows...), true
+}
+
+func (c *CallbackCodec) prepareProfileButtons(ctx context.Context, customer domain.Customer) tg.InlineKeyboardMarkup {
+	rows := [][]tg.InlineKeyboardButton{
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_full_name"), c.callback(ctx, editProfileFIOCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.edit_phone_number"), c.callback(ctx, editProfilePhoneNumberCallback)),
+		),
+	}
+	for i, a := range customer.Addresses {
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData("✖ "+a.Name, c.callbackWithString(ctx, removeProfileAddressCallback, strconv.Itoa(i+1))),
+		))
+	}
+	if len(customer.Addresses) < domain.MaxAddresses {
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.add_address"), c.callback(ctx, addProfileAddressCallback)),
+		))
+	}
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
+
+var ordersFilterTitles = []struct {
+	filter domain.OrdersFilter
+	title  string
+}{
+	{filter: domain.OrdersFilterAll, title: "button.orders_all"},
+	{filter: domain.OrdersFilterActive, title: "button.orders_active"},
+	{filter: domain.OrdersFilterCompleted, title: "button.orders_completed"},
+}
+
+func (c *CallbackCodec) prepareMyOrdersButtons(ctx context.Context, orders []domain.Order, p myOrdersPage, nPages int) tg.InlineKeyboardMarkup {
+	rows := make([][]tg.InlineKeyboardButton, 0, len(orders)+2)
+	for _, o := range orders {
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.order", o.ShortID), c.callbackWithString(ctx, orderDetailsCallback, o.ShortID)),
+		))
+	}
+
+	var nav []tg.InlineKeyboardButton
+	if p.page > 0 {
+		prev := myOrdersPage{filter: p.filter, page: p.page - 1}
+		nav = append(nav, tg.NewInlineKeyboardButtonData(arrLeft, c.callbackWithString(ctx, myOrdersPageCallback, prev.String())))
+	}
+	if p.page+1 < nPages {
+		next := myOrdersPage{filter: p.filter, page: p.page + 1}
+		nav = append(nav, tg.NewInlineKeyboardButtonData(arrRight, c.callbackWithString(ctx, myOrdersPageCallback, next.String())))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	filters := make([]tg.InlineKeyboardButton, 0, len(ordersFilterTitles))
+	for _, f := range ordersFilterTitles {
+		// Selected filter is not clickable, list wouldn't change
+		if f.filter == p.filter {
+			filters = append(filters, tg.NewInlineKeyboardButtonData("• "+tr(ctx, f.title), c.callback(ctx, noopCallback)))
+			continue
+		}
+		first := myOrdersPage{filter: f.filter}
+		filters = append(filters, tg.NewInlineKeyboardButtonData(tr(ctx, f.title), c.callbackWithString(ctx, myOrdersPageCallback, first.String())))
 	}
+	return tg.NewInlineKeyboardMarkup(append(rows, filters)...)
+}
 
-	// only prev
+func (c *CallbackCodec) prepareRepeatOrderButtons(ctx context.Context, shortOrderID string) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData(arrLeft+" "+args.prevTitle, injectMessageIDs(catalogOffset+catalogPrevCallback, args.msgIDs...)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.repeat_order"), c.callbackWithString(ctx, repeatOrderCallback, shortOrderID)),
 		))
 }
 
-func prepareAfterPaidButtons(shortOrderId string) tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) prepareAfterPaidButtons(ctx context.Context, shortOrderId string) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData(fmt.Sprintf("Заказ %s оплачен ✅", shortOrderId), strconv.Itoa(noopCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.order_paid", shortOrderId), c.callback(ctx, noopCallback)),
 		))
 }
 
-func orderTypeCalculator() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) orderTypeCalculatorButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Экспресс ~ 4 дня", strconv.Itoa(orderTypeExpressCalculatorCallback)),
-			tg.NewInlineKeyboardButtonData("Обычный 8~15 дней", strconv.Itoa(orderTypeNormalCalculatorCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.order_type_express"), c.callback(ctx, orderTypeExpressCalculatorCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.order_type_normal"), c.callback(ctx, orderTypeNormalCalculatorCallback)),
 		))
 }
 
-func calculateMore() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) calculateMoreButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Посчитать еще!", strconv.Itoa(calculateMoreCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.calculate_more"), c.callback(ctx, calculateMoreCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Другая категория", strconv.Itoa(selectCategoryAgainCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.another_category"), c.callback(ctx, selectCategoryAgainCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Добавить позицию", strconv.Itoa(addPositionCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.add_position"), c.callback(ctx, addPositionCallback)),
 		),
 	)
 }
 
-func prepareFaqButtons() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) prepareFaqButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	questionsByLevel := questions
 
 	_ = questionsByLevel[2]
 	dinoq, bossq, masterq := questionsByLevel[0], questionsByLevel[1], questionsByLevel[2]
 
 	// noop buttons
-	dinoNoOp := tg.NewInlineKeyboardButtonData("Уровень «динозавр» 🦖", strconv.Itoa(noopCallback))
-	bossNoOp := tg.NewInlineKeyboardButtonData("Уровень «boss of the gym» 🐅", strconv.Itoa(noopCallback))
-	masterNoOp := tg.NewInlineKeyboardButtonData("Уровень «dungeon master» 🦈", strconv.Itoa(noopCallback))
+	dinoNoOp := tg.NewInlineKeyboardButtonData(tr(ctx, "faq.level_dino"), c.callback(ctx, noopCallback))
+	bossNoOp := tg.NewInlineKeyboardButtonData(tr(ctx, "faq.level_boss"), c.callback(ctx, noopCallback))
+	masterNoOp := tg.NewInlineKeyboardButtonData(tr(ctx, "faq.level_master"), c.callback(ctx, noopCallback))
 
 	// prepare buttons
 
 	// firstly goes noop, then n questions
 	var rows [][]tg.InlineKeyboardButton
 
-	questionIndexForCallback := 1 + faqOffset
+	questionN := 1
 
 	// row 1
 	rows = append(rows, tg.NewInlineKeyboardRow(dinoNoOp))
 	for _, q := range dinoq {
-		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(q, strconv.Itoa(questionIndexForCallback))))
-		questionIndexForCallback++
+		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(tr(ctx, q), c.callbackWithInts(ctx, answerQuestionCallback, questionN))))
+		questionN++
 	}
-	rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(" ", strconv.Itoa(noopCallback))))
+	rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(" ", c.callback(ctx, noopCallback))))
 
 	// row 2
 	rows = append(rows, tg.NewInlineKeyboardRow(bossNoOp))
 	for _, q := range bossq {
-		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(q, strconv.Itoa(questionIndexForCallback))))
-		questionIndexForCallback++
+		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(tr(ctx, q), c.callbackWithInts(ctx, answerQuestionCallback, questionN))))
+		questionN++
 	}
-	rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(" ", strconv.Itoa(noopCallback))))
+	rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(" ", c.callback(ctx, noopCallback))))
 
 	// row 3
 	rows = append(rows, tg.NewInlineKeyboardRow(masterNoOp))
 	for _, q := range masterq {
-		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(q, strconv.Itoa(questionIndexForCallback))))
-		questionIndexForCallback++
+		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(tr(ctx, q), c.callbackWithInts(ctx, answerQuestionCallback, questionN))))
+		questionN++
 	}
 
 	return tg.NewInlineKeyboardMarkup(rows...)
 }
 
-func askMoreFaq() tg.InlineKeyboardMarkup {
+func (c *CallbackCodec) dropNotificationButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Жми", strconv.Itoa(menuFaqCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.open_catalog"), c.callback(ctx, menuCatalogCallback)),
 		))
 }
 
-func category(forCalculator bool) tg.InlineKeyboardMarkup {
-	if forCalculator {
-		return tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Парфюм/аксессуары/косметика ~ 0.5 кг", strconv.Itoa(categoryOtherCalculatorCallback)),
-			),
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Легкая одежда ~ 1.6 кг", strconv.Itoa(categoryLightCalculatorCallback)),
-			),
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Тяжелая одежда ~ 2.6 кг", strconv.Itoa(categoryHeavyCalculatorCallback)),
-			),
-		)
-	}
+func (c *CallbackCodec) cartReminderButtons(ctx context.Context) tg.InlineKeyboardMarkup {
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.checkout"), c.callback(ctx, makeOrderCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.cart_reminders_off"), c.callback(ctx, cartRemindersOptOutCallback)),
+		))
+}
+
+func (c *CallbackCodec) askMoreFaqButtons(ctx context.Context) tg.InlineKeyboardMarkup {
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.ask_more"), c.callback(ctx, menuFaqCallback)),
+		))
+}
+
+func (c *CallbackCodec) categoryButtons(ctx context.Context) tg.InlineKeyboardMarkup {
 	return tg.NewInlineKeyboardMarkup(
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Парфюм, аксессуары и др. ~ 0.5 кг", strconv.Itoa(categoryOtherCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.category_other"), c.callback(ctx, categoryOtherCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Легкая одежда ~ 1.6 кг", strconv.Itoa(categoryLightCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.category_light"), c.callback(ctx, categoryLightCallback)),
 		),
 		tg.NewInlineKeyboardRow(
-			tg.NewInlineKeyboardButtonData("Тяжелая одежда ~ 2.6 кг", strconv.Itoa(categoryHeavyCallback)),
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.category_heavy"), c.callback(ctx, categoryHeavyCallback)),
 		),
 	)
 }
+
+func (c *CallbackCodec) categoryCalculatorButtons(ctx context.Context) tg.InlineKeyboardMarkup {
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.category_other_calculator"), c.callback(ctx, categoryOtherCalculatorCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.category_light"), c.callback(ctx, categoryLightCalculatorCallback)),
+		),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(tr(ctx, "button.category_heavy"), c.callback(ctx, categoryHeavyCalculatorCallback)),
+		),
+	)
+}
+
+// prepareLanguageButtons has every language in its own language, current one is marked
+func (c *CallbackCodec) prepareLanguageButtons(ctx context.Context) tg.InlineKeyboardMarkup {
+	var (
+		current = language(ctx)
+		langs   = bundle().Languages()
+		rows    = make([][]tg.InlineKeyboardButton, 0, len(langs))
+	)
+	for _, lang := range langs {
+		title := bundle().Get(lang, "language.name")
+		if lang == current {
+			title = "• " + title
+		}
+		rows = append(rows, tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(title, c.callbackWithString(ctx, setLanguageCallback, lang)),
+		))
+	}
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
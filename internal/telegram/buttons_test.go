@@ -1,57 +1,171 @@
 package telegram
 
 import (
+	"context"
 	"math"
-	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func TestInjectAndParseCallback(t *testing.T) {
-	t.Run("inject message ids and parse", func(t *testing.T) {
-		t.Parallel()
-		type testcase struct {
-			msgIDs   []int
-			callback int
-		}
-		var testcases []testcase
-		for i := int(-math.MaxInt16); i < math.MaxInt16; i++ {
-			testcases = append(testcases, testcase{msgIDs: []int{i, i + 1, i + 2}, callback: int(math.MaxInt16 - i)})
-		}
+// memoryCallbackStore is repositories.Callback kept in memory
+type memoryCallbackStore struct {
+	callbacks map[string]domain.StoredCallback
+}
+
+func newMemoryCallbackStore() *memoryCallbackStore {
+	return &memoryCallbackStore{callbacks: make(map[string]domain.StoredCallback)}
+}
+
+func (m *memoryCallbackStore) Save(ctx context.Context, callback domain.StoredCallback) error {
+	m.callbacks[callback.Key] = callback
+	return nil
+}
+
+func (m *memoryCallbackStore) Get(ctx context.Context, key string) (domain.StoredCallback, error) {
+	callback, ok := m.callbacks[key]
+	if !ok {
+		return domain.StoredCallback{}, domain.ErrCallbackNotFound
+	}
+	return callback, nil
+}
 
+func TestCallbackCodec(t *testing.T) {
+	ctx := context.Background()
+	t.Run("encode and decode", func(t *testing.T) {
+		c := NewCallbackCodec(newMemoryCallbackStore())
+		testcases := []callbackData{
+			{action: noopCallback},
+			{action: catalogNextCallback, ints: []int{1, 2, 3}},
+			{action: orderGuideStepCallback, ints: []int{math.MaxInt32, math.MinInt32, 0}},
+			{action: addToFavouritesCallback, str: primitive.NewObjectID().Hex()},
+			{action: myOrdersPageCallback, ints: []int{-1}, str: "1:0"},
+		}
 		for _, tc := range testcases {
-			data := injectMessageIDs(tc.callback, tc.msgIDs...)
-			require.NotZero(t, data)
-			gotMsgIDs, callback, err := parseCallbackData(data)
-			intMsgIDs, ok := gotMsgIDs.([]int)
-			require.True(t, ok)
+			data := c.encode(ctx, tc)
+			require.LessOrEqual(t, len(data), maxCallbackDataLen)
+			out, err := c.decode(ctx, data)
 			require.NoError(t, err)
-			require.EqualValues(t, tc.msgIDs, intMsgIDs)
-			require.Equal(t, tc.callback, callback)
+			require.Equal(t, tc, out)
 		}
 	})
 
-	t.Run("inject string data and parse", func(t *testing.T) {
-		t.Parallel()
-		type testcase struct {
-			payload  string
-			callback int
-		}
-		var testcases []testcase
-		for i := int(math.MaxInt16); i < math.MaxInt16; i++ {
-			testcases = append(testcases, testcase{payload: strconv.Itoa(i), callback: int(math.MaxInt16 - i)})
-		}
+	t.Run("large payload is stored server-side", func(t *testing.T) {
+		c := NewCallbackCodec(newMemoryCallbackStore())
+		in := callbackData{action: paymentCallback, str: strings.Repeat("ABCDEF,", 20)}
+		data := c.encode(ctx, in)
+		require.LessOrEqual(t, len(data), maxCallbackDataLen)
+		require.Contains(t, data, string(callbackStoredSep))
+		out, err := c.decode(ctx, data)
+		require.NoError(t, err)
+		require.Equal(t, in, out)
 
+		// Unknown key
+		_, err = c.decode(ctx, "1"+string(paymentCallback)+string(callbackStoredSep)+newCallbackKey())
+		require.ErrorIs(t, err, ErrCallbackExpired)
+	})
+
+	t.Run("malformed data", func(t *testing.T) {
+		c := NewCallbackCodec(newMemoryCallbackStore())
+		testcases := []string{
+			"",
+			"1",
+			"1:AA",
+			// legacy format
+			"sA1:55",
+			"m1,2:1201",
+			// unknown version
+			"2noop",
+			// invalid base64
+			"1pay:!!",
+			// ints count is larger than payload
+			"1pay:" + callbackEncoding.EncodeToString([]byte{5, 2}),
+			// truncated varint
+			"1pay:" + callbackEncoding.EncodeToString([]byte{1, 0x80}),
+			strings.Repeat("1", maxCallbackDataLen+1),
+		}
 		for _, tc := range testcases {
-			data := injectStringData(tc.callback, tc.payload)
-			require.NotZero(t, data)
-			out, callback, err := parseCallbackData(data)
-			require.NoError(t, err)
-			require.Equal(t, tc.callback, callback)
-			strOut, ok := out.(string)
-			require.True(t, ok)
-			require.Equal(t, tc.payload, strOut)
+			_, err := c.decode(ctx, tc)
+			require.ErrorIs(t, err, ErrInvalidCallback, tc)
 		}
 	})
+
+	t.Run("intAt", func(t *testing.T) {
+		d := callbackData{action: answerQuestionCallback, ints: []int{7}}
+		n, err := d.intAt(0)
+		require.NoError(t, err)
+		require.Equal(t, 7, n)
+		_, err = d.intAt(1)
+		require.ErrorIs(t, err, ErrInvalidCallback)
+	})
+}
+
+func TestCallbackCodecExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewCallbackCodec(newMemoryCallbackStore())
+	c.now = func() time.Time { return now }
+
+	data := c.callbackWithString(ctx, paymentCallback, strings.Repeat("ABCDEF,", 20))
+	_, err := c.decode(ctx, data)
+	require.NoError(t, err)
+
+	// Mongo removes expired documents with delay
+	now = now.Add(callbackStoreTTL + time.Second)
+	_, err = c.decode(ctx, data)
+	require.ErrorIs(t, err, ErrCallbackExpired)
+}
+
+func TestPrepareCatalogButtons(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		c      = NewCallbackCodec(newMemoryCallbackStore())
+		itemID = primitive.NewObjectID().Hex()
+	)
+
+	// Single item catalog still has save button
+	buttons := c.prepareCatalogButtons(ctx, catalogButtonsArgs{itemID: itemID})
+	require.Len(t, buttons.InlineKeyboard, 1)
+	save := buttons.InlineKeyboard[0][0]
+	data, err := c.decode(ctx, *save.CallbackData)
+	require.NoError(t, err)
+	require.Equal(t, addToFavouritesCallback, data.action)
+	require.Equal(t, itemID, data.str)
+	// Telegram limits callback data to 64 bytes
+	require.LessOrEqual(t, len(*save.CallbackData), 64)
+
+	buttons = c.prepareCatalogButtons(ctx, catalogButtonsArgs{
+		hasNext: true,
+		hasPrev: true,
+		msgIDs:  []int{1, 2},
+		itemID:  itemID,
+	})
+	require.Len(t, buttons.InlineKeyboard, 2)
+	require.Len(t, buttons.InlineKeyboard[0], 2)
+}
+
+func TestPrepareEditCartButtons(t *testing.T) {
+	var (
+		ctx = context.Background()
+		c   = NewCallbackCodec(newMemoryCallbackStore())
+	)
+	buttons := c.prepareEditCartButtons(ctx, 4, 10)
+	// 2 rows to remove and 2 rows to edit
+	require.Len(t, buttons.InlineKeyboard, 4)
+
+	remove := buttons.InlineKeyboard[1][0]
+	data, err := c.decode(ctx, *remove.CallbackData)
+	require.NoError(t, err)
+	require.Equal(t, removeCartPositionCallback, data.action)
+	require.Equal(t, []int{4, 10}, data.ints)
+
+	edit := buttons.InlineKeyboard[2][1]
+	data, err = c.decode(ctx, *edit.CallbackData)
+	require.NoError(t, err)
+	require.Equal(t, editPositionCallback, data.action)
+	require.Equal(t, "2", data.str)
 }
@@ -0,0 +1,231 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Callback data layout:
+//
+//	<version><action>                 - no payload
+//	<version><action>:<base64 payload> - payload inlined
+//	<version><action>@<key>            - payload is too large, stored server-side
+//
+// Payload is uvarint count of ints, zigzag varint ints and then raw string bytes.
+const (
+	callbackVersion = '1'
+
+	callbackPayloadSep = ':'
+	callbackStoredSep  = '@'
+
+	// Telegram limits callback data to 64 bytes
+	maxCallbackDataLen = 64
+
+	callbackStoreTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidCallback = errors.New("invalid callback data")
+	ErrCallbackExpired = errors.New("callback data expired")
+)
+
+var callbackEncoding = base64.RawURLEncoding
+
+// callbackAction is a stable name of button action. Renaming an action breaks already sent buttons
+type callbackAction string
+
+type callbackData struct {
+	action callbackAction
+	ints   []int
+	str    string
+}
+
+func (d callbackData) hasPayload() bool {
+	return len(d.ints) > 0 || d.str != ""
+}
+
+// intAt returns i-th int from payload
+func (d callbackData) intAt(i int) (int, error) {
+	if i >= len(d.ints) {
+		return 0, fmt.Errorf("%w: %s has no int at %d", ErrInvalidCallback, d.action, i)
+	}
+	return d.ints[i], nil
+}
+
+// CallbackCodec encodes callback data of buttons and decodes it back when button is pressed.
+// Payloads that don't fit into callback data are kept in store, so buttons survive restarts
+type CallbackCodec struct {
+	store repositories.Callback
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func NewCallbackCodec(store repositories.Callback) *CallbackCodec {
+	return &CallbackCodec{
+		store: store,
+		ttl:   callbackStoreTTL,
+		now:   time.Now,
+	}
+}
+
+func (c *CallbackCodec) callback(ctx context.Context, action callbackAction) string {
+	return c.encode(ctx, callbackData{action: action})
+}
+
+func (c *CallbackCodec) callbackWithInts(ctx context.Context, action callbackAction, ints ...int) string {
+	return c.encode(ctx, callbackData{action: action, ints: ints})
+}
+
+func (c *CallbackCodec) callbackWithString(ctx context.Context, action callbackAction, str string) string {
+	return c.encode(ctx, callbackData{action: action, str: str})
+}
+
+func (c *CallbackCodec) encode(ctx context.Context, d callbackData) string {
+	head := string(callbackVersion) + string(d.action)
+	if !d.hasPayload() {
+		return head
+	}
+
+	payload := marshalCallbackPayload(d)
+	data := head + string(callbackPayloadSep) + callbackEncoding.EncodeToString(payload)
+	if len(data) <= maxCallbackDataLen {
+		return data
+	}
+
+	return head + string(callbackStoredSep) + c.put(ctx, payload)
+}
+
+func (c *CallbackCodec) decode(ctx context.Context, data string) (callbackData, error) {
+	if data == "" {
+		return callbackData{}, fmt.Errorf("%w: empty", ErrInvalidCallback)
+	}
+	if len(data) > maxCallbackDataLen {
+		return callbackData{}, fmt.Errorf("%w: too long", ErrInvalidCallback)
+	}
+	if data[0] != callbackVersion {
+		return callbackData{}, fmt.Errorf("%w: unsupported version %q", ErrInvalidCallback, data[0])
+	}
+	data = data[1:]
+
+	sepIdx := strings.IndexAny(data, string(callbackPayloadSep)+string(callbackStoredSep))
+	if sepIdx == -1 {
+		if data == "" {
+			return callbackData{}, fmt.Errorf("%w: no action", ErrInvalidCallback)
+		}
+		return callbackData{action: callbackAction(data)}, nil
+	}
+
+	var (
+		action  = callbackAction(data[:sepIdx])
+		encoded = data[sepIdx+1:]
+		payload []byte
+		err     error
+	)
+	if action == "" {
+		return callbackData{}, fmt.Errorf("%w: no action", ErrInvalidCallback)
+	}
+
+	switch data[sepIdx] {
+	case callbackPayloadSep:
+		payload, err = callbackEncoding.DecodeString(encoded)
+		if err != nil {
+			return callbackData{}, fmt.Errorf("%w: %s", ErrInvalidCallback, err.Error())
+		}
+	case callbackStoredSep:
+		payload, err = c.get(ctx, encoded)
+		if err != nil {
+			if errors.Is(err, domain.ErrCallbackNotFound) {
+				return callbackData{}, fmt.Errorf("%w: %s", ErrCallbackExpired, action)
+			}
+			return callbackData{}, err
+		}
+	}
+
+	d, err := unmarshalCallbackPayload(payload)
+	if err != nil {
+		return callbackData{}, err
+	}
+	d.action = action
+	return d, nil
+}
+
+func marshalCallbackPayload(d callbackData) []byte {
+	var (
+		buf = make([]byte, binary.MaxVarintLen64*(len(d.ints)+1)+len(d.str))
+		n   = binary.PutUvarint(buf, uint64(len(d.ints)))
+	)
+	for _, v := range d.ints {
+		n += binary.PutVarint(buf[n:], int64(v))
+	}
+	n += copy(buf[n:], d.str)
+	return buf[:n]
+}
+
+func unmarshalCallbackPayload(payload []byte) (callbackData, error) {
+	count, n := binary.Uvarint(payload)
+	// Every int takes at least 1 byte
+	if n <= 0 || count > uint64(len(payload)-n) {
+		return callbackData{}, fmt.Errorf("%w: malformed ints count", ErrInvalidCallback)
+	}
+	payload = payload[n:]
+
+	var d callbackData
+	if count > 0 {
+		d.ints = make([]int, 0, count)
+	}
+	for i := uint64(0); i < count; i++ {
+		v, n := binary.Varint(payload)
+		if n <= 0 {
+			return callbackData{}, fmt.Errorf("%w: malformed int", ErrInvalidCallback)
+		}
+		d.ints = append(d.ints, int(v))
+		payload = payload[n:]
+	}
+	d.str = string(payload)
+	return d, nil
+}
+
+// put stores payload and returns its key.
+// Buttons are built without error handling, button with payload that failed to be saved is answered as expired
+func (c *CallbackCodec) put(ctx context.Context, payload []byte) string {
+	key := newCallbackKey()
+	if err := c.store.Save(ctx, domain.StoredCallback{
+		Key:       key,
+		Payload:   payload,
+		ExpiresAt: c.now().Add(c.ttl),
+	}); err != nil {
+		logger.Get().Error("can't store callback payload", zap.Error(err))
+	}
+	return key
+}
+
+// get returns domain.ErrCallbackNotFound for expired payload even if store still has it
+func (c *CallbackCodec) get(ctx context.Context, key string) ([]byte, error) {
+	stored, err := c.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if c.now().After(stored.ExpiresAt) {
+		return nil, domain.ErrCallbackNotFound
+	}
+	return stored.Payload, nil
+}
+
+func newCallbackKey() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("can't generate callback key: %v", err))
+	}
+	return callbackEncoding.EncodeToString(b)
+}
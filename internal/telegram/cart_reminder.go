@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const defaultCartReminderPeriod = time.Minute * 10
+
+// CartReminder reminds customers about abandoned carts
+type CartReminder struct {
+	b            Bot
+	customerRepo repositories.Customer
+	codec        *CallbackCodec
+	// n-th reminder is sent after after[n] of inactivity
+	after  []time.Duration
+	period time.Duration
+	now    func() time.Time
+}
+
+func NewCartReminder(bot Bot, customerRepo repositories.Customer, codec *CallbackCodec, after []time.Duration) *CartReminder {
+	return &CartReminder{
+		b:            bot,
+		customerRepo: customerRepo,
+		codec:        codec,
+		after:        after,
+		period:       defaultCartReminderPeriod,
+		now:          time.Now,
+	}
+}
+
+// Run blocks until ctx is done
+func (c *CartReminder) Run(ctx context.Context) {
+	logger.Get().Info("cart reminder is running")
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Get().Info("cart reminder is shutting down")
+			return
+		case <-ticker.C:
+			c.remind(ctx)
+		}
+	}
+}
+
+func (c *CartReminder) remind(ctx context.Context) {
+	now := c.now()
+	// Latest reminders first. Customer inactive for long gets only the latest one,
+	// earlier reminders are skipped since sent counter is already past them
+	for i := len(c.after) - 1; i >= 0; i-- {
+		sent := uint(i + 1)
+		customers, err := c.customerRepo.GetAbandonedCarts(ctx, now.Add(-c.after[i]), sent)
+		if err != nil {
+			logger.Get().Error("can't get abandoned carts", zap.Error(err))
+			return
+		}
+		for _, customer := range customers {
+			if err := c.send(ctx, customer, sent); err != nil {
+				logger.Get().Error("can't remind about cart",
+					zap.Int64("telegramId", customer.TelegramID),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+func (c *CartReminder) send(ctx context.Context, customer domain.Customer, sent uint) error {
+	// Mark first, so customer is not spammed if telegram keeps failing
+	if err := c.customerRepo.SetCartRemindersSent(ctx, customer.TelegramID, sent); err != nil {
+		return fmt.Errorf("customerRepo.SetCartRemindersSent: %w", err)
+	}
+
+	ctx = withLanguage(ctx, customerLanguage(customer, ""))
+	msg := tg.NewMessage(customer.TelegramID, getCartReminder(ctx, customer))
+	msg.ReplyMarkup = c.codec.cartReminderButtons(ctx)
+	if _, err := botWithContext(ctx, c.b).Send(msg); err != nil {
+		if isBlockedError(err) {
+			return c.customerRepo.SetBlockedBot(ctx, customer.TelegramID, true)
+		}
+		return err
+	}
+	return nil
+}
+
+func getCartReminder(ctx context.Context, customer domain.Customer) string {
+	return tr(ctx, "reminder.cart") + prepareCartPreview(ctx, customer.Cart)
+}
+
+func (h *handler) OptOutCartReminders(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	optedOut := true
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		CartRemindersOptedOut: &optedOut,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	return h.sendMessage(ctx, chatID, tr(ctx, "reminder.opted_out"))
+}
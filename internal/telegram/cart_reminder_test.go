@@ -0,0 +1,61 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCartReminder(t *testing.T) {
+	var (
+		now  = time.Date(2023, 4, 20, 12, 0, 0, 0, time.UTC)
+		ago  = func(h int) *time.Time { at := now.Add(-time.Duration(h) * time.Hour); return &at }
+		cart = domain.Cart{{ShopLink: "https://example.com", PriceRUB: 1000}}
+	)
+
+	repo := newFakeCustomerRepo(
+		// active recently
+		domain.Customer{TelegramID: 1, Cart: cart, LastActivityAt: ago(1)},
+		// due first reminder
+		domain.Customer{TelegramID: 2, Cart: cart, LastActivityAt: ago(30)},
+		// long gone, gets only second reminder
+		domain.Customer{TelegramID: 3, Cart: cart, LastActivityAt: ago(100)},
+		// empty cart
+		domain.Customer{TelegramID: 4, LastActivityAt: ago(100)},
+		// opted out
+		domain.Customer{TelegramID: 5, Cart: cart, LastActivityAt: ago(100), CartReminders: domain.CartReminders{OptedOut: true}},
+		// blocked bot
+		domain.Customer{TelegramID: 6, Cart: cart, LastActivityAt: ago(30)},
+	)
+
+	bot := newFakeBot()
+	bot.fail(6, errBlockedByUser)
+
+	reminder := NewCartReminder(bot, repo, NewCallbackCodec(newMemoryCallbackStore()), []time.Duration{24 * time.Hour, 72 * time.Hour})
+	reminder.now = func() time.Time { return now }
+
+	reminder.remind(context.Background())
+
+	require.Empty(t, bot.sentTo(1))
+	require.Len(t, bot.sentTo(2), 1)
+	require.Len(t, bot.sentTo(3), 1)
+	require.Empty(t, bot.sentTo(4))
+	require.Empty(t, bot.sentTo(5))
+	require.Equal(t, uint(1), repo.customer(2).CartReminders.Sent)
+	require.Equal(t, uint(2), repo.customer(3).CartReminders.Sent)
+	require.True(t, repo.customer(6).BlockedBot)
+
+	// Nothing new is due
+	reminder.remind(context.Background())
+	require.Len(t, bot.sentTo(2), 1)
+	require.Len(t, bot.sentTo(3), 1)
+
+	// Second reminder for customer 2
+	now = now.Add(48 * time.Hour)
+	reminder.remind(context.Background())
+	require.Len(t, bot.sentTo(2), 2)
+	require.Len(t, bot.sentTo(3), 1)
+}
@@ -2,28 +2,141 @@ package catalog
 
 import (
 	"sync"
+	"time"
 
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type CatalogProvider struct {
-	mu    *sync.RWMutex
-	items []domain.CatalogItem
+	mu *sync.RWMutex
+	// every item including unpublished ones
+	all []domain.CatalogItem
+	// items published at the moment of last Refresh. Load doesn't touch them,
+	// so Refresh sees every change of visible catalog
+	items   []domain.CatalogItem
+	now     func() time.Time
+	changes chan struct{}
+	// Fires when price or quantity of any item has changed
+	priceChanges chan struct{}
+	// Drops published before lastRefresh are already announced
+	lastRefresh time.Time
 }
 
 func NewCatalogProvider() *CatalogProvider {
 	return &CatalogProvider{
-		mu:    new(sync.RWMutex),
-		items: nil,
+		mu:           new(sync.RWMutex),
+		items:        nil,
+		now:          time.Now,
+		changes:      make(chan struct{}, 1),
+		priceChanges: make(chan struct{}, 1),
+		lastRefresh:  time.Now(),
 	}
 }
 
 func (c *CatalogProvider) Load(items []domain.CatalogItem) {
 	c.mu.Lock()
-	c.items = make([]domain.CatalogItem, len(items), len(items))
-	copy(c.items, items)
+	// Prices seen by customers might have changed while bot was down, so first load counts too
+	priceChanged := c.all == nil || !samePrices(c.all, items)
+	c.all = make([]domain.CatalogItem, len(items), len(items))
+	copy(c.all, items)
 	c.mu.Unlock()
+
+	// Load is called on repository writes, do not block them.
+	// Notify scheduler that visible catalog and boundaries might have changed
+	notify(c.changes)
+	if priceChanged {
+		notify(c.priceChanges)
+	}
+}
+
+// notify coalesces signals listener hasn't received yet
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Refresh re-evaluates publication windows.
+// Returns items that got published since previous Refresh and whether visible catalog has changed
+func (c *CatalogProvider) Refresh() (newlyPublished []domain.CatalogItem, changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	items := c.published(now)
+	for _, item := range items {
+		if item.PublishAt != nil && item.PublishAt.After(c.lastRefresh) {
+			newlyPublished = append(newlyPublished, item)
+		}
+	}
+	changed = !sameItems(c.items, items)
+	c.items = items
+	c.lastRefresh = now
+	return newlyPublished, changed
+}
+
+// LastRefresh is the moment drops are announced up to
+func (c *CatalogProvider) LastRefresh() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastRefresh
+}
+
+// RestoreLastRefresh continues from refresh made before restart, so drops published in between are announced
+func (c *CatalogProvider) RestoreLastRefresh(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastRefresh = t
+}
+
+// NextBoundary returns closest moment when visible catalog changes
+func (c *CatalogProvider) NextBoundary() (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var (
+		now   = c.now()
+		next  time.Time
+		found bool
+	)
+	for _, item := range c.all {
+		b, ok := item.NextBoundary(now)
+		if ok && (!found || b.Before(next)) {
+			next = b
+			found = true
+		}
+	}
+	return next, found
+}
+
+// PriceChanges fires after Load that has changed price or quantity of any item
+func (c *CatalogProvider) PriceChanges() <-chan struct{} {
+	return c.priceChanges
+}
+
+// Published returns items published at the moment
+func (c *CatalogProvider) Published() []domain.CatalogItem {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.published(c.now())
+}
+
+// Changes fires after each Load, visible catalog is updated by Refresh after it
+func (c *CatalogProvider) Changes() <-chan struct{} {
+	return c.changes
+}
+
+// must be called under lock
+func (c *CatalogProvider) published(now time.Time) []domain.CatalogItem {
+	items := make([]domain.CatalogItem, 0, len(c.all))
+	for _, item := range c.all {
+		if item.IsPublishedAt(now) {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func (c *CatalogProvider) HasNext(offset uint) bool {
@@ -67,13 +180,57 @@ func (c *CatalogProvider) LoadFirst() domain.CatalogItem {
 }
 
 func (c *CatalogProvider) LoadAt(offset uint) domain.CatalogItem {
-	if len(c.items) == 0 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	// Offset might be outdated after unpublishing
+	if offset >= uint(len(c.items)) {
 		return domain.CatalogItem{}
 	}
+	return c.items[offset]
+}
+
+// FindByID looks up published item
+func (c *CatalogProvider) FindByID(itemID primitive.ObjectID) (domain.CatalogItem, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	_ = c.items[offset]
-	return c.items[offset]
+	for _, item := range c.items {
+		if item.ItemID == itemID {
+			return item, true
+		}
+	}
+	return domain.CatalogItem{}, false
+}
+
+func sameItems(a, b []domain.CatalogItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ItemID != b[i].ItemID {
+			return false
+		}
+	}
+	return true
+}
+
+// samePrices reports whether items present before and after have same price and quantity.
+// Order doesn't matter, so ranking and reordering are not price changes
+func samePrices(before, after []domain.CatalogItem) bool {
+	type stock struct {
+		priceRUB uint64
+		quantity int
+	}
+	seen := make(map[primitive.ObjectID]stock, len(before))
+	for _, item := range before {
+		seen[item.ItemID] = stock{priceRUB: item.PriceRUB, quantity: item.Quantity}
+	}
+	for _, item := range after {
+		s, ok := seen[item.ItemID]
+		if ok && s != (stock{priceRUB: item.PriceRUB, quantity: item.Quantity}) {
+			return false
+		}
+	}
+	return true
 }
 
 func MakeUpdateOnChangeFunc(catalogProvider *CatalogProvider) repositories.OnChangeFunc {
@@ -130,3 +130,40 @@ func TestCatalogProvider(t *testing.T) {
 		}
 	})
 }
+
+func TestPriceChanges(t *testing.T) {
+	var (
+		provider = NewCatalogProvider()
+		first    = domain.CatalogItem{ItemID: primitive.NewObjectID(), Rank: 0, PriceRUB: 1000, Quantity: 1}
+		second   = domain.CatalogItem{ItemID: primitive.NewObjectID(), Rank: 1, PriceRUB: 2000, Quantity: 1}
+	)
+	changed := func() bool {
+		select {
+		case <-provider.PriceChanges():
+			return true
+		default:
+			return false
+		}
+	}
+
+	// Prices might have changed while bot was down
+	provider.Load([]domain.CatalogItem{first, second})
+	require.True(t, changed())
+
+	// Reorder is not price change
+	first.Rank, second.Rank = 1, 0
+	provider.Load([]domain.CatalogItem{second, first})
+	require.False(t, changed())
+
+	// New item is no one's favourite yet
+	provider.Load([]domain.CatalogItem{second, first, {ItemID: primitive.NewObjectID(), PriceRUB: 500}})
+	require.False(t, changed())
+
+	second.Quantity = 0
+	provider.Load([]domain.CatalogItem{second, first})
+	first.PriceRUB = 900
+	provider.Load([]domain.CatalogItem{second, first})
+	// Both changes are handled at once
+	require.True(t, changed())
+	require.False(t, changed())
+}
@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+)
+
+// OnPriceChangeFunc is called with published items after price or quantity of some of them has changed
+type OnPriceChangeFunc func(ctx context.Context, items []domain.CatalogItem)
+
+// PriceWatcher calls OnPriceChangeFunc one call at a time.
+// Changes made during the call are handled by single next one
+type PriceWatcher struct {
+	provider *CatalogProvider
+	onChange OnPriceChangeFunc
+}
+
+func NewPriceWatcher(provider *CatalogProvider, onChange OnPriceChangeFunc) *PriceWatcher {
+	return &PriceWatcher{
+		provider: provider,
+		onChange: onChange,
+	}
+}
+
+// Run blocks until ctx is done
+func (w *PriceWatcher) Run(ctx context.Context) {
+	logger.Get().Info("catalog price watcher is running")
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Get().Info("catalog price watcher is shutting down")
+			return
+		case <-w.provider.PriceChanges():
+			w.onChange(ctx, w.provider.Published())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type (
+	// OnCatalogChangeFunc is called when visible catalog has changed
+	OnCatalogChangeFunc func(ctx context.Context) error
+	// OnDropFunc is called with drops that just got published
+	OnDropFunc func(ctx context.Context, drops []domain.CatalogItem)
+	// RefreshStore keeps moment of last refresh across restarts
+	RefreshStore interface {
+		GetLastRefresh(ctx context.Context) (time.Time, error)
+		SaveLastRefresh(ctx context.Context, at time.Time) error
+	}
+)
+
+// Scheduler refreshes CatalogProvider at publication boundaries of catalog items
+type Scheduler struct {
+	provider *CatalogProvider
+	store    RefreshStore
+	onChange OnCatalogChangeFunc
+	onDrop   OnDropFunc
+	// Fallback period when there're no boundaries
+	idle time.Duration
+}
+
+func NewScheduler(provider *CatalogProvider, store RefreshStore, onChange OnCatalogChangeFunc, onDrop OnDropFunc) *Scheduler {
+	return &Scheduler{
+		provider: provider,
+		store:    store,
+		onChange: onChange,
+		onDrop:   onDrop,
+		idle:     time.Hour,
+	}
+}
+
+// Run blocks until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	logger.Get().Info("catalog scheduler is running")
+	s.restore(ctx)
+	// Visible catalog is computed by refresh only, drops published while bot was down are announced by it
+	s.tick(ctx)
+	for {
+		wait := s.idle
+		if next, ok := s.provider.NextBoundary(); ok {
+			wait = next.Sub(s.provider.now())
+		}
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Get().Info("catalog scheduler is shutting down")
+			return
+		case <-s.provider.Changes():
+			// Boundaries might have changed, recalculate
+			timer.Stop()
+		case <-timer.C:
+		}
+		s.tick(ctx)
+	}
+}
+
+func (s *Scheduler) restore(ctx context.Context) {
+	lastRefresh, err := s.store.GetLastRefresh(ctx)
+	if err != nil {
+		if !errors.Is(err, domain.ErrCatalogStateNotFound) {
+			logger.Get().Error("can't get last catalog refresh", zap.Error(err))
+		}
+		// Nothing to catch up with
+		return
+	}
+	s.provider.RestoreLastRefresh(lastRefresh)
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	published, changed := s.provider.Refresh()
+	if changed {
+		logger.Get().Info("visible catalog has changed", zap.Int("newlyPublished", len(published)))
+		if s.onChange != nil {
+			if err := s.onChange(ctx); err != nil {
+				logger.Get().Error("catalog on change", zap.Error(err))
+			}
+		}
+	}
+
+	var drops []domain.CatalogItem
+	for _, item := range published {
+		if item.IsDrop {
+			drops = append(drops, item)
+		}
+	}
+	if len(drops) > 0 && s.onDrop != nil {
+		s.onDrop(ctx, drops)
+	}
+
+	if err := s.store.SaveLastRefresh(ctx, s.provider.LastRefresh()); err != nil {
+		logger.Get().Error("can't save last catalog refresh", zap.Error(err))
+	}
+}
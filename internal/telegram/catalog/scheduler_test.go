@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+type memoryRefreshStore struct {
+	lastRefresh *time.Time
+}
+
+func (m *memoryRefreshStore) GetLastRefresh(ctx context.Context) (time.Time, error) {
+	if m.lastRefresh == nil {
+		return time.Time{}, domain.ErrCatalogStateNotFound
+	}
+	return *m.lastRefresh, nil
+}
+
+func (m *memoryRefreshStore) SaveLastRefresh(ctx context.Context, at time.Time) error {
+	m.lastRefresh = &at
+	return nil
+}
+
+func TestPublicationWindows(t *testing.T) {
+	var (
+		clock   = &fakeClock{t: time.Now()}
+		dropAt  = clock.t.Add(time.Hour)
+		closeAt = clock.t.Add(2 * time.Hour)
+		items   = []domain.CatalogItem{
+			{ItemID: primitive.NewObjectID(), Title: "always", Rank: 0},
+			{ItemID: primitive.NewObjectID(), Title: "drop", Rank: 1, PublishAt: &dropAt, UnpublishAt: &closeAt, IsDrop: true},
+		}
+	)
+
+	provider := NewCatalogProvider()
+	provider.now = clock.now
+	provider.lastRefresh = clock.t
+	provider.Load(items)
+
+	var (
+		store   = &memoryRefreshStore{}
+		changes int
+		drops   []domain.CatalogItem
+	)
+	scheduler := NewScheduler(provider, store, func(ctx context.Context) error {
+		changes++
+		return nil
+	}, func(ctx context.Context, d []domain.CatalogItem) {
+		drops = append(drops, d...)
+	})
+
+	// Loaded items become visible on refresh only
+	require.Equal(t, domain.CatalogItem{}, provider.LoadFirst())
+	scheduler.tick(context.Background())
+	require.Equal(t, 1, changes)
+	require.Empty(t, drops)
+	require.Equal(t, clock.t, *store.lastRefresh)
+
+	// Only item without window is visible
+	require.Equal(t, "always", provider.LoadFirst().Title)
+	require.False(t, provider.HasNext(0))
+	next, ok := provider.NextBoundary()
+	require.True(t, ok)
+	require.Equal(t, dropAt, next)
+
+	// Nothing happened yet
+	scheduler.tick(context.Background())
+	require.Equal(t, 1, changes)
+	require.Empty(t, drops)
+
+	// Drop goes live
+	clock.t = dropAt
+	scheduler.tick(context.Background())
+	require.Equal(t, 2, changes)
+	require.Len(t, drops, 1)
+	require.Equal(t, "drop", drops[0].Title)
+	require.True(t, provider.HasNext(0))
+	next, ok = provider.NextBoundary()
+	require.True(t, ok)
+	require.Equal(t, closeAt, next)
+
+	// Drop is not announced twice
+	scheduler.tick(context.Background())
+	require.Len(t, drops, 1)
+	require.Equal(t, 2, changes)
+
+	// Drop is closed
+	clock.t = closeAt
+	scheduler.tick(context.Background())
+	require.Equal(t, 3, changes)
+	require.False(t, provider.HasNext(0))
+	require.Equal(t, domain.CatalogItem{}, provider.LoadAt(1))
+	_, ok = provider.NextBoundary()
+	require.False(t, ok)
+}
+
+func TestDropPublishedWhileDown(t *testing.T) {
+	var (
+		clock  = &fakeClock{t: time.Now()}
+		before = clock.t.Add(-time.Hour)
+		dropAt = clock.t.Add(-time.Minute)
+		store  = &memoryRefreshStore{lastRefresh: &before}
+		drops  []domain.CatalogItem
+	)
+	provider := NewCatalogProvider()
+	provider.now = clock.now
+	provider.Load([]domain.CatalogItem{
+		{ItemID: primitive.NewObjectID(), Title: "drop", PublishAt: &dropAt, IsDrop: true},
+	})
+	scheduler := NewScheduler(provider, store, nil, func(ctx context.Context, d []domain.CatalogItem) {
+		drops = append(drops, d...)
+	})
+
+	scheduler.restore(context.Background())
+	scheduler.tick(context.Background())
+	require.Len(t, drops, 1)
+	require.Equal(t, clock.t, *store.lastRefresh)
+
+	// Restart right after announce
+	provider = NewCatalogProvider()
+	provider.now = clock.now
+	provider.Load([]domain.CatalogItem{
+		{ItemID: primitive.NewObjectID(), Title: "drop", PublishAt: &dropAt, IsDrop: true},
+	})
+	scheduler.provider = provider
+	scheduler.restore(context.Background())
+	scheduler.tick(context.Background())
+	require.Len(t, drops, 1)
+}
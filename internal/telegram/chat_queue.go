@@ -0,0 +1,121 @@
+package telegram
+
+import (
+	"errors"
+	"sync"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Updates of one chat beyond the limit are dropped, customer is spamming buttons then
+const maxQueuedUpdatesPerChat = 16
+
+var (
+	errChatQueueFull    = errors.New("chat queue is full")
+	errChatQueuesClosed = errors.New("chat queues are closed")
+)
+
+// QueueStats is a snapshot of router queues
+type QueueStats struct {
+	// Chats having updates in progress or queued
+	ActiveChats int `json:"activeChats"`
+	// Updates waiting for the previous update of the same chat
+	Queued int `json:"queued"`
+	// The longest queue of a single chat since start
+	MaxDepth  int    `json:"maxDepth"`
+	Processed uint64 `json:"processed"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// chatQueues handles updates of the same chat one by one, different chats are handled concurrently.
+// Goroutine of a chat lives while chat has updates to handle
+type chatQueues struct {
+	mu     sync.Mutex
+	queues map[int64]chan tg.Update
+	limit  int
+	handle func(u tg.Update)
+	wg     *sync.WaitGroup
+	stats  QueueStats
+	// No more updates are accepted, queued ones are still handled
+	closed bool
+}
+
+func newChatQueues(limit int, wg *sync.WaitGroup, handle func(u tg.Update)) *chatQueues {
+	return &chatQueues{
+		queues: make(map[int64]chan tg.Update),
+		limit:  limit,
+		handle: handle,
+		wg:     wg,
+	}
+}
+
+// push returns error if update is dropped
+func (q *chatQueues) push(chatID int64, u tg.Update) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errChatQueuesClosed
+	}
+
+	queue, ok := q.queues[chatID]
+	if !ok {
+		queue = make(chan tg.Update, q.limit)
+		q.queues[chatID] = queue
+		q.wg.Add(1)
+		go q.drain(chatID, queue)
+	}
+
+	select {
+	case queue <- u:
+		if depth := len(queue); depth > q.stats.MaxDepth {
+			q.stats.MaxDepth = depth
+		}
+		return nil
+	default:
+		q.stats.Dropped++
+		return errChatQueueFull
+	}
+}
+
+// close stops accepting updates. wg can be waited after it, no chat goroutine is started anymore
+func (q *chatQueues) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+}
+
+func (q *chatQueues) drain(chatID int64, queue chan tg.Update) {
+	defer q.wg.Done()
+	for {
+		var u tg.Update
+		// Queue is removed under the lock, so push can't put update into abandoned queue
+		q.mu.Lock()
+		select {
+		case u = <-queue:
+			q.mu.Unlock()
+		default:
+			delete(q.queues, chatID)
+			q.mu.Unlock()
+			return
+		}
+
+		q.handle(u)
+
+		q.mu.Lock()
+		q.stats.Processed++
+		q.mu.Unlock()
+	}
+}
+
+func (q *chatQueues) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	stats.ActiveChats = len(q.queues)
+	for _, queue := range q.queues {
+		stats.Queued += len(queue)
+	}
+	return stats
+}
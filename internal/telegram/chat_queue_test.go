@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatQueues(t *testing.T) {
+	t.Run("same chat is handled in order", func(t *testing.T) {
+		var (
+			wg      = new(sync.WaitGroup)
+			mu      sync.Mutex
+			handled []int
+			running int
+			overlap bool
+		)
+		q := newChatQueues(100, wg, func(u tg.Update) {
+			mu.Lock()
+			running++
+			overlap = overlap || running > 1
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running--
+			handled = append(handled, u.UpdateID)
+			mu.Unlock()
+		})
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, q.push(1, tg.Update{UpdateID: i}))
+		}
+		wg.Wait()
+
+		require.False(t, overlap, "updates of the same chat overlap")
+		require.Len(t, handled, 20)
+		for i, id := range handled {
+			require.Equal(t, i, id)
+		}
+		stats := q.Stats()
+		require.Equal(t, uint64(20), stats.Processed)
+		require.Zero(t, stats.ActiveChats)
+		require.Zero(t, stats.Queued)
+	})
+
+	t.Run("different chats are handled concurrently", func(t *testing.T) {
+		var (
+			wg      = new(sync.WaitGroup)
+			started = make(chan int64, 2)
+			release = make(chan struct{})
+		)
+		q := newChatQueues(1, wg, func(u tg.Update) {
+			started <- u.Message.Chat.ID
+			<-release
+		})
+
+		q.push(1, tg.Update{Message: &tg.Message{Chat: &tg.Chat{ID: 1}}})
+		q.push(2, tg.Update{Message: &tg.Message{Chat: &tg.Chat{ID: 2}}})
+
+		// Both handlers are running before any of them finishes
+		for i := 0; i < 2; i++ {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatal("chats are not handled concurrently")
+			}
+		}
+		require.Equal(t, 2, q.Stats().ActiveChats)
+
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("queue is bounded", func(t *testing.T) {
+		var (
+			wg      = new(sync.WaitGroup)
+			started = make(chan struct{})
+			release = make(chan struct{})
+		)
+		q := newChatQueues(2, wg, func(u tg.Update) {
+			if u.UpdateID == 0 {
+				close(started)
+			}
+			<-release
+		})
+
+		require.NoError(t, q.push(1, tg.Update{UpdateID: 0}))
+		<-started
+		// First update is in progress, two more fit in the queue
+		require.NoError(t, q.push(1, tg.Update{UpdateID: 1}))
+		require.NoError(t, q.push(1, tg.Update{UpdateID: 2}))
+		require.ErrorIs(t, q.push(1, tg.Update{UpdateID: 3}), errChatQueueFull)
+
+		stats := q.Stats()
+		require.Equal(t, 2, stats.Queued)
+		require.Equal(t, 2, stats.MaxDepth)
+		require.Equal(t, uint64(1), stats.Dropped)
+
+		close(release)
+		wg.Wait()
+		require.Equal(t, uint64(3), q.Stats().Processed)
+	})
+
+	t.Run("closed queues drain but don't accept updates", func(t *testing.T) {
+		var (
+			wg      = new(sync.WaitGroup)
+			release = make(chan struct{})
+			handled int
+		)
+		q := newChatQueues(10, wg, func(u tg.Update) {
+			<-release
+			handled++
+		})
+
+		require.NoError(t, q.push(1, tg.Update{UpdateID: 0}))
+		require.NoError(t, q.push(1, tg.Update{UpdateID: 1}))
+		q.close()
+		require.ErrorIs(t, q.push(1, tg.Update{UpdateID: 2}), errChatQueuesClosed)
+
+		close(release)
+		wg.Wait()
+		require.Equal(t, 2, handled)
+	})
+}
+
+func TestUpdateChatID(t *testing.T) {
+	user := &tg.User{ID: 42}
+	require.Equal(t, int64(42), updateChatID(tg.Update{Message: &tg.Message{From: user, Chat: &tg.Chat{ID: 42}}}))
+	require.Equal(t, int64(42), updateChatID(tg.Update{CallbackQuery: &tg.CallbackQuery{From: user}}))
+	require.Zero(t, updateChatID(tg.Update{}))
+}
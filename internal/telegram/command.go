@@ -1,8 +1,9 @@
 package telegram
 
+// Commands of reply keyboard are message ids, see Router.command
 const (
 	startCommand       = "/start"
-	menuCommand        = "Меню"
-	getCartCommand     = "Посмотреть корзину"
-	addPositionCommand = "Добавить позицию"
+	menuCommand        = "command.menu"
+	getCartCommand     = "command.cart"
+	addPositionCommand = "command.add_position"
 )
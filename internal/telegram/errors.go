@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+)
+
+// ErrorKind decides what customer is told when handler fails, details are only logged
+type ErrorKind int
+
+const (
+	// ErrorKindInternal is a failure customer can't do anything about, e.g. database timeout
+	ErrorKindInternal ErrorKind = iota
+	// ErrorKindStaleButton is a button of an old message, e.g. position which is already removed
+	ErrorKindStaleButton
+	// ErrorKindInvalidInput is a message bot doesn't understand
+	ErrorKindInvalidInput
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindStaleButton:
+		return "stale_button"
+	case ErrorKindInvalidInput:
+		return "invalid_input"
+	default:
+		return "internal"
+	}
+}
+
+// UserError is returned by handlers when error needs a reply other than the default one of its kind
+type UserError struct {
+	Kind ErrorKind
+	// Message is id of message sent to customer instead of the default reply of the kind if set
+	Message string
+	Err     error
+}
+
+func (e *UserError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
+
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// staleButtonError explains customer what exactly is gone
+func staleButtonError(message string, err error) error {
+	return &UserError{Kind: ErrorKindStaleButton, Message: message, Err: err}
+}
+
+// Errors of well known kind, handlers don't have to wrap them into UserError
+var (
+	staleButtonErrors = []error{
+		ErrInvalidState,
+		ErrInvalidCallback,
+		ErrCallbackExpired,
+		domain.ErrInvalidPositionN,
+		domain.ErrInvalidAddressN,
+		domain.ErrOrderNotFound,
+		domain.ErrItemNotFound,
+	}
+	invalidInputErrors = []error{
+		// Text sent outside of flow
+		ErrNoHandler,
+		ErrNoRoute,
+		domain.ErrAddressNameTaken,
+	}
+)
+
+// classifyError returns kind of err and reply which is sent to customer in language of ctx
+func classifyError(ctx context.Context, err error) (ErrorKind, string) {
+	var userErr *UserError
+	if errors.As(err, &userErr) {
+		if userErr.Message != "" {
+			return userErr.Kind, tr(ctx, userErr.Message)
+		}
+		return userErr.Kind, errorReply(ctx, userErr.Kind)
+	}
+
+	kind := ErrorKindInternal
+	switch {
+	case isOneOf(err, staleButtonErrors):
+		kind = ErrorKindStaleButton
+	case isOneOf(err, invalidInputErrors):
+		kind = ErrorKindInvalidInput
+	}
+	return kind, errorReply(ctx, kind)
+}
+
+func errorReply(ctx context.Context, kind ErrorKind) string {
+	switch kind {
+	case ErrorKindStaleButton:
+		return tr(ctx, "error.stale_button")
+	case ErrorKindInvalidInput:
+		return tr(ctx, "error.invalid_input")
+	default:
+		return getInternalErrorTemplate(ctx, correlationID(ctx))
+	}
+}
+
+func isOneOf(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type correlationIDKey struct{}
+
+// withCorrelationID marks handling of update, the ID is logged and shown to customer if handler fails
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// newCorrelationID is short enough for customer to send it to support
+func newCorrelationID() string {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("can't generate correlation id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,112 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	const id = "a1b2c3d4e5"
+
+	ctx := withCorrelationID(withLanguage(context.Background(), "en"), id)
+	tests := []struct {
+		name  string
+		err   error
+		kind  ErrorKind
+		reply string
+	}{
+		{
+			name:  "old flow button",
+			err:   fmt.Errorf("fsm.handleButton: %w", ErrInvalidState),
+			kind:  ErrorKindStaleButton,
+			reply: tr(ctx, "error.stale_button"),
+		},
+		{
+			name:  "expired callback",
+			err:   fmt.Errorf("codec.decode: %w", ErrCallbackExpired),
+			kind:  ErrorKindStaleButton,
+			reply: tr(ctx, "error.stale_button"),
+		},
+		{
+			name:  "removed position",
+			err:   staleButtonError("error.position_not_found", domain.ErrInvalidPositionN),
+			kind:  ErrorKindStaleButton,
+			reply: tr(ctx, "error.position_not_found"),
+		},
+		{
+			name:  "text outside of flow",
+			err:   ErrNoHandler,
+			kind:  ErrorKindInvalidInput,
+			reply: tr(ctx, "error.invalid_input"),
+		},
+		{
+			name:  "database timeout",
+			err:   fmt.Errorf("customerRepo.GetByTelegramID: %w", context.DeadlineExceeded),
+			kind:  ErrorKindInternal,
+			reply: getInternalErrorTemplate(ctx, id),
+		},
+		{
+			name:  "user error without reply",
+			err:   &UserError{Kind: ErrorKindInternal, Err: errors.New("payment is unavailable")},
+			kind:  ErrorKindInternal,
+			reply: getInternalErrorTemplate(ctx, id),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, reply := classifyError(ctx, tt.err)
+			require.Equal(t, tt.kind, kind)
+			require.Equal(t, tt.reply, reply)
+		})
+	}
+
+	require.Contains(t, getInternalErrorTemplate(ctx, id), id)
+	require.NotEqual(t, tr(ctx, "error.stale_button"), tr(context.Background(), "error.stale_button"))
+	require.ErrorIs(t, staleButtonError("error.order_not_found", domain.ErrOrderNotFound), domain.ErrOrderNotFound)
+}
+
+// failingHandler fails menu command and records what HandleError got
+type failingHandler struct {
+	RouteHandler
+	err    error
+	failed chan string
+}
+
+func (h failingHandler) Menu(ctx context.Context, chatID int64) error {
+	return h.err
+}
+
+func (h failingHandler) HandleError(ctx context.Context, err error, m tg.Update) {
+	h.failed <- correlationID(ctx)
+}
+
+func TestRouterCorrelationID(t *testing.T) {
+	logger.Get()
+
+	var (
+		updates = make(chan tg.Update)
+		h       = failingHandler{err: context.DeadlineExceeded, failed: make(chan string, 2)}
+		router  = NewRouter(updates, h, newFakeCustomerRepo(), NewCallbackCodec(newMemoryCallbackStore()), time.Second)
+	)
+	go router.Bootstrap() //nolint:errcheck
+	defer func() {
+		close(updates)
+		router.Shutdown(context.Background()) //nolint:errcheck
+	}()
+
+	updates <- tgMenuUpdate(1)
+	updates <- tgMenuUpdate(1)
+
+	// Every update has its own id
+	first, second := <-h.failed, <-h.failed
+	require.Len(t, first, 10)
+	require.NotEqual(t, first, second)
+}
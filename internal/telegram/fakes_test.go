@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+var errBlockedByUser = &tg.Error{Code: http.StatusForbidden, Message: "Forbidden: bot was blocked by the user"}
+
+// fakeBot fails requests with errors scripted for their chat and records the rest. Safe for concurrent use
+type fakeBot struct {
+	mu    sync.Mutex
+	errs  map[int64][]error
+	sent  map[int64][]tg.Chattable
+	texts []string
+	// onSend is called on every request before it's handled, e.g. to cancel ctx of caller
+	onSend func(chatID int64)
+}
+
+func newFakeBot() *fakeBot {
+	return &fakeBot{
+		errs: make(map[int64][]error),
+		sent: make(map[int64][]tg.Chattable),
+	}
+}
+
+// fail scripts results of next requests to chat, nil is a request that succeeds
+func (b *fakeBot) fail(chatID int64, errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errs[chatID] = append(b.errs[chatID], errs...)
+}
+
+func (b *fakeBot) handle(chatID int64, c tg.Chattable) error {
+	if b.onSend != nil {
+		b.onSend(chatID)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if errs := b.errs[chatID]; len(errs) > 0 {
+		b.errs[chatID] = errs[1:]
+		if errs[0] != nil {
+			return errs[0]
+		}
+	}
+	b.sent[chatID] = append(b.sent[chatID], c)
+	if msg, ok := c.(tg.MessageConfig); ok {
+		b.texts = append(b.texts, msg.Text)
+	}
+	return nil
+}
+
+func (b *fakeBot) Send(c tg.Chattable) (tg.Message, error) {
+	chatID, _ := requestTarget(c)
+	return tg.Message{}, b.handle(chatID, c)
+}
+
+func (b *fakeBot) CleanRequest(c tg.Chattable) error {
+	return nil
+}
+
+func (b *fakeBot) SendMediaGroup(c tg.MediaGroupConfig) ([]tg.Message, error) {
+	return nil, b.handle(c.ChatID, c)
+}
+
+// sentTo is requests sent to chat
+func (b *fakeBot) sentTo(chatID int64) []tg.Chattable {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]tg.Chattable(nil), b.sent[chatID]...)
+}
+
+// sentTexts is texts of messages sent to every chat in order they were sent
+func (b *fakeBot) sentTexts() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.texts...)
+}
+
+// fakeCustomerRepo keeps customers in memory by telegram id. Other methods of repositories.Customer panic
+type fakeCustomerRepo struct {
+	repositories.Customer
+	mu        sync.Mutex
+	customers map[int64]*domain.Customer
+}
+
+func newFakeCustomerRepo(customers ...domain.Customer) *fakeCustomerRepo {
+	r := &fakeCustomerRepo{customers: make(map[int64]*domain.Customer, len(customers))}
+	for _, c := range customers {
+		c := c
+		r.customers[c.TelegramID] = &c
+	}
+	return r
+}
+
+// customer is copy of customer for assertions
+func (r *fakeCustomerRepo) customer(telegramID int64) domain.Customer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.customers[telegramID]
+}
+
+func (r *fakeCustomerRepo) TouchActivity(ctx context.Context, telegramID int64, at time.Time) (domain.Customer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.customers[telegramID]
+	if !ok {
+		return domain.Customer{}, domain.ErrCustomerNotFound
+	}
+	c.LastActivityAt = &at
+	c.BlockedBot = false
+	c.CartReminders.Sent = 0
+	return domain.Customer{Language: c.Language}, nil
+}
+
+func (r *fakeCustomerRepo) SetBlockedBot(ctx context.Context, telegramID int64, blocked bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.customers[telegramID]; ok {
+		c.BlockedBot = blocked
+	}
+	return nil
+}
+
+func (r *fakeCustomerRepo) GetAbandonedCarts(ctx context.Context, inactiveSince time.Time, remindersSentBelow uint) ([]domain.Customer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Customer
+	for _, c := range r.customers {
+		if len(c.Cart) == 0 || c.BlockedBot || c.CartReminders.OptedOut {
+			continue
+		}
+		if c.LastActivityAt == nil || !c.LastActivityAt.Before(inactiveSince) {
+			continue
+		}
+		if c.CartReminders.Sent >= remindersSentBelow {
+			continue
+		}
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (r *fakeCustomerRepo) SetCartRemindersSent(ctx context.Context, telegramID int64, sent uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.customers[telegramID].CartReminders.Sent = sent
+	return nil
+}
+
+// GetBroadcastAudience returns customers who don't block bot after cursor in order of id like mongo does.
+// Audience itself isn't filtered
+func (r *fakeCustomerRepo) GetBroadcastAudience(ctx context.Context, audience dto.BroadcastAudienceDTO) ([]domain.Customer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Customer
+	for _, c := range r.customers {
+		if c.BlockedBot {
+			continue
+		}
+		if audience.AfterCustomerID == nil || c.CustomerID.Hex() > audience.AfterCustomerID.Hex() {
+			out = append(out, *c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CustomerID.Hex() < out[j].CustomerID.Hex()
+	})
+	return out, nil
+}
@@ -0,0 +1,302 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+)
+
+// inputKind is a kind of update step accepts, kinds can be combined
+type inputKind uint8
+
+const (
+	inputText inputKind = 1 << iota
+	inputContact
+	inputButton
+)
+
+// flowInput is what customer sent in response to step prompt
+type flowInput struct {
+	kind    inputKind
+	text    string
+	contact *tg.Contact
+	// Sender of the message, contact might be forwarded from someone else
+	fromID int64
+	// Typed value of pressed button
+	value any
+}
+
+// invalidInput is returned by validators and step handlers.
+// Text is sent to customer and step prompt is repeated, customer stays at the step
+type invalidInput string
+
+func (e invalidInput) Error() string {
+	return string(e)
+}
+
+// flowPrompt is sent when customer enters a step
+type flowPrompt struct {
+	text string
+	// Inline buttons of the step, navigation buttons are added below
+	buttons [][]tg.InlineKeyboardButton
+	// Reply keyboard is shown instead of inline buttons, navigation is sent in a separate message then
+	keyboard *tg.ReplyKeyboardMarkup
+}
+
+// flowStep is a single state of conversation
+type flowStep struct {
+	state   domain.State
+	accepts inputKind
+	// validate checks input before it's handled, optional
+	validate func(ctx context.Context, in flowInput) error
+	// prompt is sent after customer is moved to the step
+	prompt func(ctx context.Context, customer domain.Customer) (flowPrompt, error)
+	// answer is what customer answered at the step before, it's shown when customer returns back. Optional
+	answer func(ctx context.Context, customer domain.Customer) string
+	// handle applies input to customer and returns next state. StateDefault finishes the flow.
+	// Returning state of the step itself keeps customer there without prompting again
+	handle func(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error)
+	// back returns state of previous step, StateDefault if there's none and flow is left
+	back func(customer domain.Customer) domain.State
+}
+
+// flow is a sequence of steps sharing collected data
+type flow struct {
+	name  string
+	steps []flowStep
+	// cancel drops data collected by the flow
+	cancel func(ctx context.Context, customer domain.Customer) error
+}
+
+// backTo is for steps which always have the same previous step
+func backTo(state domain.State) func(customer domain.Customer) domain.State {
+	return func(customer domain.Customer) domain.State {
+		return state
+	}
+}
+
+type flowCustomers interface {
+	GetByTelegramID(ctx context.Context, telegramID int64) (domain.Customer, error)
+	UpdateState(ctx context.Context, telegramID int64, newState domain.State) error
+}
+
+type flowStepEntry struct {
+	step flowStep
+	flow *flow
+}
+
+// fsm routes customer input to step of the flow customer is at and moves customer between steps
+type fsm struct {
+	customers flowCustomers
+	codec     *CallbackCodec
+	send      func(ctx context.Context, chatID int64, text string, markup interface{}) error
+	steps     map[domain.State]flowStepEntry
+}
+
+// newFSM panics if flows are misconfigured, same as regexp.MustCompile does
+func newFSM(customers flowCustomers, codec *CallbackCodec, send func(ctx context.Context, chatID int64, text string, markup interface{}) error, flows ...flow) *fsm {
+	f := &fsm{
+		customers: customers,
+		codec:     codec,
+		send:      send,
+		steps:     make(map[domain.State]flowStepEntry),
+	}
+	for i := range flows {
+		fl := &flows[i]
+		for _, step := range fl.steps {
+			if step.state == domain.StateDefault {
+				panic(fmt.Sprintf("flow %s: step can't have default state", fl.name))
+			}
+			if _, ok := f.steps[step.state]; ok {
+				panic(fmt.Sprintf("flow %s: state %d belongs to several steps", fl.name, step.state.V))
+			}
+			if step.accepts == 0 || step.handle == nil || step.prompt == nil {
+				panic(fmt.Sprintf("flow %s: step %d is incomplete", fl.name, step.state.V))
+			}
+			if step.back == nil {
+				step.back = backTo(domain.StateDefault)
+			}
+			f.steps[step.state] = flowStepEntry{step: step, flow: fl}
+		}
+	}
+	return f
+}
+
+// handleMessage passes text or contact to step customer is at
+func (f *fsm) handleMessage(ctx context.Context, m *tg.Message) error {
+	var (
+		telegramID = m.From.ID
+		in         = flowInput{kind: inputText, text: m.Text, fromID: m.From.ID}
+	)
+	if m.Contact != nil {
+		in.kind = inputContact
+		in.contact = m.Contact
+	}
+
+	customer, err := f.customers.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customers.GetByTelegramID: %w", err)
+	}
+
+	entry, ok := f.steps[customer.TgState]
+	if !ok {
+		return ErrNoHandler
+	}
+	// Customer typed something instead of pressing a button, remind what's expected
+	if entry.step.accepts&in.kind == 0 {
+		return f.sendPrompt(ctx, customer, entry.step, false)
+	}
+	return f.process(ctx, &customer, entry.step, in)
+}
+
+// handleButton passes value of pressed button to step, button must belong to the step customer is at
+func (f *fsm) handleButton(ctx context.Context, chatID int64, state domain.State, value any) error {
+	var telegramID = chatID
+
+	customer, err := f.customers.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customers.GetByTelegramID: %w", err)
+	}
+
+	entry, ok := f.steps[state]
+	if !ok || customer.TgState != state || entry.step.accepts&inputButton == 0 {
+		return ErrInvalidState
+	}
+	return f.process(ctx, &customer, entry.step, flowInput{kind: inputButton, value: value, fromID: telegramID})
+}
+
+func (f *fsm) process(ctx context.Context, customer *domain.Customer, step flowStep, in flowInput) error {
+	if step.validate != nil {
+		if err := step.validate(ctx, in); err != nil {
+			return f.reject(ctx, *customer, step, err)
+		}
+	}
+
+	next, err := step.handle(ctx, customer, in)
+	if err != nil {
+		return f.reject(ctx, *customer, step, err)
+	}
+	if next == step.state {
+		return nil
+	}
+	return f.enter(ctx, *customer, next)
+}
+
+// reject repeats step prompt if input is invalid, other errors are returned as is
+func (f *fsm) reject(ctx context.Context, customer domain.Customer, step flowStep, err error) error {
+	var invalid invalidInput
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	if err := f.send(ctx, customer.TelegramID, invalid.Error(), nil); err != nil {
+		return err
+	}
+	return f.sendPrompt(ctx, customer, step, false)
+}
+
+// enter moves customer to state and sends prompt of the step. Flows are started with it as well
+func (f *fsm) enter(ctx context.Context, customer domain.Customer, state domain.State) error {
+	return f.moveTo(ctx, customer, state, false)
+}
+
+func (f *fsm) moveTo(ctx context.Context, customer domain.Customer, state domain.State, returned bool) error {
+	if err := f.customers.UpdateState(ctx, customer.TelegramID, state); err != nil {
+		return fmt.Errorf("customers.UpdateState: %w", err)
+	}
+	customer.TgState = state
+
+	entry, ok := f.steps[state]
+	if !ok {
+		// Flow is finished
+		return nil
+	}
+	return f.sendPrompt(ctx, customer, entry.step, returned)
+}
+
+// sendPrompt sends step prompt with navigation buttons, returned is true if customer came back to the step
+func (f *fsm) sendPrompt(ctx context.Context, customer domain.Customer, step flowStep, returned bool) error {
+	var chatID = customer.TelegramID
+
+	if returned && step.answer != nil {
+		if answer := step.answer(ctx, customer); answer != "" {
+			if err := f.send(ctx, chatID, getPreviousAnswer(ctx, answer), nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	p, err := step.prompt(ctx, customer)
+	if err != nil {
+		return err
+	}
+
+	// Back from the first step is the same as cancel
+	_, hasBack := f.steps[step.back(customer)]
+	navigation := f.codec.prepareFlowNavigationButtons(ctx, hasBack)
+	if p.keyboard != nil {
+		if err := f.send(ctx, chatID, p.text, *p.keyboard); err != nil {
+			return err
+		}
+		return f.send(ctx, chatID, tr(ctx, "flow.navigation"), navigation)
+	}
+
+	rows := make([][]tg.InlineKeyboardButton, 0, len(p.buttons)+1)
+	rows = append(rows, p.buttons...)
+	rows = append(rows, navigation.InlineKeyboard...)
+	return f.send(ctx, chatID, p.text, tg.NewInlineKeyboardMarkup(rows...))
+}
+
+// back returns customer to previous step of the flow. Leaving the flow backwards cancels it, left is true then
+func (f *fsm) back(ctx context.Context, chatID int64) (left bool, err error) {
+	var telegramID = chatID
+
+	customer, err := f.customers.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return false, fmt.Errorf("customers.GetByTelegramID: %w", err)
+	}
+
+	entry, ok := f.steps[customer.TgState]
+	if !ok {
+		return false, ErrInvalidState
+	}
+
+	prev := entry.step.back(customer)
+	if _, ok := f.steps[prev]; !ok {
+		return true, f.cancelFlow(ctx, customer, entry.flow)
+	}
+	return false, f.moveTo(ctx, customer, prev, true)
+}
+
+// inFlow is true if state is a step of any flow
+func (f *fsm) inFlow(state domain.State) bool {
+	_, ok := f.steps[state]
+	return ok
+}
+
+// cancel drops data of the flow customer is in and leaves it
+func (f *fsm) cancel(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := f.customers.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customers.GetByTelegramID: %w", err)
+	}
+
+	entry, ok := f.steps[customer.TgState]
+	if !ok {
+		return ErrInvalidState
+	}
+	return f.cancelFlow(ctx, customer, entry.flow)
+}
+
+func (f *fsm) cancelFlow(ctx context.Context, customer domain.Customer, fl *flow) error {
+	if fl.cancel != nil {
+		if err := fl.cancel(ctx, customer); err != nil {
+			return fmt.Errorf("cancel %s: %w", fl.name, err)
+		}
+	}
+	return f.enter(ctx, customer, domain.StateDefault)
+}
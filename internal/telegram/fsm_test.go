@@ -0,0 +1,259 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// memStates keeps only states of customers
+type memStates map[int64]domain.State
+
+func (m memStates) GetByTelegramID(ctx context.Context, telegramID int64) (domain.Customer, error) {
+	state, ok := m[telegramID]
+	if !ok {
+		return domain.Customer{}, domain.ErrCustomerNotFound
+	}
+	return domain.Customer{TelegramID: telegramID, TgState: state}, nil
+}
+
+func (m memStates) UpdateState(ctx context.Context, telegramID int64, newState domain.State) error {
+	m[telegramID] = newState
+	return nil
+}
+
+var (
+	stateFirst  = domain.State{V: 101}
+	stateSecond = domain.State{V: 102}
+	stateThird  = domain.State{V: 103}
+)
+
+type fsmRecorder struct {
+	prompts []domain.State
+	// Every sent message in order
+	sent      []sentMessage
+	cancelled int
+}
+
+type sentMessage struct {
+	text   string
+	markup interface{}
+}
+
+// replies are messages sent without markup
+func (r *fsmRecorder) replies() []string {
+	var out []string
+	for _, m := range r.sent {
+		if m.markup == nil {
+			out = append(out, m.text)
+		}
+	}
+	return out
+}
+
+// testFlow is first (button) -> second (text, must be "ok") -> third (text) -> done
+func testFlow(rec *fsmRecorder) flow {
+	prompt := func(state domain.State) func(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+		return func(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+			rec.prompts = append(rec.prompts, state)
+			return flowPrompt{text: "prompt"}, nil
+		}
+	}
+	return flow{
+		name: "test",
+		steps: []flowStep{
+			{
+				state:   stateFirst,
+				accepts: inputButton,
+				prompt:  prompt(stateFirst),
+				answer: func(ctx context.Context, customer domain.Customer) string {
+					return "first answer"
+				},
+				handle: func(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+					return stateSecond, nil
+				},
+			},
+			{
+				state:   stateSecond,
+				accepts: inputText,
+				validate: func(ctx context.Context, in flowInput) error {
+					if in.text != "ok" {
+						return invalidInput("not ok")
+					}
+					return nil
+				},
+				prompt: prompt(stateSecond),
+				handle: func(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+					return stateThird, nil
+				},
+				back: backTo(stateFirst),
+			},
+			{
+				state:   stateThird,
+				accepts: inputText | inputContact,
+				prompt:  prompt(stateThird),
+				handle: func(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+					return domain.StateDefault, nil
+				},
+				back: backTo(stateSecond),
+			},
+		},
+		cancel: func(ctx context.Context, customer domain.Customer) error {
+			rec.cancelled++
+			return nil
+		},
+	}
+}
+
+func newTestFSM(states memStates, rec *fsmRecorder) *fsm {
+	send := func(ctx context.Context, chatID int64, text string, markup interface{}) error {
+		rec.sent = append(rec.sent, sentMessage{text: text, markup: markup})
+		return nil
+	}
+	return newFSM(states, NewCallbackCodec(newMemoryCallbackStore()), send, testFlow(rec))
+}
+
+func textMessage(telegramID int64, text string) *tg.Message {
+	return &tg.Message{From: &tg.User{ID: telegramID}, Text: text}
+}
+
+func TestFSM(t *testing.T) {
+	const telegramID = 1
+	ctx := context.Background()
+
+	t.Run("walks through flow", func(t *testing.T) {
+		var (
+			rec    = new(fsmRecorder)
+			states = memStates{telegramID: domain.StateDefault}
+			f      = newTestFSM(states, rec)
+		)
+
+		require.NoError(t, f.enter(ctx, domain.Customer{TelegramID: telegramID}, stateFirst))
+		require.Equal(t, stateFirst, states[telegramID])
+
+		// Button of another step
+		require.ErrorIs(t, f.handleButton(ctx, telegramID, stateSecond, nil), ErrInvalidState)
+		// Text instead of button repeats prompt
+		require.NoError(t, f.handleMessage(ctx, textMessage(telegramID, "hi")))
+		require.Equal(t, stateFirst, states[telegramID])
+
+		require.NoError(t, f.handleButton(ctx, telegramID, stateFirst, 1))
+		require.Equal(t, stateSecond, states[telegramID])
+
+		require.NoError(t, f.handleMessage(ctx, textMessage(telegramID, "ok")))
+		require.Equal(t, stateThird, states[telegramID])
+
+		require.NoError(t, f.handleMessage(ctx, textMessage(telegramID, "anything")))
+		require.Equal(t, domain.StateDefault, states[telegramID])
+		require.Equal(t, []domain.State{stateFirst, stateFirst, stateSecond, stateThird}, rec.prompts)
+
+		// Flow is finished
+		require.ErrorIs(t, f.handleMessage(ctx, textMessage(telegramID, "again")), ErrNoHandler)
+	})
+
+	t.Run("invalid input repeats prompt", func(t *testing.T) {
+		var (
+			rec    = new(fsmRecorder)
+			states = memStates{telegramID: stateSecond}
+			f      = newTestFSM(states, rec)
+		)
+
+		require.NoError(t, f.handleMessage(ctx, textMessage(telegramID, "not ok")))
+		require.Equal(t, stateSecond, states[telegramID])
+		require.Equal(t, []string{"not ok"}, rec.replies())
+		require.Equal(t, []domain.State{stateSecond}, rec.prompts)
+	})
+
+	t.Run("back", func(t *testing.T) {
+		var (
+			rec    = new(fsmRecorder)
+			states = memStates{telegramID: stateThird}
+			f      = newTestFSM(states, rec)
+		)
+
+		left, err := f.back(ctx, telegramID)
+		require.NoError(t, err)
+		require.False(t, left)
+		require.Equal(t, stateSecond, states[telegramID])
+		_, err = f.back(ctx, telegramID)
+		require.NoError(t, err)
+		require.Equal(t, stateFirst, states[telegramID])
+		require.Equal(t, []domain.State{stateSecond, stateFirst}, rec.prompts)
+		// Earlier answer is shown before prompt of the step customer returned to
+		require.Equal(t, []string{getPreviousAnswer(context.Background(), "first answer")}, rec.replies())
+
+		// Back from the first step leaves the flow
+		left, err = f.back(ctx, telegramID)
+		require.NoError(t, err)
+		require.True(t, left)
+		require.Equal(t, domain.StateDefault, states[telegramID])
+		require.Equal(t, 1, rec.cancelled)
+
+		_, err = f.back(ctx, telegramID)
+		require.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		var (
+			rec    = new(fsmRecorder)
+			states = memStates{telegramID: stateSecond}
+			f      = newTestFSM(states, rec)
+		)
+
+		require.NoError(t, f.cancel(ctx, telegramID))
+		require.Equal(t, domain.StateDefault, states[telegramID])
+		require.Equal(t, 1, rec.cancelled)
+		require.Empty(t, rec.prompts)
+	})
+
+	t.Run("prompts have navigation buttons", func(t *testing.T) {
+		var (
+			rec    = new(fsmRecorder)
+			states = memStates{telegramID: domain.StateDefault}
+			f      = newTestFSM(states, rec)
+		)
+
+		require.NoError(t, f.enter(ctx, domain.Customer{TelegramID: telegramID}, stateFirst))
+		require.NoError(t, f.handleButton(ctx, telegramID, stateFirst, 1))
+		require.Len(t, rec.sent, 2)
+
+		// Nothing to go back to from the first step
+		require.Equal(t, f.codec.prepareFlowNavigationButtons(ctx, false), rec.sent[0].markup)
+		require.Equal(t, f.codec.prepareFlowNavigationButtons(ctx, true), rec.sent[1].markup)
+	})
+
+	t.Run("misconfigured flows", func(t *testing.T) {
+		rec := new(fsmRecorder)
+		require.Panics(t, func() {
+			newFSM(memStates{}, nil, nil, testFlow(rec), testFlow(rec))
+		})
+		require.Panics(t, func() {
+			newFSM(memStates{}, nil, nil, flow{name: "empty", steps: []flowStep{{state: stateFirst}}})
+		})
+	})
+}
+
+func TestHandlerFlows(t *testing.T) {
+	h := &handler{}
+	f := newFSM(memStates{}, nil, h.sendWithKeyboard, h.positionFlow(), h.calculatorFlow(), h.detailsFlow())
+
+	customers := []domain.Customer{
+		{},
+		{Meta: domain.Meta{EditingProfile: true, EditPositionN: 1}},
+	}
+	// Every step goes back to another step or leaves the flow
+	for state, entry := range f.steps {
+		for _, customer := range customers {
+			prev := entry.step.back(customer)
+			if prev == domain.StateDefault {
+				continue
+			}
+			_, ok := f.steps[prev]
+			require.True(t, ok, "state %d goes back to unknown state %d", state.V, prev.V)
+			require.NotEqual(t, state, prev)
+		}
+	}
+}
@@ -3,21 +3,30 @@ package telegram
 import (
 	"context"
 	"errors"
+	"time"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories"
 	"github.com/sonyamoonglade/poison-tg/internal/telegram/catalog"
 )
 
 var (
-	ErrInvalidState      = errors.New("invalid state")
-	ErrInvalidPriceInput = errors.New("invalid price input")
+	ErrInvalidState = errors.New("invalid state")
 )
 
+// Error reply is sent when handler is done, ctx of handler might be done by then
+const errorReplyTimeout = time.Second * 5
+
 type RateProvider interface {
 	GetYuanRate() float64
 }
 
+// PickupPointDirectory looks up CDEK and PickPoint offices by code
+type PickupPointDirectory interface {
+	Find(ctx context.Context, provider domain.PickupProvider, code string) (domain.PickupPoint, error)
+}
+
 type Bot interface {
 	Send(c tg.Chattable) (tg.Message, error)
 	CleanRequest(c tg.Chattable) error
@@ -28,31 +37,53 @@ type handler struct {
 	b               Bot
 	customerRepo    repositories.Customer
 	orderRepo       repositories.Order
+	catalogRepo     repositories.Catalog
 	rateProvider    RateProvider
 	catalogProvider *catalog.CatalogProvider
+	mediaCache      *mediaCache
+	pickupPoints    PickupPointDirectory
+	codec           *CallbackCodec
+	flows           *fsm
+	// Notifications customer didn't ask for, they go after replies
+	bulk Bot
 }
 
 func NewHandler(bot Bot,
+	bulkBot Bot,
 	repositories repositories.Repositories,
 	rateProvider RateProvider,
-	catalogProvider *catalog.CatalogProvider) *handler {
-	return &handler{
+	catalogProvider *catalog.CatalogProvider,
+	pickupPoints PickupPointDirectory,
+	codec *CallbackCodec) *handler {
+	h := &handler{
 		b:               bot,
+		bulk:            bulkBot,
 		customerRepo:    repositories.Customer,
 		orderRepo:       repositories.Order,
+		catalogRepo:     repositories.Catalog,
 		catalogProvider: catalogProvider,
 		rateProvider:    rateProvider,
+		mediaCache:      newMediaCache(repositories.Media),
+		pickupPoints:    pickupPoints,
+		codec:           codec,
 	}
+	h.flows = newFSM(h.customerRepo, h.codec, h.sendWithKeyboard, h.positionFlow(), h.calculatorFlow(), h.detailsFlow())
+	return h
 }
 
-func (h *handler) AnswerCallback(callbackID string) error {
-	return h.cleanSend(tg.NewCallback(callbackID, ""))
+func (h *handler) AnswerCallback(ctx context.Context, callbackID string) error {
+	return h.cleanSend(ctx, tg.NewCallback(callbackID, ""))
 }
 
+// HandleError replies depending on kind of err, see classifyError. Internal errors are shown with correlation id of update
 func (h *handler) HandleError(ctx context.Context, err error, m tg.Update) {
-	if errors.Is(err, ErrInvalidPriceInput) {
-		h.sendMessage(m.FromChat().ID, "Неправильный формат ввода")
+	chat := m.FromChat()
+	if chat == nil {
 		return
 	}
-	h.b.Send(tg.NewMessage(m.FromChat().ID, "Извини, я не понимаю тебя :("))
+	_, reply := classifyError(ctx, err)
+	// ctx is done if handler timed out, customer is replied anyway
+	sendCtx, cancel := context.WithTimeout(context.Background(), errorReplyTimeout)
+	defer cancel()
+	h.sendMessage(sendCtx, chat.ID, reply)
 }
@@ -12,283 +12,343 @@ import (
 	"github.com/sonyamoonglade/poison-tg/pkg/utils/url"
 )
 
+// positionFlow fills in new position step by step. Size, button, price and link steps
+// also edit single field of existing position, see h.StartPositionEdit
+func (h *handler) positionFlow() flow {
+	return flow{
+		name: "position",
+		steps: []flowStep{
+			{
+				state:   domain.StateWaitingForOrderType,
+				accepts: inputButton,
+				prompt:  h.askForOrderType,
+				answer:  answerOrderType,
+				handle:  h.handleOrderType,
+			},
+			{
+				state:   domain.StateWaitingForCategory,
+				accepts: inputButton,
+				prompt:  h.askForCategory,
+				answer:  answerPosition(func(ctx context.Context, p domain.Position) string { return getCategoryText(ctx, p.Category) }),
+				handle:  h.handleCategory,
+				back:    backTo(domain.StateWaitingForOrderType),
+			},
+			{
+				state:   domain.StateWaitingForSize,
+				accepts: inputText,
+				prompt:  h.askForSize,
+				answer:  answerPosition(func(ctx context.Context, p domain.Position) string { return p.Size }),
+				handle:  h.handleSize,
+				back:    positionBack(domain.StateWaitingForCategory),
+			},
+			{
+				state:   domain.StateWaitingForButton,
+				accepts: inputButton,
+				prompt:  h.askForButtonColor,
+				answer:  answerPosition(func(ctx context.Context, p domain.Position) string { return getButtonColorText(ctx, p.Button) }),
+				handle:  h.handleButtonColor,
+				back:    positionBack(domain.StateWaitingForSize),
+			},
+			{
+				state:    domain.StateWaitingForPrice,
+				accepts:  inputText,
+				validate: validatePrice,
+				prompt:   h.askForPrice,
+				answer:   answerPosition(answerPrice),
+				handle:   h.handlePrice,
+				back:     positionBack(domain.StateWaitingForButton),
+			},
+			{
+				state:    domain.StateWaitingForLink,
+				accepts:  inputText,
+				validate: validateLink,
+				prompt:   h.askForLink,
+				answer:   answerPosition(func(ctx context.Context, p domain.Position) string { return p.ShopLink }),
+				handle:   h.handleLink,
+				back:     positionBack(domain.StateWaitingForPrice),
+			},
+		},
+		cancel: h.cancelPosition,
+	}
+}
+
+// positionBack leaves the flow if single field of existing position is edited
+func positionBack(prev domain.State) func(customer domain.Customer) domain.State {
+	return func(customer domain.Customer) domain.State {
+		if customer.IsEditingPosition() {
+			return domain.StateDefault
+		}
+		return prev
+	}
+}
+
+// answerPosition shows field of position being filled in
+func answerPosition(field func(ctx context.Context, p domain.Position) string) func(ctx context.Context, customer domain.Customer) string {
+	return func(ctx context.Context, customer domain.Customer) string {
+		if customer.LastEditPosition == nil {
+			return ""
+		}
+		return field(ctx, *customer.LastEditPosition)
+	}
+}
+
+func answerOrderType(ctx context.Context, customer domain.Customer) string {
+	if customer.Meta.NextOrderType == nil {
+		return ""
+	}
+	return getOrderTypeText(ctx, *customer.Meta.NextOrderType)
+}
+
+func answerPrice(ctx context.Context, p domain.Position) string {
+	if p.PriceYUAN == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d ¥", p.PriceYUAN)
+}
+
 func (h *handler) AddPosition(ctx context.Context, chatID int64) error {
 	var (
 		telegramID = chatID
 	)
-	if err := h.sendMessage(chatID, newPositionWarnTemplate); err != nil {
-		return err
-	}
 	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		return err
 	}
-	if len(customer.Cart) == 0 {
-		if err := h.customerRepo.UpdateState(ctx, telegramID, domain.StateWaitingForOrderType); err != nil {
-			return err
-		}
-		// Start from scratch
-		return h.askForOrderType(ctx, chatID)
-	}
-	if err := h.customerRepo.UpdateState(ctx, telegramID, domain.StateWaitingForCategory); err != nil {
+	if err := h.stopPositionEdit(ctx, customer); err != nil {
 		return err
 	}
-	// Otherwise start from category selection
-	return h.askForCategory(ctx, chatID)
+	// Every position has its own delivery type
+	return h.flows.enter(ctx, customer, domain.StateWaitingForOrderType)
 }
 
-func (h *handler) askForCategory(ctx context.Context, chatID int64) error {
-	if err := h.customerRepo.UpdateState(ctx, chatID, domain.StateWaitingForCategory); err != nil {
-		return err
-	}
-	return h.sendWithKeyboard(chatID, askForCategoryTemplate, categoryButtons)
+func (h *handler) HandleOrderTypeInput(ctx context.Context, chatID int64, typ domain.OrderType) error {
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForOrderType, typ)
 }
 
 func (h *handler) HandleCategoryInput(ctx context.Context, chatID int64, cat domain.Category) error {
-	var telegramID = chatID
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForCategory, cat)
+}
 
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForCategory, chatID); err != nil {
-		return err
-	}
+func (h *handler) HandleButtonSelect(ctx context.Context, c *tg.CallbackQuery, button domain.Button) error {
+	return h.flows.handleButton(ctx, c.From.ID, domain.StateWaitingForButton, button)
+}
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return err
+func (h *handler) askForOrderType(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_order_type"), buttons: h.codec.orderTypeButtons(ctx).InlineKeyboard}, nil
+}
+
+func (h *handler) handleOrderType(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	typ, ok := in.value.(domain.OrderType)
+	if !ok {
+		return domain.StateDefault, ErrInvalidState
 	}
 
-	customer.UpdateLastEditPositionCategory(cat)
+	customer.UpdateMetaOrderType(typ)
+
 	updateDTO := dto.UpdateCustomerDTO{
-		LastPosition: customer.LastEditPosition,
+		Meta: &customer.Meta,
 	}
-
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, err
 	}
 
-	if err := h.sendMessage(chatID, fmt.Sprintf("Выбрана категория: %s", string(cat))); err != nil {
-		return err
+	if err := h.sendMessage(ctx, customer.TelegramID, tr(ctx, "position.order_type_selected", getOrderTypeText(ctx, typ))); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.askForSize(ctx, chatID)
+	return domain.StateWaitingForCategory, nil
 }
 
-func (h *handler) askForOrderType(ctx context.Context, chatID int64) error {
-	text := "Выбери тип доставки"
-	return h.sendWithKeyboard(chatID, text, orderTypeButtons)
+func (h *handler) askForCategory(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_category"), buttons: h.codec.categoryButtons(ctx).InlineKeyboard}, nil
 }
 
-func (h *handler) HandleOrderTypeInput(ctx context.Context, chatID int64, typ domain.OrderType) error {
-	var (
-		telegramID = chatID
-		isExpress  = typ == domain.OrderTypeExpress
-	)
-
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForOrderType, chatID); err != nil {
-		return err
+func (h *handler) handleCategory(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	cat, ok := in.value.(domain.Category)
+	if !ok {
+		return domain.StateDefault, ErrInvalidState
 	}
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return err
-	}
-
-	customer.UpdateMetaOrderType(typ)
-
+	customer.UpdateLastEditPositionCategory(cat)
 	updateDTO := dto.UpdateCustomerDTO{
-		Meta:  &customer.Meta,
-		State: &domain.StateWaitingForCategory,
+		LastPosition: customer.LastEditPosition,
 	}
+
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return err
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
 
-	var resp = "Тип доставки: "
-	switch isExpress {
-	case true:
-		resp += "Экспресс"
-		break
-	case false:
-		resp += "Обычный"
-		break
-	}
-	if err := h.sendMessage(chatID, resp); err != nil {
-		return err
+	if err := h.sendMessage(ctx, customer.TelegramID, tr(ctx, "position.category_selected", getCategoryText(ctx, cat))); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.askForCategory(ctx, chatID)
+	return domain.StateWaitingForSize, nil
 }
 
-func (h *handler) askForSize(ctx context.Context, chatID int64) error {
-	if err := h.sendWithKeyboard(chatID, askForSizeTemplate, bottomMenuWithoutAddPositionButtons); err != nil {
-		return err
-	}
-
-	return h.customerRepo.UpdateState(ctx, chatID, domain.StateWaitingForSize)
+func (h *handler) askForSize(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	keyboard := bottomMenuWithoutAddPositionButtons(ctx)
+	return flowPrompt{text: tr(ctx, "position.ask_size"), keyboard: &keyboard}, nil
 }
 
-func (h *handler) HandleSizeInput(ctx context.Context, m *tg.Message) error {
+func (h *handler) handleSize(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
 	var (
-		chatID     = m.Chat.ID
-		telegramID = chatID
-		sizeText   = strings.TrimSpace(m.Text)
+		chatID   = customer.TelegramID
+		sizeText = strings.TrimSpace(in.text)
 	)
 
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForSize, chatID); err != nil {
-		return err
-	}
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
-	}
-
 	customer.UpdateLastEditPositionSize(sizeText)
+	if customer.IsEditingPosition() {
+		return domain.StateDefault, h.finishPositionEdit(ctx, chatID, *customer)
+	}
 
 	updateDTO := dto.UpdateCustomerDTO{
 		LastPosition: customer.LastEditPosition,
-		State:        &domain.StateWaitingForButton,
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
 	if sizeText == "#" {
-		sizeText = "БЕЗ размера"
+		sizeText = tr(ctx, "position.no_size")
 	}
-	if err := h.sendMessage(chatID, fmt.Sprintf("Твой размер: %s", sizeText)); err != nil {
-		return err
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "position.size_selected", sizeText)); err != nil {
+		return domain.StateDefault, err
 	}
-	return h.sendWithKeyboard(chatID, askForButtonColorTemplate, selectColorButtons)
+	return domain.StateWaitingForButton, nil
 }
 
-func (h *handler) HandleButtonSelect(ctx context.Context, c *tg.CallbackQuery, button domain.Button) error {
-	var (
-		chatID     = c.From.ID
-		telegramID = chatID
-	)
+func (h *handler) askForButtonColor(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_button_color"), buttons: h.codec.selectColorButtons(ctx).InlineKeyboard}, nil
+}
 
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForButton, chatID); err != nil {
-		return err
-	}
+func (h *handler) handleButtonColor(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	var chatID = customer.TelegramID
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	button, ok := in.value.(domain.Button)
+	if !ok {
+		return domain.StateDefault, ErrInvalidState
 	}
 
 	customer.UpdateLastEditPositionButtonColor(button)
+	if customer.IsEditingPosition() {
+		return domain.StateDefault, h.finishPositionEdit(ctx, chatID, *customer)
+	}
 	updateDTO := dto.UpdateCustomerDTO{
 		LastPosition: customer.LastEditPosition,
-		State:        &domain.StateWaitingForPrice,
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
-	if err := h.sendMessage(chatID, fmt.Sprintf("Цвет выбранной кнопки: %s", string(button))); err != nil {
-		return err
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "position.button_color_selected", getButtonColorText(ctx, button))); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.sendMessage(chatID, askForPriceTemplate)
+	return domain.StateWaitingForPrice, nil
 }
 
-func (h *handler) HandlePriceInput(ctx context.Context, m *tg.Message) error {
-	var (
-		chatID     = m.Chat.ID
-		telegramID = chatID
-		input      = strings.TrimSpace(m.Text)
-	)
-	// validate state
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForPrice, chatID); err != nil {
-		return err
+func validatePrice(ctx context.Context, in flowInput) error {
+	if _, err := strconv.ParseUint(strings.TrimSpace(in.text), 10, 64); err != nil {
+		return invalidInput(tr(ctx, "position.invalid_price"))
 	}
+	return nil
+}
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
-	}
+func (h *handler) askForPrice(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_price")}, nil
+}
 
-	priceYuan, err := strconv.ParseUint(input, 10, 64)
-	if err != nil {
-		return ErrInvalidPriceInput
-	}
-	var (
-		ordTyp = customer.Meta.NextOrderType
-	)
-	if ordTyp == nil {
-		return fmt.Errorf("order type in meta is nil")
+func (h *handler) handlePrice(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	var chatID = customer.TelegramID
+
+	// Input is validated already
+	priceYuan, _ := strconv.ParseUint(strings.TrimSpace(in.text), 10, 64)
+	ordTyp, ok := customer.PositionOrderType()
+	if !ok {
+		return domain.StateDefault, fmt.Errorf("order type in meta is nil")
 	}
-	// We should apply customer.Meta and customer.LastEditPosition.Category in order to calculate correctly
+	// We should apply order type and customer.LastEditPosition.Category in order to calculate correctly
 	args := domain.ConvertYuanArgs{
 		X:         priceYuan,
 		Rate:      h.rateProvider.GetYuanRate(),
-		OrderType: *ordTyp,
+		OrderType: ordTyp,
 		Category:  customer.LastEditPosition.Category,
 	}
 
 	priceRub := domain.ConvertYuan(args)
 	customer.UpdateLastEditPositionPrice(priceRub, priceYuan)
+	customer.UpdateLastEditPositionOrderType(ordTyp)
+	if customer.IsEditingPosition() {
+		if err := h.sendMessage(ctx, chatID, tr(ctx, "position.price_selected", priceRub)); err != nil {
+			return domain.StateDefault, err
+		}
+		return domain.StateDefault, h.finishPositionEdit(ctx, chatID, *customer)
+	}
 
 	updateDTO := dto.UpdateCustomerDTO{
 		LastPosition: customer.LastEditPosition,
-		State:        &domain.StateWaitingForLink,
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
 
-	if err := h.sendMessage(chatID, fmt.Sprintf("Стоимость товара: %d ₽", priceRub)); err != nil {
-		return err
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "position.price_selected", priceRub)); err != nil {
+		return domain.StateDefault, err
 	}
 
-	if err := h.sendMessage(chatID, deliveryOnlyToMoscowTemplate); err != nil {
-		return err
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "delivery.only_to_moscow")); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.sendMessage(chatID, askForLinkTemplate)
+	return domain.StateWaitingForLink, nil
 }
 
-func (h *handler) HandleLinkInput(ctx context.Context, m *tg.Message) error {
-	var (
-		chatID     = m.From.ID
-		telegramID = chatID
-		link       = strings.TrimSpace(m.Text)
-	)
-
-	// validate state
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForLink, chatID); err != nil {
-		return err
+func validateLink(ctx context.Context, in flowInput) error {
+	if ok := url.IsValidDW4URL(strings.TrimSpace(in.text)); !ok {
+		return invalidInput(tr(ctx, "position.invalid_link"))
 	}
+	return nil
+}
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
-	}
+func (h *handler) askForLink(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_link")}, nil
+}
 
-	if ok := url.IsValidDW4URL(link); !ok {
-		if err := h.sendMessage(chatID, "Неправильная ссылка! Смотри инструкцию"); err != nil {
-			return err
-		}
-		return h.sendMessage(chatID, "Введи повторно корректную ссылку 😀")
-	}
+func (h *handler) handleLink(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	var (
+		chatID = customer.TelegramID
+		link   = strings.TrimSpace(in.text)
+	)
 
 	customer.UpdateLastEditPositionLink(link)
+	if customer.IsEditingPosition() {
+		return domain.StateDefault, h.finishPositionEdit(ctx, chatID, *customer)
+	}
 	customer.Cart.Add(*customer.LastEditPosition)
 	updateDTO := dto.UpdateCustomerDTO{
 		LastPosition: customer.LastEditPosition,
 		Cart:         &customer.Cart,
-		State:        &domain.StateDefault,
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
 
-	if err := h.sendMessage(chatID, fmt.Sprintf("Товар по ссылке: %s", link)); err != nil {
-		return err
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "position.link_selected", link)); err != nil {
+		return domain.StateDefault, err
 	}
 
-	positionAddedMsg := tg.NewMessage(chatID, "Позиция успешно добавлена!")
-	positionAddedMsg.ReplyMarkup = bottomMenuButtons
-	return h.cleanSend(positionAddedMsg)
+	positionAddedMsg := tg.NewMessage(chatID, tr(ctx, "position.added"))
+	positionAddedMsg.ReplyMarkup = bottomMenuButtons(ctx)
+	return domain.StateDefault, h.cleanSend(ctx, positionAddedMsg)
+}
+
+// cancelPosition drops unfinished position, position being edited stays in cart as it was
+func (h *handler) cancelPosition(ctx context.Context, customer domain.Customer) error {
+	var none uint
+	return h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		LastPosition:  &domain.Position{},
+		EditPositionN: &none,
+	})
 }
@@ -0,0 +1,212 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+// Structured address is typed in field by field in this order. Pickup provider is selected with buttons
+// between house and pickup point code, see h.addressSteps
+var addressInputSteps = []struct {
+	state domain.State
+	field domain.AddressField
+}{
+	{state: domain.StateWaitingForAddressCountry, field: domain.AddressFieldCountry},
+	{state: domain.StateWaitingForAddressRegion, field: domain.AddressFieldRegion},
+	{state: domain.StateWaitingForAddressCity, field: domain.AddressFieldCity},
+	{state: domain.StateWaitingForAddressStreet, field: domain.AddressFieldStreet},
+	{state: domain.StateWaitingForAddressHouse, field: domain.AddressFieldHouse},
+	{state: domain.StateWaitingForPickupPointCode, field: domain.AddressFieldPickupPointCode},
+}
+
+// addressSteps are steps of structured address input, both for order and for profile
+func (h *handler) addressSteps() []flowStep {
+	steps := make([]flowStep, 0, len(addressInputSteps)+1)
+	prev := domain.StateDefault
+	for i, s := range addressInputSteps {
+		var (
+			field = s.field
+			next  = domain.StateDefault
+		)
+		if i+1 < len(addressInputSteps) {
+			next = addressInputSteps[i+1].state
+		}
+		if field == domain.AddressFieldHouse {
+			next = domain.StateWaitingForPickupProvider
+		}
+		if field == domain.AddressFieldPickupPointCode {
+			steps = append(steps, flowStep{
+				state:   domain.StateWaitingForPickupProvider,
+				accepts: inputButton,
+				prompt:  h.askForPickupProvider,
+				answer:  answerPickupProvider,
+				handle:  h.handlePickupProvider,
+				back:    backTo(prev),
+			})
+			prev = domain.StateWaitingForPickupProvider
+		}
+
+		step := flowStep{
+			state:   s.state,
+			accepts: inputText,
+			prompt: func(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+				return flowPrompt{text: getAddressStepTemplate(ctx, field)}, nil
+			},
+			answer: func(ctx context.Context, customer domain.Customer) string {
+				if pending := customer.Meta.PendingAddress; pending != nil && pending.Details != nil {
+					return pending.Details.Get(field)
+				}
+				return ""
+			},
+			handle: func(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+				return h.handleAddressField(ctx, customer, field, in.text, next)
+			},
+			back: backTo(prev),
+		}
+		if field == domain.AddressFieldCountry {
+			step.back = addressInputBack
+		}
+		steps = append(steps, step)
+		prev = s.state
+	}
+	return steps
+}
+
+// addressInputBack returns to the step address input was started from
+func addressInputBack(customer domain.Customer) domain.State {
+	if customer.Meta.EditingProfile {
+		return domain.StateWaitingForProfileAddress
+	}
+	if hasDeliveryAddresses(customer.Addresses) {
+		return domain.StateWaitingForDeliveryAddress
+	}
+	return domain.StateWaitingForPhoneNumber
+}
+
+// beginAddressInput prepares empty address named name, customer is to be moved to the first address step
+func (h *handler) beginAddressInput(ctx context.Context, customer *domain.Customer, name string) (domain.State, error) {
+	customer.Meta.PendingAddress = &domain.Address{
+		Name:    name,
+		Details: &domain.StructuredAddress{},
+	}
+	updateDTO := dto.UpdateCustomerDTO{
+		PendingAddress: customer.Meta.PendingAddress,
+	}
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
+	}
+	return addressInputSteps[0].state, nil
+}
+
+func (h *handler) NewDeliveryAddress(ctx context.Context, chatID int64) error {
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForDeliveryAddress, newAddressInput{})
+}
+
+func (h *handler) HandlePickupProviderSelect(ctx context.Context, chatID int64, provider domain.PickupProvider) error {
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForPickupProvider, provider)
+}
+
+func (h *handler) handleAddressField(ctx context.Context, customer *domain.Customer, field domain.AddressField, value string, next domain.State) (domain.State, error) {
+	pending := customer.Meta.PendingAddress
+	if pending == nil || pending.Details == nil {
+		return domain.StateDefault, ErrInvalidState
+	}
+
+	if err := pending.Details.Set(field, value); err != nil {
+		if errors.Is(err, domain.ErrInvalidAddress) {
+			return domain.StateDefault, invalidInput(tr(ctx, "address.invalid_field"))
+		}
+		return domain.StateDefault, err
+	}
+
+	if field == domain.AddressFieldPickupPointCode {
+		return domain.StateDefault, h.finishAddressInput(ctx, customer)
+	}
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		PendingAddress: pending,
+	}); err != nil {
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
+	}
+	return next, nil
+}
+
+func (h *handler) askForPickupProvider(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "address.ask_pickup_provider"), buttons: h.codec.pickupProviderButtons(ctx).InlineKeyboard}, nil
+}
+
+func answerPickupProvider(ctx context.Context, customer domain.Customer) string {
+	if pending := customer.Meta.PendingAddress; pending != nil && pending.Details != nil {
+		return getPickupProviderText(ctx, pending.Details.Provider)
+	}
+	return ""
+}
+
+func (h *handler) handlePickupProvider(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	provider, ok := in.value.(domain.PickupProvider)
+	pending := customer.Meta.PendingAddress
+	if !ok || pending == nil || pending.Details == nil {
+		return domain.StateDefault, ErrInvalidState
+	}
+	pending.Details.Provider = provider
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		PendingAddress: pending,
+	}); err != nil {
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	return domain.StateWaitingForPickupPointCode, nil
+}
+
+// finishAddressInput checks pickup point against directory and either saves address to profile or places order
+func (h *handler) finishAddressInput(ctx context.Context, customer *domain.Customer) error {
+	var (
+		chatID  = customer.TelegramID
+		pending = customer.Meta.PendingAddress
+		details = pending.Details
+	)
+
+	point, err := h.pickupPoints.Find(ctx, details.Provider, details.PickupPointCode)
+	if err != nil {
+		if errors.Is(err, domain.ErrPickupPointNotFound) {
+			return invalidInput(getPickupPointNotFound(ctx, details.Provider, details.PickupPointCode))
+		}
+		return fmt.Errorf("pickupPoints.Find: %w", err)
+	}
+
+	if err := details.SetPickupPoint(point); err != nil {
+		if errors.Is(err, domain.ErrPickupPointWrongCity) {
+			return invalidInput(getPickupPointWrongCity(ctx, point, details.City))
+		}
+		return err
+	}
+
+	if err := details.Validate(); err != nil {
+		return err
+	}
+
+	address := domain.NewAddress(pending.Name, *details)
+	if err := customer.AddAddress(address); err != nil && !errors.Is(err, domain.ErrTooManyAddresses) {
+		return err
+	}
+
+	if !customer.Meta.EditingProfile {
+		return h.placeOrder(ctx, chatID, *customer, *details)
+	}
+
+	editingProfile := false
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		Addresses:      &customer.Addresses,
+		PendingAddress: &domain.Address{},
+		EditingProfile: &editingProfile,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	return h.sendWithKeyboard(ctx, chatID, getProfile(ctx, *customer), h.codec.prepareProfileButtons(ctx, *customer))
+}
@@ -6,33 +6,81 @@ import (
 	"strconv"
 	"strings"
 
-	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
 )
 
-func (h *handler) AskForCalculatorOrderType(ctx context.Context, chatID int64) error {
-	if err := h.customerRepo.UpdateState(ctx, chatID, domain.StateWaitingForCalculatorOrderType); err != nil {
-		return err
+func (h *handler) calculatorFlow() flow {
+	return flow{
+		name: "calculator",
+		steps: []flowStep{
+			{
+				state:   domain.StateWaitingForCalculatorOrderType,
+				accepts: inputButton,
+				prompt:  h.askForCalculatorOrderType,
+				answer:  answerCalculatorOrderType,
+				handle:  h.handleCalculatorOrderType,
+			},
+			{
+				state:   domain.StateWaitingForCalculatorCategory,
+				accepts: inputButton,
+				prompt:  h.askForCalculatorCategory,
+				answer:  answerCalculatorCategory,
+				handle:  h.handleCalculatorCategory,
+				back:    backTo(domain.StateWaitingForCalculatorOrderType),
+			},
+			{
+				state:    domain.StateWaitingForCalculatorInput,
+				accepts:  inputText,
+				validate: validatePrice,
+				prompt:   h.askForCalculatorInput,
+				handle:   h.handleCalculatorInput,
+				back:     backTo(domain.StateWaitingForCalculatorCategory),
+			},
+		},
+		// Calculator meta is overwritten by every calculation, nothing to clean up
+	}
+}
+
+func answerCalculatorOrderType(ctx context.Context, customer domain.Customer) string {
+	if customer.CalculatorMeta.NextOrderType == nil {
+		return ""
 	}
+	return getOrderTypeText(ctx, *customer.CalculatorMeta.NextOrderType)
+}
 
-	text := "Выбери тип доставки"
-	return h.sendWithKeyboard(chatID, text, orderTypeCalculatorButtons)
+func answerCalculatorCategory(ctx context.Context, customer domain.Customer) string {
+	if customer.CalculatorMeta.Category == nil {
+		return ""
+	}
+	return getCategoryText(ctx, *customer.CalculatorMeta.Category)
+}
+
+func (h *handler) AskForCalculatorOrderType(ctx context.Context, chatID int64) error {
+	return h.startFlow(ctx, chatID, domain.StateWaitingForCalculatorOrderType)
 }
 
 func (h *handler) HandleCalculatorOrderTypeInput(ctx context.Context, chatID int64, typ domain.OrderType) error {
-	var (
-		telegramID = chatID
-		isExpress  = typ == domain.OrderTypeExpress
-	)
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForCalculatorOrderType, typ)
+}
 
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForCalculatorOrderType, chatID); err != nil {
-		return err
-	}
+// AskForCalculatorCategory lets to calculate price of another category with the same order type
+func (h *handler) AskForCalculatorCategory(ctx context.Context, chatID int64) error {
+	return h.startFlow(ctx, chatID, domain.StateWaitingForCalculatorCategory)
+}
+
+func (h *handler) HandleCalculatorCategoryInput(ctx context.Context, chatID int64, cat domain.Category) error {
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForCalculatorCategory, cat)
+}
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return err
+func (h *handler) askForCalculatorOrderType(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_order_type"), buttons: h.codec.orderTypeCalculatorButtons(ctx).InlineKeyboard}, nil
+}
+
+func (h *handler) handleCalculatorOrderType(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	typ, ok := in.value.(domain.OrderType)
+	if !ok {
+		return domain.StateDefault, ErrInvalidState
 	}
 
 	customer.UpdateCalculatorMetaOrderType(typ)
@@ -42,42 +90,24 @@ func (h *handler) HandleCalculatorOrderTypeInput(ctx context.Context, chatID int
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return err
+		return domain.StateDefault, err
 	}
 
-	var resp = "Тип доставки: "
-	switch isExpress {
-	case true:
-		resp += "Экспресс"
-		break
-	case false:
-		resp += "Обычный"
-		break
-	}
-	if err := h.sendMessage(chatID, resp); err != nil {
-		return err
+	if err := h.sendMessage(ctx, customer.TelegramID, tr(ctx, "position.order_type_selected", getOrderTypeText(ctx, typ))); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.AskForCalculatorCategory(ctx, chatID)
+	return domain.StateWaitingForCalculatorCategory, nil
 }
 
-func (h *handler) AskForCalculatorCategory(ctx context.Context, chatID int64) error {
-	if err := h.customerRepo.UpdateState(ctx, chatID, domain.StateWaitingForCalculatorCategory); err != nil {
-		return err
-	}
-	return h.sendWithKeyboard(chatID, askForCategoryTemplate, categoryCalculatorButtons)
+func (h *handler) askForCalculatorCategory(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "position.ask_category"), buttons: h.codec.categoryCalculatorButtons(ctx).InlineKeyboard}, nil
 }
 
-func (h *handler) HandleCalculatorCategoryInput(ctx context.Context, chatID int64, cat domain.Category) error {
-	var telegramID = chatID
-
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForCalculatorCategory, chatID); err != nil {
-		return err
-	}
-
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
-		return err
+func (h *handler) handleCalculatorCategory(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	cat, ok := in.value.(domain.Category)
+	if !ok {
+		return domain.StateDefault, ErrInvalidState
 	}
 
 	customer.UpdateCalculatorMetaCategory(cat)
@@ -86,54 +116,30 @@ func (h *handler) HandleCalculatorCategoryInput(ctx context.Context, chatID int6
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
 
-	if err := h.sendMessage(chatID, fmt.Sprintf("Выбрана категория: %s", string(cat))); err != nil {
-		return err
+	if err := h.sendMessage(ctx, customer.TelegramID, tr(ctx, "position.category_selected", getCategoryText(ctx, cat))); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.askForCalculatorInput(ctx, chatID)
+	return domain.StateWaitingForCalculatorInput, nil
 }
 
-func (h *handler) askForCalculatorInput(ctx context.Context, chatID int64) error {
-	var telegramID = chatID
-
-	if err := h.customerRepo.UpdateState(ctx, telegramID, domain.StateWaitingForCalculatorInput); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
-	}
-
-	return h.sendMessage(chatID, askForCalculatorInputTemplate)
+func (h *handler) askForCalculatorInput(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "calculator.ask_price")}, nil
 }
 
-func (h *handler) HandleCalculatorInput(ctx context.Context, m *tg.Message) error {
-	var (
-		chatID = m.Chat.ID
-		input  = strings.TrimSpace(m.Text)
-	)
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForCalculatorInput, chatID); err != nil {
-		return err
-	}
-
-	priceYuan, err := strconv.ParseUint(input, 10, 64)
-	if err != nil {
-		if err := h.sendMessage(chatID, "Неправильный формат ввода"); err != nil {
-			return err
-		}
-		return fmt.Errorf("strconv.ParseUint: %w", err)
-	}
-
-	customer, err := h.customerRepo.GetByTelegramID(ctx, chatID)
-	if err != nil {
-		return err
-	}
+func (h *handler) handleCalculatorInput(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	// Input is validated already
+	priceYuan, _ := strconv.ParseUint(strings.TrimSpace(in.text), 10, 64)
 
 	var (
 		ordTyp = customer.CalculatorMeta.NextOrderType
 		cat    = customer.CalculatorMeta.Category
 	)
 	if ordTyp == nil || cat == nil {
-		return fmt.Errorf("order type or category in meta is nil")
+		return domain.StateDefault, fmt.Errorf("order type or category in meta is nil")
 	}
 	// We should apply customer.Meta and customer.CalculatorMeta.Category in order to calculate correctly
 	args := domain.ConvertYuanArgs{
@@ -145,13 +151,5 @@ func (h *handler) HandleCalculatorInput(ctx context.Context, m *tg.Message) erro
 
 	priceRub := domain.ConvertYuan(args)
 
-	if err != nil {
-		return err
-	}
-
-	if err := h.customerRepo.UpdateState(ctx, chatID, domain.StateDefault); err != nil {
-		return err
-	}
-
-	return h.sendWithKeyboard(chatID, getCalculatorOutput(priceRub), calculateMoreButtons)
+	return domain.StateDefault, h.sendWithKeyboard(ctx, customer.TelegramID, getCalculatorOutput(ctx, priceRub), h.codec.calculateMoreButtons(ctx))
 }
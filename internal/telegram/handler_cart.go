@@ -3,6 +3,7 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
@@ -18,13 +19,12 @@ func (h *handler) GetCart(ctx context.Context, chatID int64) error {
 	}
 
 	if len(customer.Cart) == 0 {
-		return h.emptyCart(chatID)
+		return h.emptyCart(ctx, chatID)
 	}
-	isExpressOrder := *customer.Meta.NextOrderType == domain.OrderTypeExpress
-	msg := tg.NewMessage(chatID, h.prepareCartPreview(customer.Cart, isExpressOrder))
-	msg.ReplyMarkup = cartPreviewButtons
+	msg := tg.NewMessage(chatID, prepareCartPreview(ctx, customer.Cart))
+	msg.ReplyMarkup = h.codec.prepareCartPreviewButtons(ctx, customer.Cart)
 
-	return h.cleanSend(msg)
+	return h.cleanSend(ctx, msg)
 }
 
 func (h *handler) EditCart(ctx context.Context, chatID int64, previewCartMsgID int) error {
@@ -36,25 +36,27 @@ func (h *handler) EditCart(ctx context.Context, chatID int64, previewCartMsgID i
 	}
 
 	if len(customer.Cart) == 0 {
-		return h.emptyCart(chatID)
+		return h.emptyCart(ctx, chatID)
 	}
 
+	// Unfinished edit of other position is dropped
+	var noEdit uint
 	updateDTO := dto.UpdateCustomerDTO{
-		State: &domain.StateWaitingForCartPositionToEdit,
+		State:         &domain.StateWaitingForCartPositionToEdit,
+		EditPositionN: &noEdit,
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
 		return fmt.Errorf("customerRepo.Update: %w", err)
 	}
-	buttons := prepareEditCartButtons(len(customer.Cart), previewCartMsgID)
-	return h.sendWithKeyboard(chatID, editPositionTemplate, buttons)
+	buttons := h.codec.prepareEditCartButtons(ctx, len(customer.Cart), previewCartMsgID)
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "cart.edit"), buttons)
 }
 
-func (h *handler) RemoveCartPosition(ctx context.Context, chatID int64, callbackData int, originalMsgID, cartPreviewMsgID int) error {
+func (h *handler) RemoveCartPosition(ctx context.Context, chatID int64, positionN int, originalMsgID, cartPreviewMsgID int) error {
 	var (
-		telegramID    = chatID
-		buttonClicked = callbackData - editCartRemovePositionOffset
-		cartIndex     = buttonClicked - 1
+		telegramID = chatID
+		cartIndex  = positionN - 1
 	)
 
 	if err := h.checkRequiredState(ctx, domain.StateWaitingForCartPositionToEdit, chatID); err != nil {
@@ -66,7 +68,7 @@ func (h *handler) RemoveCartPosition(ctx context.Context, chatID int64, callback
 		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
 	}
 
-	if buttonClicked > len(customer.Cart) {
+	if positionN < 1 || positionN > len(customer.Cart) {
 		return fmt.Errorf("invalid button clicked")
 	}
 
@@ -82,60 +84,87 @@ func (h *handler) RemoveCartPosition(ctx context.Context, chatID int64, callback
 	// if customer has emptied cart
 	if len(customer.Cart) == 0 {
 		// delete edit buttons
-		if err := h.b.CleanRequest(tg.NewDeleteMessage(chatID, originalMsgID)); err != nil {
+		if err := h.bot(ctx).CleanRequest(tg.NewDeleteMessage(chatID, originalMsgID)); err != nil {
 			return fmt.Errorf("can't delete message: %w", err)
 		}
 		// update cartPreview
-		msg := tg.NewEditMessageText(chatID, cartPreviewMsgID, "Ваша корзина пуста!")
-		msg.ReplyMarkup = &addPositionButtons
-		if err := h.cleanSend(msg); err != nil {
+		msg := tg.NewEditMessageText(chatID, cartPreviewMsgID, tr(ctx, "cart.empty"))
+		buttons := h.codec.addPositionButtons(ctx)
+		msg.ReplyMarkup = &buttons
+		if err := h.cleanSend(ctx, msg); err != nil {
 			return fmt.Errorf("cant edit cart preview message: %w", err)
 		}
 		return nil
 	}
 
 	// edit original preview cart message and edit buttons
-	buttonsForNewCart := prepareEditCartButtons(len(customer.Cart), int(cartPreviewMsgID))
+	buttonsForNewCart := h.codec.prepareEditCartButtons(ctx, len(customer.Cart), int(cartPreviewMsgID))
 
-	isExpressOrder := *customer.Meta.NextOrderType == domain.OrderTypeExpress
-	textForNewCart := h.prepareCartPreview(customer.Cart, isExpressOrder)
+	textForNewCart := prepareCartPreview(ctx, customer.Cart)
 
 	updatePreviewText := tg.NewEditMessageText(chatID, int(cartPreviewMsgID), textForNewCart)
-	updatePreviewText.ReplyMarkup = &cartPreviewButtons
+	previewButtons := h.codec.prepareCartPreviewButtons(ctx, customer.Cart)
+	updatePreviewText.ReplyMarkup = &previewButtons
 	updateButtons := tg.NewEditMessageReplyMarkup(chatID, int(originalMsgID), buttonsForNewCart)
 
-	if err := h.cleanSend(updateButtons); err != nil {
+	if err := h.cleanSend(ctx, updateButtons); err != nil {
 		return err
 	}
 
-	if err := h.cleanSend(updatePreviewText); err != nil {
+	if err := h.cleanSend(ctx, updatePreviewText); err != nil {
 		return err
 	}
 
-	return h.sendMessage(chatID, fmt.Sprintf("Позиция %d успешно удалена. Корзина сверху обновлена ✅", buttonClicked))
+	return h.sendMessage(ctx, chatID, tr(ctx, "cart.position_removed", positionN))
 }
 
-func (h *handler) emptyCart(chatID int64) error {
-	return h.sendWithKeyboard(chatID, "Ваша корзина пуста!", addPositionButtons)
+// ChangePositionQuantity updates quantity of n-th position and redraws cart preview in place
+func (h *handler) ChangePositionQuantity(ctx context.Context, chatID int64, cartPreviewMsgID int, positionN string, increase bool) error {
+	var telegramID = chatID
+
+	n, err := strconv.Atoi(positionN)
+	if err != nil {
+		return fmt.Errorf("strconv.Atoi: %w", err)
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	var changed bool
+	if increase {
+		changed = customer.Cart.IncreaseQuantity(n)
+	} else {
+		changed = customer.Cart.DecreaseQuantity(n)
+	}
+	// Already at bounds
+	if !changed {
+		return nil
+	}
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		Cart: &customer.Cart,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	previewButtons := h.codec.prepareCartPreviewButtons(ctx, customer.Cart)
+	updatePreview := tg.NewEditMessageText(chatID, cartPreviewMsgID, prepareCartPreview(ctx, customer.Cart))
+	updatePreview.ReplyMarkup = &previewButtons
+	return h.cleanSend(ctx, updatePreview)
 }
 
-func (h *handler) prepareCartPreview(cart domain.Cart, isExpressOrder bool) string {
-	var out = getCartPreviewStartTemplate(len(cart), isExpressOrder)
-	var totalRub uint64
-	var totalYuan uint64
+func (h *handler) emptyCart(ctx context.Context, chatID int64) error {
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "cart.empty"), h.codec.addPositionButtons(ctx))
+}
+
+func prepareCartPreview(ctx context.Context, cart domain.Cart) string {
+	var out = getCartPreviewStartTemplate(ctx, len(cart))
 	for n, cartItem := range cart {
-		positionText := getPositionTemplate(cartPositionPreviewArgs{
-			n:         n + 1,
-			link:      cartItem.ShopLink,
-			size:      cartItem.Size,
-			category:  string(cartItem.Category),
-			priceRub:  cartItem.PriceRUB,
-			priceYuan: cartItem.PriceYUAN,
-		})
-		totalRub += cartItem.PriceRUB
-		totalYuan += cartItem.PriceYUAN
-		out += positionText
-	}
-	out += getCartPreviewEndTemplate(totalRub, totalYuan)
+		out += getPositionTemplate(ctx, newCartPositionPreviewArgs(n+1, cartItem))
+	}
+	totalRub, totalYuan := cart.Totals()
+	out += getCartPreviewEndTemplate(ctx, totalRub, totalYuan)
 	return out
 }
@@ -11,38 +11,35 @@ import (
 )
 
 func (h *handler) Catalog(ctx context.Context, chatID int64) error {
-	var (
-		telegramID = chatID
-		first      bool
-	)
+	var telegramID = chatID
 
 	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		return err
 	}
 
-	if err := h.sendMessage(chatID, getCatalog(*customer.Username)); err != nil {
+	if err := h.sendMessage(ctx, chatID, getCatalog(ctx, *customer.Username)); err != nil {
 		return err
 	}
 
 	// Load appropriate item
 	item := h.catalogProvider.LoadAt(customer.CatalogOffset)
-
-	thumbnails := functools.Map(func(url string, i int) interface{} {
-		thumbnail := tg.NewInputMediaPhoto(tg.FileURL(url))
-		if !first {
-			// add caption to first element
-			thumbnail.Caption = item.GetCaption()
-			first = true
+	if item.ItemID.IsZero() {
+		// Offset is outdated (e.g. item got unpublished), start over
+		item = h.catalogProvider.LoadFirst()
+		if item.ItemID.IsZero() {
+			return h.sendMessage(ctx, chatID, tr(ctx, "catalog.empty"))
 		}
-		thumbnail.ParseMode = parseModeHTML
-		return thumbnail
-	}, item.ImageURLs)
-
-	group := tg.NewMediaGroup(chatID, thumbnails)
+		customer.NullifyCatalogOffset()
+		if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+			CatalogOffset: &customer.CatalogOffset,
+		}); err != nil {
+			return err
+		}
+	}
 
 	// Sends thumnails with caption
-	sentMsgs, err := h.b.SendMediaGroup(group)
+	sentMsgs, err := h.sendCatalogItem(ctx, chatID, item)
 	if err != nil {
 		return err
 	}
@@ -62,6 +59,7 @@ func (h *handler) Catalog(ctx context.Context, chatID int64) error {
 		hasNext: hasNext,
 		hasPrev: hasPrev,
 		msgIDs:  msgIDs,
+		itemID:  item.ItemID.Hex(),
 	}
 
 	if hasNext {
@@ -74,12 +72,8 @@ func (h *handler) Catalog(ctx context.Context, chatID int64) error {
 		btnArgs.prevTitle = prev.Title
 	}
 
-	if !hasPrev && !hasNext {
-		return nil
-	}
-
-	buttons := prepareCatalogButtons(btnArgs)
-	return h.sendWithKeyboard(chatID, "Кнопки для пролистывания каталога", buttons)
+	buttons := h.codec.prepareCatalogButtons(ctx, btnArgs)
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "catalog.controls"), buttons)
 }
 
 // No need to call h.catalogProvider.HasNext. See h.Catalog impl
@@ -116,34 +110,9 @@ func (h *handler) updateCatalog(ctx context.Context, chatID int64, thumbnailMsgI
 	if reflect.DeepEqual(domain.CatalogItem{}, item) {
 		return nil
 	}
-	// Get next item images
-	var first bool
-	thumbnails := functools.Map(func(url string, i int) interface{} {
-		thumbnail := tg.NewInputMediaPhoto(tg.FileURL(url))
-		if !first {
-			// add caption to first element
-			thumbnail.Caption = item.GetCaption()
-			first = true
-		}
-		thumbnail.ParseMode = parseModeHTML
-		return thumbnail
-	}, item.ImageURLs)
-
-	var sentMsgIDs []int
-	// Draw it by updating
-	for i, thumbMsgID := range thumbnailMsgIDs {
-		editOneMedia := &tg.EditMessageMediaConfig{
-			BaseEdit: tg.BaseEdit{
-				ChatID:    chatID,
-				MessageID: int(thumbMsgID),
-			},
-			Media: thumbnails[i],
-		}
-		sentMessage, err := h.b.Send(editOneMedia)
-		if err != nil {
-			return err
-		}
-		sentMsgIDs = append(sentMsgIDs, sentMessage.MessageID)
+	// Draw it by updating thumbnails in place, message ids stay the same
+	if err := h.editPhotoGroup(ctx, chatID, thumbnailMsgIDs, catalogPhotoGroup(ctx, item)); err != nil {
+		return err
 	}
 
 	updateDTO := dto.UpdateCustomerDTO{
@@ -163,7 +132,8 @@ func (h *handler) updateCatalog(ctx context.Context, chatID int64, thumbnailMsgI
 	btnArgs := catalogButtonsArgs{
 		hasNext: hasNext,
 		hasPrev: hasPrev,
-		msgIDs:  sentMsgIDs,
+		msgIDs:  thumbnailMsgIDs,
+		itemID:  item.ItemID.Hex(),
 	}
 	if hasNext {
 		next := h.catalogProvider.LoadNext(customer.CatalogOffset)
@@ -175,8 +145,23 @@ func (h *handler) updateCatalog(ctx context.Context, chatID int64, thumbnailMsgI
 		btnArgs.prevTitle = prev.Title
 	}
 
-	buttons := prepareCatalogButtons(btnArgs)
+	buttons := h.codec.prepareCatalogButtons(ctx, btnArgs)
 	editButtons := tg.NewEditMessageReplyMarkup(chatID, int(controlButtonsMsgID), buttons)
 
-	return h.cleanSend(editButtons)
+	return h.cleanSend(ctx, editButtons)
+}
+
+// sendCatalogItem sends item images with caption
+func (h *handler) sendCatalogItem(ctx context.Context, chatID int64, item domain.CatalogItem) ([]tg.Message, error) {
+	return h.sendPhotoGroup(ctx, chatID, catalogPhotoGroup(ctx, item))
+}
+
+// catalogPhotoGroup is item images with caption on the first one. File ids are cached by url,
+// so changed images of item are sent by their new urls
+func catalogPhotoGroup(ctx context.Context, item domain.CatalogItem) photoGroup {
+	return photoGroup{
+		caption:   getCatalogItemCaption(ctx, item),
+		parseMode: parseModeHTML,
+		urls:      item.ImageURLs,
+	}
 }
@@ -0,0 +1,29 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogPhotoGroup(t *testing.T) {
+	item := domain.CatalogItem{
+		Title:     "Nike Air Force 1",
+		ImageURLs: []string{"https://example.com/1.jpg", "https://example.com/2.jpg"},
+	}
+	g := catalogPhotoGroup(context.Background(), item)
+	require.Equal(t, item.ImageURLs, g.urls)
+
+	thumbnails := makeThumbnails(g.caption, g.parseMode, urlFiles(g.urls)...)
+	require.Len(t, thumbnails, 2)
+	for i, th := range thumbnails {
+		photo := th.(tg.InputMediaPhoto)
+		require.Equal(t, tg.FileURL(item.ImageURLs[i]), photo.Media)
+	}
+	require.Contains(t, thumbnails[0].(tg.InputMediaPhoto).Caption, item.Title)
+	require.Equal(t, parseModeHTML, thumbnails[0].(tg.InputMediaPhoto).ParseMode)
+	require.Empty(t, thumbnails[1].(tg.InputMediaPhoto).Caption)
+}
@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func (h *handler) ToggleDropsSubscription(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	customer.ToggleDropsSubscription()
+	updateDTO := dto.UpdateCustomerDTO{
+		SubscribedToDrops: &customer.SubscribedToDrops,
+	}
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	if customer.SubscribedToDrops {
+		return h.sendMessage(ctx, chatID, tr(ctx, "drops.subscribed"))
+	}
+	return h.sendMessage(ctx, chatID, tr(ctx, "drops.unsubscribed"))
+}
+
+// NotifyDrop announces just published drops to subscribed customers.
+// Matches catalog.OnDropFunc
+func (h *handler) NotifyDrop(ctx context.Context, drops []domain.CatalogItem) {
+	customers, err := h.customerRepo.GetDropSubscribers(ctx)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNoCustomers) {
+			logger.Get().Error("can't get drop subscribers", zap.Error(err))
+		}
+		return
+	}
+
+	for _, c := range customers {
+		ctx := withLanguage(ctx, customerLanguage(c, ""))
+		msg := tg.NewMessage(c.TelegramID, getDropNotification(ctx, drops))
+		msg.ReplyMarkup = h.codec.dropNotificationButtons(ctx)
+		if _, err := botWithContext(ctx, h.bulk).Send(msg); err != nil {
+			logger.Get().Error("can't notify about drop",
+				zap.Int64("telegramId", c.TelegramID),
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+func (h *handler) SelectPositionToEdit(ctx context.Context, chatID int64, positionN string) error {
+	var telegramID = chatID
+
+	if err := h.checkRequiredState(ctx, domain.StateWaitingForCartPositionToEdit, chatID); err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(positionN)
+	if err != nil {
+		return fmt.Errorf("strconv.Atoi: %w", err)
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if n <= 0 || n > len(customer.Cart) {
+		return staleButtonError("error.position_not_found", domain.ErrInvalidPositionN)
+	}
+
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "position.ask_edit_field", n), h.codec.preparePositionEditButtons(ctx, n))
+}
+
+// StartPositionEdit switches customer to state of the field being edited.
+// Input handlers of those states write the result back to the cart, see h.finishPositionEdit
+func (h *handler) StartPositionEdit(ctx context.Context, chatID int64, positionN string, field domain.State) error {
+	var telegramID = chatID
+
+	if err := h.checkRequiredState(ctx, domain.StateWaitingForCartPositionToEdit, chatID); err != nil {
+		return err
+	}
+
+	switch field {
+	case domain.StateWaitingForSize, domain.StateWaitingForButton, domain.StateWaitingForPrice, domain.StateWaitingForLink:
+	default:
+		return ErrInvalidState
+	}
+
+	n, err := strconv.ParseUint(positionN, 10, 64)
+	if err != nil {
+		return fmt.Errorf("strconv.ParseUint: %w", err)
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if err := customer.StartPositionEdit(uint(n)); err != nil {
+		return err
+	}
+
+	updateDTO := dto.UpdateCustomerDTO{
+		LastPosition:  customer.LastEditPosition,
+		EditPositionN: &customer.Meta.EditPositionN,
+	}
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	return h.flows.enter(ctx, customer, field)
+}
+
+// finishPositionEdit saves edited position in place of the original one and shows updated cart
+func (h *handler) finishPositionEdit(ctx context.Context, chatID int64, customer domain.Customer) error {
+	n, err := customer.FinishPositionEdit()
+	if err != nil {
+		return err
+	}
+
+	updateDTO := dto.UpdateCustomerDTO{
+		LastPosition:  customer.LastEditPosition,
+		Cart:          &customer.Cart,
+		EditPositionN: &customer.Meta.EditPositionN,
+	}
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	if err := h.sendWithKeyboard(ctx, chatID, tr(ctx, "position.updated", n), bottomMenuButtons(ctx)); err != nil {
+		return err
+	}
+	return h.GetCart(ctx, chatID)
+}
+
+// stopPositionEdit drops unfinished edit so new position is not written over existing one
+func (h *handler) stopPositionEdit(ctx context.Context, customer domain.Customer) error {
+	if !customer.IsEditingPosition() {
+		return nil
+	}
+	var none uint
+	return h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		EditPositionN: &none,
+	})
+}
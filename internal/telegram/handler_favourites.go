@@ -0,0 +1,164 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const notifyFavouritesTimeout = time.Minute
+
+func (h *handler) Favourites(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	text, buttons, ok := h.prepareFavourites(ctx, customer)
+	if !ok {
+		return h.sendMessage(ctx, chatID, text)
+	}
+	return h.sendWithKeyboard(ctx, chatID, text, buttons)
+}
+
+func (h *handler) AddToFavourites(ctx context.Context, chatID int64, itemID string) error {
+	var telegramID = chatID
+
+	id, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return fmt.Errorf("primitive.ObjectIDFromHex: %w", err)
+	}
+
+	item, ok := h.catalogProvider.FindByID(id)
+	if !ok {
+		return h.sendMessage(ctx, chatID, tr(ctx, "favourites.item_not_found"))
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if !customer.AddFavourite(item) {
+		return h.sendMessage(ctx, chatID, tr(ctx, "favourites.exists"))
+	}
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		Favourites: &customer.Favourites,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	return h.sendMessage(ctx, chatID, tr(ctx, "favourites.added"))
+}
+
+func (h *handler) RemoveFromFavourites(ctx context.Context, chatID int64, favouritesMsgID int, itemID string) error {
+	var telegramID = chatID
+
+	id, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return fmt.Errorf("primitive.ObjectIDFromHex: %w", err)
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if customer.RemoveFavourite(id) {
+		if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+			Favourites: &customer.Favourites,
+		}); err != nil {
+			return fmt.Errorf("customerRepo.Update: %w", err)
+		}
+	}
+
+	// Redraw favourites message
+	text, buttons, ok := h.prepareFavourites(ctx, customer)
+	if !ok {
+		return h.cleanSend(ctx, tg.NewEditMessageText(chatID, favouritesMsgID, text))
+	}
+	return h.cleanSend(ctx, tg.NewEditMessageTextAndMarkup(chatID, favouritesMsgID, text, buttons))
+}
+
+// prepareFavourites returns false if there's nothing to show
+func (h *handler) prepareFavourites(ctx context.Context, customer domain.Customer) (string, tg.InlineKeyboardMarkup, bool) {
+	var (
+		items       []domain.CatalogItem
+		unavailable int
+	)
+	for _, f := range customer.Favourites {
+		item, ok := h.catalogProvider.FindByID(f.ItemID)
+		if !ok {
+			unavailable++
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return tr(ctx, "favourites.empty"), tg.InlineKeyboardMarkup{}, false
+	}
+	return getFavourites(ctx, items, unavailable), h.codec.prepareFavouritesButtons(ctx, items), true
+}
+
+// NotifyFavourites tells customers about price drops and restocks of saved items.
+// Matches catalog.OnPriceChangeFunc, calls are not concurrent
+func (h *handler) NotifyFavourites(ctx context.Context, items []domain.CatalogItem) {
+	ctx, cancel := context.WithTimeout(ctx, notifyFavouritesTimeout)
+	defer cancel()
+
+	customers, err := h.customerRepo.GetWithFavourites(ctx)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNoCustomers) {
+			logger.Get().Error("can't get customers with favourites", zap.Error(err))
+		}
+		return
+	}
+
+	byID := make(map[primitive.ObjectID]domain.CatalogItem, len(items))
+	for _, item := range items {
+		byID[item.ItemID] = item
+	}
+
+	for _, c := range customers {
+		ctx := withLanguage(ctx, customerLanguage(c, ""))
+		for _, f := range c.Favourites {
+			item, ok := byID[f.ItemID]
+			if !ok {
+				continue
+			}
+			seen := domain.NewFavouriteItem(item)
+			if seen == f {
+				continue
+			}
+			// Remember seen price and stock so customer is notified once
+			if err := h.customerRepo.SetFavouriteSeen(ctx, c.CustomerID, seen); err != nil {
+				logger.Get().Error("can't update favourite",
+					zap.Int64("telegramId", c.TelegramID),
+					zap.Error(err))
+				continue
+			}
+			change, ok := f.DetectChange(item)
+			if !ok {
+				continue
+			}
+			msg := tg.NewMessage(c.TelegramID, getFavouriteChange(ctx, change))
+			msg.ReplyMarkup = h.codec.dropNotificationButtons(ctx)
+			if _, err := botWithContext(ctx, h.bulk).Send(msg); err != nil {
+				logger.Get().Error("can't notify about favourite",
+					zap.Int64("telegramId", c.TelegramID),
+					zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+)
+
+// FlowBack returns customer to previous step of the flow, going back from the first step cancels the flow
+func (h *handler) FlowBack(ctx context.Context, chatID int64) error {
+	left, err := h.flows.back(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if !left {
+		return nil
+	}
+	return h.afterFlowCancel(ctx, chatID)
+}
+
+// FlowCancel drops everything filled in at the flow and returns customer to menu
+func (h *handler) FlowCancel(ctx context.Context, chatID int64) error {
+	if err := h.flows.cancel(ctx, chatID); err != nil {
+		return err
+	}
+	return h.afterFlowCancel(ctx, chatID)
+}
+
+func (h *handler) afterFlowCancel(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	// Reply keyboard of the step (e.g. contact request) is replaced with the usual one
+	keyboard := initialMenuKeyboard(ctx)
+	if len(customer.Cart) > 0 {
+		keyboard = bottomMenuButtons(ctx)
+	}
+	if err := h.sendWithKeyboard(ctx, chatID, tr(ctx, "flow.cancelled"), keyboard); err != nil {
+		return err
+	}
+	return h.Menu(ctx, chatID)
+}
@@ -14,10 +14,10 @@ func (h *handler) StartMakeOrderGuide(ctx context.Context, m *tg.Message) error
 		telegramID = chatID
 	)
 
-	thumbnails := makeThumbnails(getTemplate().GuideStep1, guideStep1Thumbnail1, guideStep1Thumbnail2)
-	group := tg.NewMediaGroup(chatID, thumbnails)
-
-	sentMsgs, err := h.b.SendMediaGroup(group)
+	sentMsgs, err := h.sendPhotoGroup(ctx, chatID, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep1, nil),
+		urls:    []string{guideStep1Thumbnail1, guideStep1Thumbnail2},
+	})
 	if err != nil {
 		return err
 	}
@@ -26,8 +26,8 @@ func (h *handler) StartMakeOrderGuide(ctx context.Context, m *tg.Message) error
 		return m.MessageID
 	}, sentMsgs)
 
-	buttons := prepareOrderGuideButtons(orderGuideStep0Callback, msgIDs...)
-	if err := h.sendWithKeyboard(chatID, "Кнопки для пролистывания инструкции", buttons); err != nil {
+	buttons := h.codec.prepareOrderGuideButtons(ctx, firstOrderGuideStep, msgIDs...)
+	if err := h.sendWithKeyboard(ctx, chatID, tr(ctx, "guide.controls"), buttons); err != nil {
 		return err
 	}
 
@@ -38,62 +38,60 @@ func (h *handler) StartMakeOrderGuide(ctx context.Context, m *tg.Message) error
 
 	// If cart is not empty then skip order type ask
 	if len(customer.Cart) > 0 {
-		return h.askForCategory(ctx, chatID)
-	}
-
-	if err := h.customerRepo.UpdateState(ctx, telegramID, domain.StateWaitingForOrderType); err != nil {
-		return err
+		return h.flows.enter(ctx, customer, domain.StateWaitingForCategory)
 	}
 
-	return h.askForOrderType(ctx, chatID)
+	return h.flows.enter(ctx, customer, domain.StateWaitingForOrderType)
 }
 
 func (h *handler) MakeOrderGuideStep1(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error {
-	thumbnails := makeThumbnails(getTemplate().GuideStep1, guideStep1Thumbnail1, guideStep1Thumbnail2)
-	return h.updateGuideStep(chatID, guideMsgIDs, controlButtonsMessageID, orderGuideStep0Callback, thumbnails)
+	return h.updateGuideStep(ctx, chatID, guideMsgIDs, controlButtonsMessageID, 0, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep1, nil),
+		urls:    []string{guideStep1Thumbnail1, guideStep1Thumbnail2},
+	})
 }
 
 func (h *handler) MakeOrderGuideStep2(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error {
-	thumbnails := makeThumbnails(getTemplate().GuideStep2, guideStep2Thumbnail1, guideStep2Thumbnail2)
-	return h.updateGuideStep(chatID, guideMsgIDs, controlButtonsMessageID, orderGuideStep1Callback, thumbnails)
+	return h.updateGuideStep(ctx, chatID, guideMsgIDs, controlButtonsMessageID, 1, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep2, nil),
+		urls:    []string{guideStep2Thumbnail1, guideStep2Thumbnail2},
+	})
 }
 
 func (h *handler) MakeOrderGuideStep3(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error {
-	thumbnails := makeThumbnails(getTemplate().GuideStep3, guideStep3Thumbnail1, guideStep3Thumbnail2)
-	return h.updateGuideStep(chatID, guideMsgIDs, controlButtonsMessageID, orderGuideStep2Callback, thumbnails)
+	return h.updateGuideStep(ctx, chatID, guideMsgIDs, controlButtonsMessageID, 2, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep3, nil),
+		urls:    []string{guideStep3Thumbnail1, guideStep3Thumbnail2},
+	})
 }
 
 func (h *handler) MakeOrderGuideStep4(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error {
-	thumbnails := makeThumbnails(getTemplate().GuideStep4, guideStep4Thumbnail1, guideStep4Thumbnail2)
-	return h.updateGuideStep(chatID, guideMsgIDs, controlButtonsMessageID, orderGuideStep3Callback, thumbnails)
+	return h.updateGuideStep(ctx, chatID, guideMsgIDs, controlButtonsMessageID, 3, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep4, nil),
+		urls:    []string{guideStep4Thumbnail1, guideStep4Thumbnail2},
+	})
 }
 
 func (h *handler) MakeOrderGuideStep5(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error {
-	thumbnails := makeThumbnails(getTemplate().GuideStep5, guideStep5Thumbnail1, guideStep5Thumbnail2)
-	return h.updateGuideStep(chatID, guideMsgIDs, controlButtonsMessageID, orderGuideStep4Callback, thumbnails)
+	return h.updateGuideStep(ctx, chatID, guideMsgIDs, controlButtonsMessageID, 4, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep5, nil),
+		urls:    []string{guideStep5Thumbnail1, guideStep5Thumbnail2},
+	})
 }
 
 func (h *handler) MakeOrderGuideStep6(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error {
-	thumbnails := makeThumbnails(getTemplate().GuideStep6, guideStep6Thumbnail1, guideStep6Thumbnail2)
-	return h.updateGuideStep(chatID, guideMsgIDs, controlButtonsMessageID, orderGuideStep5Callback, thumbnails)
+	return h.updateGuideStep(ctx, chatID, guideMsgIDs, controlButtonsMessageID, 5, photoGroup{
+		caption: execute(getTemplate(ctx).GuideStep6, nil),
+		urls:    []string{guideStep6Thumbnail1, guideStep6Thumbnail2},
+	})
 }
 
-func (h *handler) updateGuideStep(chatID int64, guideMsgIDs []int, controlButtonsMessageID int, nextCallback int, thumbnails []interface{}) error {
-	for i, t := range thumbnails {
-		editOneMedia := &tg.EditMessageMediaConfig{
-			BaseEdit: tg.BaseEdit{
-				ChatID:    chatID,
-				MessageID: guideMsgIDs[i],
-			},
-			Media: t,
-		}
-		if err := h.cleanSend(editOneMedia); err != nil {
-			return err
-		}
+func (h *handler) updateGuideStep(ctx context.Context, chatID int64, guideMsgIDs []int, controlButtonsMessageID int, step int, g photoGroup) error {
+	if err := h.editPhotoGroup(ctx, chatID, guideMsgIDs, g); err != nil {
+		return err
 	}
 
 	// update control buttons
-	buttons := tg.NewEditMessageReplyMarkup(chatID, controlButtonsMessageID, prepareOrderGuideButtons(nextCallback, guideMsgIDs...))
-	return h.cleanSend(buttons)
-
+	buttons := tg.NewEditMessageReplyMarkup(chatID, controlButtonsMessageID, h.codec.prepareOrderGuideButtons(ctx, step, guideMsgIDs...))
+	return h.cleanSend(ctx, buttons)
 }
@@ -0,0 +1,44 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+func (h *handler) Language(ctx context.Context, chatID int64) error {
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "language.select"), h.codec.prepareLanguageButtons(ctx))
+}
+
+// SetLanguage saves language customer picked, texts of the rest of the update are sent in it already
+func (h *handler) SetLanguage(ctx context.Context, chatID int64, lang string) error {
+	var telegramID = chatID
+
+	// Locale might be gone since buttons were sent
+	if !bundle().Has(lang) {
+		return ErrInvalidCallback
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		Language: &lang,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+	ctx = withLanguage(ctx, lang)
+
+	// Reply keyboard keeps texts of the old language until it's sent again
+	keyboard := initialMenuKeyboard(ctx)
+	if len(customer.Cart) > 0 {
+		keyboard = bottomMenuButtons(ctx)
+	}
+	if err := h.sendWithKeyboard(ctx, chatID, tr(ctx, "language.changed"), keyboard); err != nil {
+		return err
+	}
+	return h.Menu(ctx, chatID)
+}
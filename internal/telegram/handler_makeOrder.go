@@ -3,6 +3,7 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -10,177 +11,428 @@ import (
 	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
 )
 
+// detailsFlow collects customer details and delivery address for order.
+// Profile reuses its steps, customer.Meta.EditingProfile tells where to return after the step
+func (h *handler) detailsFlow() flow {
+	steps := []flowStep{
+		{
+			state:    domain.StateWaitingForFIO,
+			accepts:  inputText,
+			validate: validateFullName,
+			prompt:   h.askForFIO,
+			answer:   answerString(func(c domain.Customer) *string { return c.FullName }),
+			handle:   h.handleFIO,
+		},
+		{
+			state:    domain.StateWaitingForPhoneNumber,
+			accepts:  inputText | inputContact,
+			validate: validatePhoneNumber,
+			prompt:   h.askForPhoneNumber,
+			answer:   answerString(func(c domain.Customer) *string { return c.PhoneNumber }),
+			handle:   h.handlePhoneNumber,
+			back: func(customer domain.Customer) domain.State {
+				if customer.Meta.EditingProfile {
+					return domain.StateDefault
+				}
+				return domain.StateWaitingForFIO
+			},
+		},
+		{
+			state:   domain.StateWaitingForDeliveryAddress,
+			accepts: inputButton,
+			prompt:  h.askForDeliveryAddress,
+			handle:  h.handleDeliveryAddress,
+			back:    backTo(domain.StateWaitingForPhoneNumber),
+		},
+		{
+			state:    domain.StateWaitingForProfileAddress,
+			accepts:  inputText,
+			validate: validateAddressName,
+			prompt:   h.askForAddressName,
+			answer:   answerPendingAddressName,
+			handle:   h.handleAddressName,
+		},
+	}
+	return flow{
+		name:   "details",
+		steps:  append(steps, h.addressSteps()...),
+		cancel: h.cancelDetails,
+	}
+}
+
+// Values of buttons on delivery address step
+type (
+	// 1-based number of address in customer's address book
+	savedAddressN   int
+	newAddressInput struct{}
+)
+
+// answerString shows optional customer field
+func answerString(field func(c domain.Customer) *string) func(ctx context.Context, customer domain.Customer) string {
+	return func(ctx context.Context, customer domain.Customer) string {
+		if v := field(customer); v != nil {
+			return *v
+		}
+		return ""
+	}
+}
+
 func (h *handler) AskForFIO(ctx context.Context, chatID int64) error {
 	var telegramID = chatID
 
-	if err := h.customerRepo.UpdateState(ctx, telegramID, domain.StateWaitingForFIO); err != nil {
-		return err
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	// Nothing to order
+	if len(customer.Cart) == 0 {
+		return h.emptyCart(ctx, chatID)
 	}
-	return h.sendMessage(chatID, askForFIOTemplate)
+
+	// Offer details saved from previous order
+	if customer.HasSavedDetails() {
+		return h.sendWithKeyboard(ctx, chatID, getSavedDetails(ctx, *customer.FullName, *customer.PhoneNumber), h.codec.savedDetailsButtons(ctx))
+	}
+
+	return h.EnterNewDetails(ctx, chatID)
 }
 
-func (h *handler) HandleFIOInput(ctx context.Context, m *tg.Message) error {
-	var (
-		chatID     = m.From.ID
-		telegramID = chatID
-		fullName   = strings.TrimSpace(m.Text)
-	)
+func (h *handler) EnterNewDetails(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
 
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForFIO, chatID); err != nil {
-		return err
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if len(customer.Cart) == 0 {
+		return h.emptyCart(ctx, chatID)
+	}
+
+	editingProfile := false
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		EditingProfile: &editingProfile,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
 	}
+	customer.Meta.EditingProfile = editingProfile
+
+	return h.flows.enter(ctx, customer, domain.StateWaitingForFIO)
+}
+
+func (h *handler) UseSavedDetails(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
 
 	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
 	}
 
-	if !domain.IsValidFullName(fullName) {
-		return h.sendMessage(chatID, invalidFIOInputTemplate)
+	if len(customer.Cart) == 0 {
+		return h.emptyCart(ctx, chatID)
 	}
 
-	updateDTO := dto.UpdateCustomerDTO{
-		State:    &domain.StateWaitingForPhoneNumber,
-		FullName: &fullName,
+	if !customer.HasSavedDetails() {
+		return h.EnterNewDetails(ctx, chatID)
 	}
 
-	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+	editingProfile := false
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		EditingProfile: &editingProfile,
+	}); err != nil {
 		return fmt.Errorf("customerRepo.Update: %w", err)
 	}
+	customer.Meta.EditingProfile = editingProfile
 
-	if err := h.sendMessage(chatID, fmt.Sprintf("Спасибо, %s. ", fullName)); err != nil {
+	next, err := h.deliveryAddressStep(ctx, &customer)
+	if err != nil {
 		return err
 	}
+	return h.flows.enter(ctx, customer, next)
+}
+
+// HandleFlowInput passes text or shared contact to the step of flow customer is at
+func (h *handler) HandleFlowInput(ctx context.Context, m *tg.Message) error {
+	return h.flows.handleMessage(ctx, m)
+}
+
+func validateFullName(ctx context.Context, in flowInput) error {
+	if !domain.IsValidFullName(strings.TrimSpace(in.text)) {
+		return invalidInput(tr(ctx, "flow.invalid_fio"))
+	}
+	return nil
+}
 
-	return h.sendMessage(chatID, askForPhoneNumberTemplate)
+func (h *handler) askForFIO(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "flow.ask_fio")}, nil
 }
 
-func (h *handler) HandlePhoneNumberInput(ctx context.Context, m *tg.Message) error {
+func (h *handler) handleFIO(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
 	var (
-		chatID      = m.From.ID
-		telegramID  = chatID
-		phoneNumber = strings.TrimSpace(m.Text)
+		chatID   = customer.TelegramID
+		fullName = strings.TrimSpace(in.text)
 	)
 
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForPhoneNumber, chatID); err != nil {
-		return err
+	if customer.Meta.EditingProfile {
+		return domain.StateDefault, h.finishProfileEdit(ctx, chatID, *customer, dto.UpdateCustomerDTO{
+			FullName: &fullName,
+		})
+	}
+
+	updateDTO := dto.UpdateCustomerDTO{
+		FullName: &fullName,
 	}
 
-	if !domain.IsValidPhoneNumber(phoneNumber) {
-		return h.sendMessage(chatID, "Неправильный формат номера телефона.\n"+askForPhoneNumberTemplate)
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
+	customer.FullName = &fullName
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "flow.fio_accepted", fullName)); err != nil {
+		return domain.StateDefault, err
+	}
+
+	return domain.StateWaitingForPhoneNumber, nil
+}
+
+// rawPhoneNumber is either typed in or shared with contact request button
+func rawPhoneNumber(in flowInput) string {
+	if in.kind == inputContact {
+		return in.contact.PhoneNumber
+	}
+	return in.text
+}
+
+func validatePhoneNumber(ctx context.Context, in flowInput) error {
+	// Contact of someone else might be forwarded
+	if in.kind == inputContact && in.contact.UserID != in.fromID {
+		return invalidInput(tr(ctx, "flow.foreign_contact"))
+	}
+	if _, err := domain.NormalizePhoneNumber(rawPhoneNumber(in)); err != nil {
+		return invalidInput(tr(ctx, "flow.invalid_phone_number"))
+	}
+	return nil
+}
+
+func (h *handler) askForPhoneNumber(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	keyboard := shareContactKeyboard(ctx)
+	return flowPrompt{text: tr(ctx, "flow.ask_phone_number"), keyboard: &keyboard}, nil
+}
+
+func (h *handler) handlePhoneNumber(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	var chatID = customer.TelegramID
+
+	phoneNumber, err := domain.NormalizePhoneNumber(rawPhoneNumber(in))
 	if err != nil {
-		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+		return domain.StateDefault, err
+	}
+
+	if customer.Meta.EditingProfile {
+		return domain.StateDefault, h.finishProfileEdit(ctx, chatID, *customer, dto.UpdateCustomerDTO{
+			PhoneNumber: &phoneNumber,
+		})
 	}
 
 	updateDTO := dto.UpdateCustomerDTO{
-		State:       &domain.StateWaitingForDeliveryAddress,
 		PhoneNumber: &phoneNumber,
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
 	}
+	customer.PhoneNumber = &phoneNumber
 
-	if err := h.sendMessage(chatID, fmt.Sprintf("Спасибо, номер [%s] принят!", phoneNumber)); err != nil {
-		return err
+	// Bring menu keyboard back instead of contact request
+	if err := h.sendWithKeyboard(ctx, chatID, tr(ctx, "flow.phone_number_accepted", phoneNumber), initialMenuKeyboard(ctx)); err != nil {
+		return domain.StateDefault, err
 	}
 
-	return h.sendMessage(chatID, askForDeliveryAddressTemplate)
+	return h.deliveryAddressStep(ctx, customer)
 }
 
-func (h *handler) HandleDeliveryAddressInput(ctx context.Context, m *tg.Message) error {
-	var (
-		chatID     = m.From.ID
-		telegramID = chatID
-		address    = strings.TrimSpace(m.Text)
-	)
+// deliveryAddressStep offers addresses from customer's address book if any, otherwise starts address input
+func (h *handler) deliveryAddressStep(ctx context.Context, customer *domain.Customer) (domain.State, error) {
+	if hasDeliveryAddresses(customer.Addresses) {
+		return domain.StateWaitingForDeliveryAddress, nil
+	}
+	return h.beginAddressInput(ctx, customer, customer.DefaultAddressName(tr(ctx, "address.default_name")))
+}
 
-	// validate state
-	if err := h.checkRequiredState(ctx, domain.StateWaitingForDeliveryAddress, chatID); err != nil {
-		return err
+func (h *handler) askForDeliveryAddress(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	buttons, ok := h.codec.prepareSavedAddressesButtons(ctx, customer.Addresses)
+	if !ok {
+		return flowPrompt{}, ErrInvalidState
 	}
+	return flowPrompt{text: tr(ctx, "address.ask_saved"), buttons: buttons.InlineKeyboard}, nil
+}
 
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+func (h *handler) UseSavedAddress(ctx context.Context, chatID int64, addressN string) error {
+	n, err := strconv.Atoi(addressN)
 	if err != nil {
-		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+		return fmt.Errorf("strconv.Atoi: %w", err)
 	}
+	return h.flows.handleButton(ctx, chatID, domain.StateWaitingForDeliveryAddress, savedAddressN(n))
+}
 
-	updateDTO := dto.UpdateCustomerDTO{
-		LastPosition: &domain.Position{},
-		Cart:         &domain.Cart{},
-		State:        &domain.StateDefault,
+func (h *handler) handleDeliveryAddress(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	switch v := in.value.(type) {
+	case newAddressInput:
+		return h.beginAddressInput(ctx, customer, customer.DefaultAddressName(tr(ctx, "address.default_name")))
+	case savedAddressN:
+		address, err := customer.GetAddress(int(v))
+		if err != nil {
+			return domain.StateDefault, err
+		}
+
+		if !address.IsStructured() {
+			return domain.StateDefault, domain.ErrInvalidAddress
+		}
+		if err := address.Details.Validate(); err != nil {
+			return domain.StateDefault, err
+		}
+
+		return domain.StateDefault, h.placeOrder(ctx, customer.TelegramID, *customer, *address.Details)
+	default:
+		return domain.StateDefault, ErrInvalidState
 	}
+}
 
-	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
-		return fmt.Errorf("customerRepo.Update: %w", err)
+// cancelDetails drops unfinished address, details typed in are kept for the next order
+func (h *handler) cancelDetails(ctx context.Context, customer domain.Customer) error {
+	editingProfile := false
+	return h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		PendingAddress: &domain.Address{},
+		EditingProfile: &editingProfile,
+	})
+}
+
+func (h *handler) placeOrder(ctx context.Context, chatID int64, customer domain.Customer, address domain.StructuredAddress) error {
+	// Cart might have been emptied in other chat meanwhile, address book is kept anyway
+	if len(customer.Cart) == 0 {
+		if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+			Addresses:      &customer.Addresses,
+			PendingAddress: &domain.Address{},
+		}); err != nil {
+			return fmt.Errorf("customerRepo.Update: %w", err)
+		}
+		return h.emptyCart(ctx, chatID)
 	}
 
-	shortID, err := h.orderRepo.GetFreeShortID(ctx)
+	fallback := domain.OrderTypeNormal
+	if customer.Meta.NextOrderType != nil {
+		fallback = *customer.Meta.NextOrderType
+	}
+
+	shortIDs, err := h.getFreeShortIDs(ctx, len(customer.Cart.SplitByOrderType(fallback)))
 	if err != nil {
 		return err
 	}
 
-	isExpress := *customer.Meta.NextOrderType == domain.OrderTypeExpress
-	order := domain.NewOrder(customer, address, isExpress, shortID)
-
-	if err := h.orderRepo.Save(ctx, order); err != nil {
+	orders, err := domain.NewLinkedOrders(customer, address.String(), fallback, shortIDs)
+	if err != nil {
 		return err
 	}
 
-	return h.prepareOrderPreview(ctx, customer, order, chatID)
+	for i := range orders {
+		orders[i].Address = &address
+	}
+
+	// Cart is kept until every linked order is saved, so failed attempt can be repeated
+	if err := h.orderRepo.SaveLinked(ctx, orders); err != nil {
+		return fmt.Errorf("orderRepo.SaveLinked: %w", err)
+	}
+
+	updateDTO := dto.UpdateCustomerDTO{
+		LastPosition:   &domain.Position{},
+		Cart:           &domain.Cart{},
+		Addresses:      &customer.Addresses,
+		PendingAddress: &domain.Address{},
+	}
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	return h.prepareOrderPreview(ctx, customer, orders, chatID)
 }
 
-func (h *handler) prepareOrderPreview(ctx context.Context, customer domain.Customer, order domain.Order, chatID int64) error {
-	out := getOrderStart(orderStartArgs{
-		fullName:        *customer.FullName,
-		shortOrderID:    order.ShortID,
-		phoneNumber:     *customer.PhoneNumber,
-		isExpress:       order.IsExpress,
-		deliveryAddress: order.DeliveryAddress,
-		nCartItems:      len(order.Cart),
-	})
+// getFreeShortIDs returns n distinct short ids which are not used by any order yet
+func (h *handler) getFreeShortIDs(ctx context.Context, n int) ([]string, error) {
+	var (
+		shortIDs = make([]string, 0, n)
+		taken    = make(map[string]struct{}, n)
+	)
+	for len(shortIDs) < n {
+		shortID, err := h.orderRepo.GetFreeShortID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := taken[shortID]; ok {
+			continue
+		}
+		taken[shortID] = struct{}{}
+		shortIDs = append(shortIDs, shortID)
+	}
+	return shortIDs, nil
+}
 
-	for i, cartItem := range order.Cart {
-		out += getPositionTemplate(cartPositionPreviewArgs{
-			n:         i + 1,
-			link:      cartItem.ShopLink,
-			size:      cartItem.Size,
-			category:  string(cartItem.Category),
-			priceRub:  cartItem.PriceRUB,
-			priceYuan: cartItem.PriceYUAN,
+func (h *handler) prepareOrderPreview(ctx context.Context, customer domain.Customer, orders []domain.Order, chatID int64) error {
+	var (
+		shortIDs = make([]string, 0, len(orders))
+		totalRUB uint64
+	)
+	for _, order := range orders {
+		out := getOrderStart(ctx, orderStartArgs{
+			fullName:        *customer.FullName,
+			shortOrderID:    order.ShortID,
+			phoneNumber:     *customer.PhoneNumber,
+			isExpress:       order.IsExpress,
+			deliveryAddress: order.DeliveryAddress,
+			nCartItems:      order.Cart.ItemsCount(),
 		})
-	}
 
-	out += getOrderEnd(order.AmountRUB)
+		for i, cartItem := range order.Cart {
+			out += getPositionTemplate(ctx, newCartPositionPreviewArgs(i+1, cartItem))
+		}
 
-	if err := h.sendMessage(chatID, out); err != nil {
-		return err
+		out += getOrderEnd(ctx, order.AmountRUB)
+
+		if err := h.sendMessage(ctx, chatID, out); err != nil {
+			return err
+		}
+
+		shortIDs = append(shortIDs, order.ShortID)
+		totalRUB += order.AmountRUB
+	}
+
+	if len(orders) > 1 {
+		if err := h.sendMessage(ctx, chatID, getSplitOrders(ctx, shortIDs, totalRUB)); err != nil {
+			return err
+		}
 	}
 
 	updateDTO := dto.UpdateCustomerDTO{
-		Meta:  &domain.Meta{},
-		Cart:  new(domain.Cart),
-		State: &domain.StateDefault,
+		Meta: &domain.Meta{},
+		Cart: new(domain.Cart),
 	}
 
 	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
 		return err
 	}
 
-	requisitesMsg := tg.NewMessage(chatID, getRequisites(domain.AdminRequisites, order.ShortID))
-	sentRequisitesMsg, err := h.b.Send(requisitesMsg)
+	requisitesMsg := tg.NewMessage(chatID, getRequisites(ctx, domain.AdminRequisites, strings.Join(shortIDs, ", ")))
+	sentRequisitesMsg, err := h.bot(ctx).Send(requisitesMsg)
 	if err != nil {
 		return err
 	}
 
-	editButton := tg.NewEditMessageReplyMarkup(chatID, sentRequisitesMsg.MessageID, preparePaymentButton(order.ShortID))
-	return h.cleanSend(editButton)
+	editButton := tg.NewEditMessageReplyMarkup(chatID, sentRequisitesMsg.MessageID, h.codec.preparePaymentButton(ctx, strings.Join(shortIDs, shortIDsSeparator)))
+	return h.cleanSend(ctx, editButton)
 }
 
-func (h *handler) HandlePayment(ctx context.Context, shortOrderID string, c *tg.CallbackQuery) error {
+// HandlePayment marks orders as paid, shortOrderIDs may contain several linked orders joined by shortIDsSeparator
+func (h *handler) HandlePayment(ctx context.Context, shortOrderIDs string, c *tg.CallbackQuery) error {
 	var (
 		chatID     = c.From.ID
 		telegramID = chatID
@@ -191,14 +443,17 @@ func (h *handler) HandlePayment(ctx context.Context, shortOrderID string, c *tg.
 		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
 	}
 
-	if err := h.orderRepo.UpdateToPaid(ctx, customer.CustomerID, shortOrderID); err != nil {
-		return err
+	for _, shortOrderID := range strings.Split(shortOrderIDs, shortIDsSeparator) {
+		if err := h.orderRepo.UpdateToPaid(ctx, customer.CustomerID, shortOrderID); err != nil {
+			return err
+		}
 	}
 
-	editButtons := tg.NewEditMessageReplyMarkup(chatID, c.Message.MessageID, prepareAfterPaidButtons(shortOrderID))
-	if err := h.cleanSend(editButtons); err != nil {
+	shortOrderID := strings.ReplaceAll(shortOrderIDs, shortIDsSeparator, ", ")
+	editButtons := tg.NewEditMessageReplyMarkup(chatID, c.Message.MessageID, h.codec.prepareAfterPaidButtons(ctx, shortOrderID))
+	if err := h.cleanSend(ctx, editButtons); err != nil {
 		return err
 	}
 
-	return h.sendWithKeyboard(chatID, getAfterPaid(*customer.FullName, shortOrderID), makeOrderButtons)
+	return h.sendWithKeyboard(ctx, chatID, getAfterPaid(ctx, *customer.FullName, shortOrderID), h.codec.makeOrderButtons(ctx))
 }
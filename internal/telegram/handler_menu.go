@@ -7,7 +7,6 @@ import (
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
-	"github.com/sonyamoonglade/poison-tg/pkg/functools"
 	"github.com/sonyamoonglade/poison-tg/pkg/utils/ranges"
 )
 
@@ -24,142 +23,85 @@ func (h *handler) Start(ctx context.Context, m *tg.Message) error {
 		if !errors.Is(err, domain.ErrCustomerNotFound) {
 			return err
 		}
-		// save to db
-		if err := h.customerRepo.Save(ctx, domain.NewCustomer(telegramID, username)); err != nil {
+		// save to db, language of Telegram app is used until customer picks one
+		customer := domain.NewCustomer(telegramID, username)
+		customer.Language = language(ctx)
+		if err := h.customerRepo.Save(ctx, customer); err != nil {
 			return err
 		}
 	}
 
-	return h.sendWithKeyboard(chatID, getStartTemplate(username), initialMenuKeyboard)
+	return h.sendWithKeyboard(ctx, chatID, getStartTemplate(ctx, username), initialMenuKeyboard(ctx))
 
 }
 
 func (h *handler) Menu(ctx context.Context, chatID int64) error {
-	if err := h.customerRepo.UpdateState(ctx, chatID, domain.StateDefault); err != nil {
+	// Menu leaves any flow, nothing filled in halfway must stay behind
+	if err := h.flows.cancel(ctx, chatID); err != nil &&
+		!errors.Is(err, ErrInvalidState) && !errors.Is(err, domain.ErrCustomerNotFound) {
 		return err
 	}
-
-	if err := h.sendMessage(chatID, fmt.Sprintf("Курс юаня на сегодня: %.2f ₽", h.rateProvider.GetYuanRate())); err != nil {
+	if err := h.customerRepo.UpdateState(ctx, chatID, domain.StateDefault); err != nil {
 		return err
 	}
 
-	return h.sendWithKeyboard(chatID, getTemplate().Menu, menuButtons)
-}
-
-func (h *handler) MyOrders(ctx context.Context, chatID int64) error {
-	var telegramID = chatID
-
-	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil {
+	if err := h.sendMessage(ctx, chatID, tr(ctx, "menu.yuan_rate", h.rateProvider.GetYuanRate())); err != nil {
 		return err
 	}
 
-	orders, err := h.orderRepo.GetAllForCustomer(ctx, customer.CustomerID)
-	if err != nil {
-		if errors.Is(err, domain.ErrNoOrders) {
-			return h.sendMessage(chatID, "У тебя еще нет заказов 🦕")
-		}
-
-		return err
-	}
-	if len(orders) == 0 {
-		return h.sendMessage(chatID, "У тебя еще нет заказов 🦕")
-	}
-	var name string
-	if customer.FullName != nil {
-		name = *customer.FullName
-	} else {
-		name = *customer.Username
-	}
-	out := getMyOrdersStart(name)
-	for _, o := range orders {
-		out += getSingleOrderPreview(singleOrderArgs{
-			shortID:         o.ShortID,
-			isExpress:       o.IsExpress,
-			isPaid:          o.IsPaid,
-			isApproved:      o.IsApproved,
-			cartLen:         len(o.Cart),
-			deliveryAddress: o.DeliveryAddress,
-			comment:         o.Comment,
-			status:          o.Status,
-			totalYuan:       o.AmountYUAN,
-			totalRub:        o.AmountRUB,
-		})
-		for nCartItem, cartItem := range o.Cart {
-			out += getPositionTemplate(cartPositionPreviewArgs{
-				n:         nCartItem + 1,
-				link:      cartItem.ShopLink,
-				size:      cartItem.Size,
-				category:  string(cartItem.Category),
-				priceRub:  cartItem.PriceRUB,
-				priceYuan: cartItem.PriceYUAN,
-			})
-		}
-
-		out += getTemplate().MyOrdersEnd
-	}
-
-	return h.sendMessage(chatID, out)
+	return h.sendWithKeyboard(ctx, chatID, execute(getTemplate(ctx).Menu, nil), h.codec.menuButtons(ctx))
 }
 
 func (h *handler) FAQ(ctx context.Context, chatID int64) error {
-	return h.sendWithKeyboard(chatID, faqMenuTemplate, prepareFaqButtons())
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "faq.menu"), h.codec.prepareFaqButtons(ctx))
 }
 
-func (h *handler) AnswerQuestion(chatID int64, n int) error {
-	defer h.askForMoreFaq(chatID)
-	answers := GetAnswers(n)
+func (h *handler) AnswerQuestion(ctx context.Context, chatID int64, n int) error {
+	defer h.askForMoreFaq(ctx, chatID)
+	answers := GetAnswers(ctx, n)
 	// For questions 1,2,4,5 attach image to msg.
 	if ranges.In(n, []int{1, 2, 4, 5}) {
 		imageURLs, ok := GetImageURLs(n)
 		if !ok {
 			return fmt.Errorf("invalid image urls ask")
 		}
-		return h.answerQuestionWithPhoto(chatID, answers, imageURLs)
+		return h.answerQuestionWithPhoto(ctx, chatID, answers, imageURLs)
 	} else {
-		return h.answerQuestionWithVideo(chatID, n, answers, GetVideoPath(n))
+		return h.answerQuestionWithVideo(ctx, chatID, n, answers, GetVideoPath(n))
 	}
 }
 
-func (h *handler) answerQuestionWithPhoto(chatID int64, answers []string, imageURLs []string) error {
+func (h *handler) answerQuestionWithPhoto(ctx context.Context, chatID int64, answers []string, imageURLs []string) error {
 	n_answers := len(answers)
 
 	if n_answers > 1 {
 		// Send images with caption (answers[0])
-		if err := h.sendAnswerWithImages(chatID, answers[0], imageURLs); err != nil {
+		if err := h.sendAnswerWithImages(ctx, chatID, answers[0], imageURLs); err != nil {
 			return err
 		}
 
 		// Send the rest of answers
 		for _, leftAns := range answers[1:] {
-			return h.sendMessage(chatID, leftAns)
+			return h.sendMessage(ctx, chatID, leftAns)
 		}
 
 		return nil
 	}
 
 	// If there's only one answer then just attach every image to it and send as a group
-	return h.sendAnswerWithImages(chatID, answers[0], imageURLs)
+	return h.sendAnswerWithImages(ctx, chatID, answers[0], imageURLs)
 }
 
-func (h *handler) sendAnswerWithImages(chatID int64, answer string, imageURLs []string) error {
-	var first bool
-	thumbnails := functools.Map(func(url string, i int) interface{} {
-		thumbnail := tg.NewInputMediaPhoto(tg.FileURL(url))
-		if !first {
-			thumbnail.Caption = answer
-			thumbnail.ParseMode = parseModeHTML
-			first = true
-		}
-		return thumbnail
-	}, imageURLs)
-
-	group := tg.NewMediaGroup(chatID, thumbnails)
-	_, err := h.b.SendMediaGroup(group)
+func (h *handler) sendAnswerWithImages(ctx context.Context, chatID int64, answer string, imageURLs []string) error {
+	_, err := h.sendPhotoGroup(ctx, chatID, photoGroup{
+		caption:   answer,
+		parseMode: parseModeHTML,
+		urls:      imageURLs,
+	})
 	return err
 }
 
-func (h *handler) answerQuestionWithVideo(chatID int64, n int, answers []string, videoURL string) error {
+func (h *handler) answerQuestionWithVideo(ctx context.Context, chatID int64, n int, answers []string, videoURL string) error {
 	// Case for video file attached to msg.
 	var sentBaseImage bool
 	for i, ans := range answers {
@@ -168,19 +110,19 @@ func (h *handler) answerQuestionWithVideo(chatID int64, n int, answers []string,
 			// Send answer
 			msg := tg.NewMessage(chatID, ans)
 			msg.ParseMode = parseModeHTML
-			if err := h.cleanSend(msg); err != nil {
+			if err := h.cleanSend(ctx, msg); err != nil {
 				return err
 			}
-			if err := h.sendMessage(chatID, "Отправляем видео..."); err != nil {
+			if err := h.sendMessage(ctx, chatID, tr(ctx, "faq.sending_video")); err != nil {
 				return err
 			}
 			// Send video
-			return h.cleanSend(tg.NewVideo(chatID, tg.FilePath(videoURL)))
+			return h.cleanSend(ctx, tg.NewVideo(chatID, tg.FilePath(videoURL)))
 		}
 		// In order to prevent default image
 		hasLink := AnswerHasLink(n)
 		if hasLink && !sentBaseImage {
-			if err := h.sendAnswerWithPhoto(chatID, ans, GetBaseImageURL()); err != nil {
+			if err := h.sendPhoto(ctx, chatID, ans, GetBaseImageURL()); err != nil {
 				return err
 			}
 			sentBaseImage = true
@@ -188,20 +130,13 @@ func (h *handler) answerQuestionWithVideo(chatID int64, n int, answers []string,
 		}
 		msg := tg.NewMessage(chatID, ans)
 		msg.ParseMode = parseModeHTML
-		if err := h.cleanSend(msg); err != nil {
+		if err := h.cleanSend(ctx, msg); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (h *handler) sendAnswerWithPhoto(chatID int64, answer string, imageURL string) error {
-	photo := tg.NewPhoto(chatID, tg.FileURL(imageURL))
-	photo.ParseMode = parseModeHTML
-	photo.Caption = answer
-	return h.cleanSend(photo)
-}
-
-func (h *handler) askForMoreFaq(chatID int64) error {
-	return h.sendWithKeyboard(chatID, "Остались вопросы ❓", askMoreFaqButtons)
+func (h *handler) askForMoreFaq(ctx context.Context, chatID int64) error {
+	return h.sendWithKeyboard(ctx, chatID, tr(ctx, "faq.ask_more"), h.codec.askMoreFaqButtons(ctx))
 }
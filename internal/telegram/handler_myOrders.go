@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+const myOrdersPageSize = 5
+
+// myOrdersPage is encoded in callback data as "filter:page"
+type myOrdersPage struct {
+	filter domain.OrdersFilter
+	// 0-based
+	page int
+}
+
+func (p myOrdersPage) String() string {
+	return fmt.Sprintf("%d:%d", p.filter, p.page)
+}
+
+func parseMyOrdersPage(s string) (myOrdersPage, error) {
+	filterStr, pageStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return myOrdersPage{}, fmt.Errorf("invalid orders page: %s", s)
+	}
+	filter, err := strconv.Atoi(filterStr)
+	if err != nil {
+		return myOrdersPage{}, fmt.Errorf("strconv.Atoi: %w", err)
+	}
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		return myOrdersPage{}, fmt.Errorf("strconv.Atoi: %w", err)
+	}
+	if !domain.OrdersFilter(filter).IsValid() || page < 0 {
+		return myOrdersPage{}, fmt.Errorf("invalid orders page: %s", s)
+	}
+	return myOrdersPage{filter: domain.OrdersFilter(filter), page: page}, nil
+}
+
+func (h *handler) MyOrders(ctx context.Context, chatID int64) error {
+	text, buttons, err := h.prepareMyOrdersPage(ctx, chatID, myOrdersPage{filter: domain.OrdersFilterAll})
+	if err != nil {
+		return err
+	}
+	return h.sendWithKeyboard(ctx, chatID, text, buttons)
+}
+
+// MyOrdersPage redraws list of orders in place
+func (h *handler) MyOrdersPage(ctx context.Context, chatID int64, ordersMsgID int, page string) error {
+	p, err := parseMyOrdersPage(page)
+	if err != nil {
+		return err
+	}
+
+	text, buttons, err := h.prepareMyOrdersPage(ctx, chatID, p)
+	if err != nil {
+		return err
+	}
+	return h.cleanSend(ctx, tg.NewEditMessageTextAndMarkup(chatID, ordersMsgID, text, buttons))
+}
+
+func (h *handler) prepareMyOrdersPage(ctx context.Context, chatID int64, p myOrdersPage) (string, tg.InlineKeyboardMarkup, error) {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return "", tg.InlineKeyboardMarkup{}, fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	orders, total, err := h.orderRepo.GetPageForCustomer(ctx, dto.CustomerOrdersPageDTO{
+		CustomerID: customer.CustomerID,
+		Filter:     p.filter,
+		Offset:     p.page * myOrdersPageSize,
+		Limit:      myOrdersPageSize,
+	})
+	if err != nil {
+		return "", tg.InlineKeyboardMarkup{}, fmt.Errorf("orderRepo.GetPageForCustomer: %w", err)
+	}
+
+	var name string
+	if customer.FullName != nil {
+		name = *customer.FullName
+	} else {
+		name = *customer.Username
+	}
+
+	nPages := (total + myOrdersPageSize - 1) / myOrdersPageSize
+	text := getMyOrdersList(ctx, name, p.filter, orders, p.page*myOrdersPageSize, p.page, nPages)
+	return text, h.codec.prepareMyOrdersButtons(ctx, orders, p, nPages), nil
+}
+
+// OrderDetails sends order with all positions, long orders are split into several messages
+func (h *handler) OrderDetails(ctx context.Context, chatID int64, shortOrderID string) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	order, err := h.orderRepo.GetByShortID(ctx, shortOrderID)
+	if err != nil {
+		return fmt.Errorf("orderRepo.GetByShortID: %w", err)
+	}
+
+	// Short id comes from callback data
+	if order.Customer.CustomerID != customer.CustomerID {
+		return staleButtonError("error.order_not_found", domain.ErrOrderNotFound)
+	}
+
+	out := getSingleOrderPreview(ctx, singleOrderArgs{
+		shortID:         order.ShortID,
+		isExpress:       order.IsExpress,
+		isPaid:          order.IsPaid,
+		isApproved:      order.IsApproved,
+		cartLen:         order.Cart.ItemsCount(),
+		deliveryAddress: order.DeliveryAddress,
+		comment:         order.Comment,
+		status:          order.Status,
+		totalYuan:       order.AmountYUAN,
+		totalRub:        order.AmountRUB,
+	})
+	for nCartItem, cartItem := range order.Cart {
+		out += getPositionTemplate(ctx, newCartPositionPreviewArgs(nCartItem+1, cartItem))
+	}
+
+	parts := splitMessage(out, maxMessageLen)
+	for i, part := range parts {
+		if i < len(parts)-1 {
+			if err := h.sendMessage(ctx, chatID, part); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := h.sendWithKeyboard(ctx, chatID, part, h.codec.prepareRepeatOrderButtons(ctx, order.ShortID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
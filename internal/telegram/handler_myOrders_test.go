@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMyOrdersPage(t *testing.T) {
+	p := myOrdersPage{filter: domain.OrdersFilterCompleted, page: 3}
+	parsed, err := parseMyOrdersPage(p.String())
+	require.NoError(t, err)
+	require.Equal(t, p, parsed)
+
+	for _, invalid := range []string{"", "1", "a:1", "1:b", "9:0", "1:-1"} {
+		_, err := parseMyOrdersPage(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestPrepareMyOrdersButtons(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		c      = NewCallbackCodec(newMemoryCallbackStore())
+		orders = []domain.Order{{ShortID: "A1"}, {ShortID: "B2"}}
+	)
+
+	// Middle page has both arrows
+	buttons := c.prepareMyOrdersButtons(ctx, orders, myOrdersPage{filter: domain.OrdersFilterActive, page: 1}, 3)
+	require.Len(t, buttons.InlineKeyboard, 4)
+	require.Equal(t, c.callbackWithString(ctx, orderDetailsCallback, "A1"), *buttons.InlineKeyboard[0][0].CallbackData)
+	nav := buttons.InlineKeyboard[2]
+	require.Len(t, nav, 2)
+	require.Equal(t, c.callbackWithString(ctx, myOrdersPageCallback, "1:0"), *nav[0].CallbackData)
+	require.Equal(t, c.callbackWithString(ctx, myOrdersPageCallback, "1:2"), *nav[1].CallbackData)
+	filters := buttons.InlineKeyboard[3]
+	require.Len(t, filters, 3)
+	require.Equal(t, "• Активные", filters[1].Text)
+
+	// Single page has no arrows
+	buttons = c.prepareMyOrdersButtons(ctx, orders, myOrdersPage{}, 1)
+	require.Len(t, buttons.InlineKeyboard, 3)
+}
+
+func TestSplitMessage(t *testing.T) {
+	t.Run("short text is not split", func(t *testing.T) {
+		require.Equal(t, []string{"abc\ndef"}, splitMessage("abc\ndef", 10))
+	})
+
+	t.Run("split by lines", func(t *testing.T) {
+		parts := splitMessage("aaa\nbbb\nccc\n", 8)
+		require.Equal(t, []string{"aaa\nbbb\n", "ccc\n"}, parts)
+	})
+
+	t.Run("long line is split by runes", func(t *testing.T) {
+		parts := splitMessage("ab\n"+strings.Repeat("ж", 7), 3)
+		require.Equal(t, []string{"ab\n", "жжж", "жжж", "ж"}, parts)
+	})
+
+	t.Run("emoji takes two code units", func(t *testing.T) {
+		parts := splitMessage("🦕🦕🦕", 4)
+		require.Equal(t, []string{"🦕🦕", "🦕"}, parts)
+	})
+
+	t.Run("parts fit telegram limit", func(t *testing.T) {
+		text := strings.Repeat("Ссылка: https://dw4.co/t/A/1 🦕\n", 500)
+		parts := splitMessage(text, maxMessageLen)
+		require.Greater(t, len(parts), 1)
+		require.Equal(t, text, strings.Join(parts, ""))
+		for _, part := range parts {
+			require.LessOrEqual(t, utf16Len(part), maxMessageLen)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+func (h *handler) Profile(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	return h.sendWithKeyboard(ctx, chatID, getProfile(ctx, customer), h.codec.prepareProfileButtons(ctx, customer))
+}
+
+func (h *handler) EditProfileFIO(ctx context.Context, chatID int64) error {
+	return h.startProfileEdit(ctx, chatID, domain.StateWaitingForFIO)
+}
+
+func (h *handler) EditProfilePhoneNumber(ctx context.Context, chatID int64) error {
+	return h.startProfileEdit(ctx, chatID, domain.StateWaitingForPhoneNumber)
+}
+
+// startProfileEdit moves customer to step of details flow, the step returns back to profile
+func (h *handler) startProfileEdit(ctx context.Context, chatID int64, field domain.State) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	editingProfile := true
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		EditingProfile: &editingProfile,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+	customer.Meta.EditingProfile = editingProfile
+
+	return h.flows.enter(ctx, customer, field)
+}
+
+// finishProfileEdit returns customer back to profile after full name or phone number is changed
+func (h *handler) finishProfileEdit(ctx context.Context, chatID int64, customer domain.Customer, updateDTO dto.UpdateCustomerDTO) error {
+	editingProfile := false
+	updateDTO.EditingProfile = &editingProfile
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, updateDTO); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	if err := h.sendWithKeyboard(ctx, chatID, tr(ctx, "profile.updated"), initialMenuKeyboard(ctx)); err != nil {
+		return err
+	}
+	return h.Profile(ctx, chatID)
+}
+
+func (h *handler) AskForProfileAddress(ctx context.Context, chatID int64) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if len(customer.Addresses) >= domain.MaxAddresses {
+		return h.sendMessage(ctx, chatID, tr(ctx, "address.too_many"))
+	}
+
+	return h.flows.enter(ctx, customer, domain.StateWaitingForProfileAddress)
+}
+
+func validateAddressName(ctx context.Context, in flowInput) error {
+	if err := domain.ValidateAddressName(strings.TrimSpace(in.text)); err != nil {
+		return invalidInput(tr(ctx, "address.invalid_name"))
+	}
+	return nil
+}
+
+func (h *handler) askForAddressName(ctx context.Context, customer domain.Customer) (flowPrompt, error) {
+	return flowPrompt{text: tr(ctx, "address.ask_name")}, nil
+}
+
+func answerPendingAddressName(ctx context.Context, customer domain.Customer) string {
+	if customer.Meta.PendingAddress == nil {
+		return ""
+	}
+	return customer.Meta.PendingAddress.Name
+}
+
+// handleAddressName takes name of new address and starts address input
+func (h *handler) handleAddressName(ctx context.Context, customer *domain.Customer, in flowInput) (domain.State, error) {
+	name := strings.TrimSpace(in.text)
+	if customer.HasAddressNamed(name) {
+		return domain.StateDefault, invalidInput(tr(ctx, "address.name_taken"))
+	}
+
+	editingProfile := true
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		EditingProfile: &editingProfile,
+	}); err != nil {
+		return domain.StateDefault, fmt.Errorf("customerRepo.Update: %w", err)
+	}
+	customer.Meta.EditingProfile = editingProfile
+
+	return h.beginAddressInput(ctx, customer, name)
+}
+
+func (h *handler) RemoveProfileAddress(ctx context.Context, chatID int64, profileMsgID int, addressN string) error {
+	var telegramID = chatID
+
+	n, err := strconv.Atoi(addressN)
+	if err != nil {
+		return fmt.Errorf("strconv.Atoi: %w", err)
+	}
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	if err := customer.RemoveAddress(n); err != nil {
+		return err
+	}
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		Addresses: &customer.Addresses,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	// Redraw profile message
+	return h.cleanSend(ctx, tg.NewEditMessageTextAndMarkup(chatID, profileMsgID, getProfile(ctx, customer), h.codec.prepareProfileButtons(ctx, customer)))
+}
@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories/dto"
+)
+
+// RepeatOrder copies positions of customer's order into cart re-priced with today's rate
+func (h *handler) RepeatOrder(ctx context.Context, chatID int64, shortOrderID string) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+
+	order, err := h.orderRepo.GetByShortID(ctx, shortOrderID)
+	if err != nil {
+		return fmt.Errorf("orderRepo.GetByShortID: %w", err)
+	}
+
+	// Short id comes from callback data
+	if order.Customer.CustomerID != customer.CustomerID {
+		return staleButtonError("error.order_not_found", domain.ErrOrderNotFound)
+	}
+
+	changes := customer.Cart.AddRepeated(order, h.rateProvider.GetYuanRate())
+
+	if err := h.customerRepo.Update(ctx, customer.CustomerID, dto.UpdateCustomerDTO{
+		Cart: &customer.Cart,
+	}); err != nil {
+		return fmt.Errorf("customerRepo.Update: %w", err)
+	}
+
+	if err := h.sendMessage(ctx, chatID, getRepeatedOrder(ctx, order.ShortID, changes)); err != nil {
+		return err
+	}
+
+	return h.GetCart(ctx, chatID)
+}
@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/pkg/i18n"
+)
+
+// defaultLanguage is used when customer's language has no locale and for messages missing in a locale
+const defaultLanguage = "ru"
+
+// Messages of every language, see LoadLocales. Replaced as a whole when locales are reloaded
+var (
+	messagesMu sync.RWMutex
+	messages   = i18n.New(defaultLanguage)
+	// Number of arguments of every message as of first load, callers pass exactly that many
+	messageArgs map[string]int
+)
+
+// LoadLocales reads messages of every language from dir, see i18n.Load.
+// Messages are replaced only if every file is valid and reload keeps messages with their arguments,
+// otherwise ones loaded before stay in use
+func LoadLocales(dir string) error {
+	loaded, err := i18n.Load(dir, defaultLanguage)
+	if err != nil {
+		return fmt.Errorf("can't load locales: %w", err)
+	}
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	if messageArgs == nil {
+		messageArgs = make(map[string]int)
+		for _, id := range loaded.IDs() {
+			messageArgs[id], _ = loaded.Args(id)
+		}
+	} else if err := checkMessageArgs(loaded); err != nil {
+		return fmt.Errorf("can't reload locales: %w", err)
+	}
+	messages = loaded
+	return nil
+}
+
+// checkMessageArgs reports message which is removed or takes other number of arguments than on first load
+func checkMessageArgs(loaded *i18n.Bundle) error {
+	ids := make([]string, 0, len(messageArgs))
+	for id := range messageArgs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		n, ok := loaded.Args(id)
+		if !ok {
+			return fmt.Errorf("message %s is removed", id)
+		}
+		if n != messageArgs[id] {
+			return fmt.Errorf("message %s takes %d arguments instead of %d", id, n, messageArgs[id])
+		}
+	}
+	return nil
+}
+
+func bundle() *i18n.Bundle {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+	return messages
+}
+
+type languageKey struct{}
+
+// withLanguage sets language of texts sent while handling update
+func withLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+func language(ctx context.Context) string {
+	if lang, ok := ctx.Value(languageKey{}).(string); ok && bundle().Has(lang) {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// customerLanguage is language customer picked in menu or language of their Telegram app before that
+func customerLanguage(customer domain.Customer, languageCode string) string {
+	if customer.Language != "" && bundle().Has(customer.Language) {
+		return customer.Language
+	}
+	return bundle().Match(languageCode)
+}
+
+// tr is message with id in language of ctx
+func tr(ctx context.Context, id string, args ...any) string {
+	return bundle().Get(language(ctx), id, args...)
+}
+
+// trPlural is message with id in plural form for n, n is the first argument of the message
+func trPlural(ctx context.Context, id string, n int, args ...any) string {
+	return bundle().Plural(language(ctx), id, n, args...)
+}
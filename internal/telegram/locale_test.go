@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if err := LoadLocales(localesDir); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := LoadTemplates(templatesPath); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// messageCall is call of tr or trPlural with literal message id
+type messageCall struct {
+	id  string
+	pos token.Position
+	// Arguments message is formatted with, -1 if they're passed as slice
+	args int
+}
+
+func parseMessageCalls(t *testing.T) []messageCall {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+
+	var calls []messageCall
+	for _, pkg := range packages {
+		ast.Inspect(pkg, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			var (
+				arg  ast.Expr
+				args int
+			)
+			switch fn.Name {
+			// n passed to trPlural is the first argument of message
+			case "tr", "trPlural":
+				arg, args = call.Args[1], len(call.Args)-2
+			case "staleButtonError":
+				arg = call.Args[0]
+			}
+			if call.Ellipsis.IsValid() {
+				args = -1
+			}
+			if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				id, err := strconv.Unquote(lit.Value)
+				require.NoError(t, err)
+				calls = append(calls, messageCall{id: id, pos: fset.Position(call.Pos()), args: args})
+			}
+			return true
+		})
+	}
+	require.NotEmpty(t, calls)
+	return calls
+}
+
+// Message ids are mostly literals passed to tr, typo in one would be shown to customer as is
+func TestMessagesExist(t *testing.T) {
+	for _, call := range parseMessageCalls(t) {
+		require.NotEqual(t, call.id, bundle().Get(defaultLanguage, call.id), "%s: no message %s", call.pos, call.id)
+	}
+}
+
+// Message formatted with fewer arguments than it has verbs shows %!d(MISSING) to customer.
+// Locales are checked to take the same arguments on load, see i18n.Load
+func TestMessageArgs(t *testing.T) {
+	for _, call := range parseMessageCalls(t) {
+		if call.args == -1 {
+			continue
+		}
+		expected, ok := bundle().Args(call.id)
+		require.True(t, ok, "%s: no message %s", call.pos, call.id)
+		require.Equal(t, expected, call.args, "%s: message %s takes %d arguments", call.pos, call.id, expected)
+	}
+}
+
+// copyLocales copies locales so that test can change them, they're loaded back after test
+func copyLocales(t *testing.T) string {
+	locales := t.TempDir()
+	t.Cleanup(func() {
+		require.NoError(t, LoadLocales(localesDir))
+	})
+	paths, err := filepath.Glob(filepath.Join(localesDir, "*.json"))
+	require.NoError(t, err)
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(locales, filepath.Base(p)), content, 0o644))
+	}
+	return locales
+}
+
+// editMessages applies edit to messages of every locale in dir
+func editMessages(t *testing.T, dir string, edit func(messages map[string]any)) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		require.NoError(t, err)
+		var messages map[string]any
+		require.NoError(t, json.Unmarshal(content, &messages))
+		edit(messages)
+		content, err = json.Marshal(messages)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(p, content, 0o644))
+	}
+}
+
+// Locales are reloaded while running, but callers pass as many arguments as messages took on start
+func TestReloadLocales(t *testing.T) {
+	ctx := context.Background()
+	locales := copyLocales(t)
+
+	setMessage := func(text string) {
+		editMessages(t, locales, func(messages map[string]any) {
+			messages["button.order"] = text
+		})
+	}
+
+	setMessage("Заказ №%s")
+	require.NoError(t, LoadLocales(locales))
+	require.Equal(t, "Заказ №A1", tr(ctx, "button.order", "A1"))
+
+	t.Run("changed arguments", func(t *testing.T) {
+		setMessage("Заказ №%s на %d ₽")
+		require.ErrorContains(t, LoadLocales(locales), "button.order")
+		require.Equal(t, "Заказ №A1", tr(ctx, "button.order", "A1"))
+	})
+
+	t.Run("removed message", func(t *testing.T) {
+		editMessages(t, locales, func(messages map[string]any) {
+			delete(messages, "button.order")
+		})
+		require.ErrorContains(t, LoadLocales(locales), "button.order")
+		require.Equal(t, "Заказ №A1", tr(ctx, "button.order", "A1"))
+	})
+}
+
+func TestCustomerLanguage(t *testing.T) {
+	testcases := []struct {
+		description  string
+		customer     domain.Customer
+		languageCode string
+		expected     string
+	}{
+		{
+			description:  "language of telegram app",
+			languageCode: "en-GB",
+			expected:     "en",
+		},
+		{
+			description:  "telegram app in language without locale",
+			languageCode: "de",
+			expected:     defaultLanguage,
+		},
+		{
+			description:  "picked language is preferred",
+			customer:     domain.Customer{Language: "kk"},
+			languageCode: "en",
+			expected:     "kk",
+		},
+		{
+			description:  "picked language without locale",
+			customer:     domain.Customer{Language: "uz"},
+			languageCode: "en",
+			expected:     "en",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, customerLanguage(tc.customer, tc.languageCode))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/repositories"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// mediaCache remembers file ids telegram assigned to media sent by url.
+// Persisted in repo, in-memory map is used to avoid db roundtrips.
+// Cache failures are never critical - url is used instead
+type mediaCache struct {
+	mu    *sync.RWMutex
+	files map[string]string
+	repo  repositories.Media
+}
+
+func newMediaCache(repo repositories.Media) *mediaCache {
+	return &mediaCache{
+		mu:    new(sync.RWMutex),
+		files: make(map[string]string),
+		repo:  repo,
+	}
+}
+
+// resolve returns cached file ids for urls if every url has one, otherwise urls itself
+func (m *mediaCache) resolve(ctx context.Context, urls []string) (files []tg.RequestFileData, cached bool) {
+	fileIDs := make([]tg.RequestFileData, 0, len(urls))
+	for _, url := range urls {
+		fileID, ok := m.get(ctx, url)
+		if !ok {
+			return urlFiles(urls), false
+		}
+		fileIDs = append(fileIDs, tg.FileID(fileID))
+	}
+	return fileIDs, len(fileIDs) > 0
+}
+
+func (m *mediaCache) get(ctx context.Context, url string) (string, bool) {
+	m.mu.RLock()
+	fileID, ok := m.files[url]
+	m.mu.RUnlock()
+	if ok {
+		return fileID, true
+	}
+
+	fileID, err := m.repo.GetFileID(ctx, url)
+	if err != nil {
+		if !errors.Is(err, domain.ErrMediaNotFound) {
+			logger.Get().Error("can't get media file id", zap.String("url", url), zap.Error(err))
+		}
+		return "", false
+	}
+
+	m.mu.Lock()
+	m.files[url] = fileID
+	m.mu.Unlock()
+	return fileID, true
+}
+
+// remember saves file ids of photos in sent messages. sent[i] must correspond to urls[i]
+func (m *mediaCache) remember(ctx context.Context, urls []string, sent []tg.Message) {
+	if len(urls) != len(sent) {
+		return
+	}
+	for i, url := range urls {
+		fileID, ok := largestPhotoFileID(sent[i])
+		if !ok {
+			continue
+		}
+		m.mu.RLock()
+		known := m.files[url] == fileID
+		m.mu.RUnlock()
+		if known {
+			continue
+		}
+
+		m.mu.Lock()
+		m.files[url] = fileID
+		m.mu.Unlock()
+		if err := m.repo.SaveFileID(ctx, url, fileID); err != nil {
+			logger.Get().Error("can't save media file id", zap.String("url", url), zap.Error(err))
+		}
+	}
+}
+
+func (m *mediaCache) forget(ctx context.Context, urls ...string) {
+	for _, url := range urls {
+		m.mu.Lock()
+		delete(m.files, url)
+		m.mu.Unlock()
+		if err := m.repo.Delete(ctx, url); err != nil {
+			logger.Get().Error("can't delete media file id", zap.String("url", url), zap.Error(err))
+		}
+	}
+}
+
+func urlFiles(urls []string) []tg.RequestFileData {
+	files := make([]tg.RequestFileData, 0, len(urls))
+	for _, url := range urls {
+		files = append(files, tg.FileURL(url))
+	}
+	return files
+}
+
+// Descriptions of 400 errors telegram returns for file it can't use. Other 400s (e.g. caption parse error)
+// have nothing to do with cached file ids
+var rejectedFileDescriptions = []string{
+	"wrong file identifier",
+	"wrong remote file identifier",
+	"wrong file_id",
+	"file reference expired",
+	"failed to get http url content",
+}
+
+// isRejectedFileError reports whether telegram refused file of request, e.g. because of stale file id
+func isRejectedFileError(err error) bool {
+	var tgErr *tg.Error
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusBadRequest {
+		return false
+	}
+	description := strings.ToLower(tgErr.Message)
+	for _, d := range rejectedFileDescriptions {
+		if strings.Contains(description, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlockedError reports whether customer blocked the bot or is deactivated
+func isBlockedError(err error) bool {
+	var tgErr *tg.Error
+	return errors.As(err, &tgErr) && tgErr.Code == http.StatusForbidden
+}
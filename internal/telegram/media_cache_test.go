@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type mapMediaRepo map[string]string
+
+func (m mapMediaRepo) GetFileID(ctx context.Context, url string) (string, error) {
+	fileID, ok := m[url]
+	if !ok {
+		return "", domain.ErrMediaNotFound
+	}
+	return fileID, nil
+}
+
+func (m mapMediaRepo) SaveFileID(ctx context.Context, url string, fileID string) error {
+	m[url] = fileID
+	return nil
+}
+
+func (m mapMediaRepo) Delete(ctx context.Context, url string) error {
+	delete(m, url)
+	return nil
+}
+
+func photoMessage(fileIDs ...string) tg.Message {
+	var sizes []tg.PhotoSize
+	for _, id := range fileIDs {
+		sizes = append(sizes, tg.PhotoSize{FileID: id})
+	}
+	return tg.Message{Photo: sizes}
+}
+
+func TestMediaCache(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		urls = []string{"https://example.com/1.jpg", "https://example.com/2.jpg"}
+	)
+
+	t.Run("nothing cached. Should return urls", func(t *testing.T) {
+		cache := newMediaCache(make(mapMediaRepo))
+		files, cached := cache.resolve(ctx, urls)
+		require.False(t, cached)
+		require.Equal(t, urlFiles(urls), files)
+	})
+
+	t.Run("remember largest photo and resolve", func(t *testing.T) {
+		repo := make(mapMediaRepo)
+		cache := newMediaCache(repo)
+		cache.remember(ctx, urls, []tg.Message{photoMessage("small1", "big1"), photoMessage("small2", "big2")})
+
+		files, cached := cache.resolve(ctx, urls)
+		require.True(t, cached)
+		require.Equal(t, []tg.RequestFileData{tg.FileID("big1"), tg.FileID("big2")}, files)
+		// Persisted
+		require.Equal(t, "big1", repo[urls[0]])
+
+		// New cache instance reads from repo
+		files, cached = newMediaCache(repo).resolve(ctx, urls)
+		require.True(t, cached)
+		require.Equal(t, []tg.RequestFileData{tg.FileID("big1"), tg.FileID("big2")}, files)
+	})
+
+	t.Run("partially cached. Should return urls", func(t *testing.T) {
+		cache := newMediaCache(mapMediaRepo{urls[0]: "id1"})
+		files, cached := cache.resolve(ctx, urls)
+		require.False(t, cached)
+		require.Equal(t, urlFiles(urls), files)
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		repo := mapMediaRepo{urls[0]: "id1", urls[1]: "id2"}
+		cache := newMediaCache(repo)
+		_, cached := cache.resolve(ctx, urls)
+		require.True(t, cached)
+
+		cache.forget(ctx, urls...)
+		_, cached = cache.resolve(ctx, urls)
+		require.False(t, cached)
+		require.Empty(t, repo)
+	})
+}
+
+func TestIsRejectedFileError(t *testing.T) {
+	require.True(t, isRejectedFileError(&tg.Error{Code: 400, Message: "Bad Request: wrong file identifier/HTTP URL specified"}))
+	require.True(t, isRejectedFileError(&tg.Error{Code: 400, Message: "Bad Request: failed to get HTTP URL content"}))
+	require.False(t, isRejectedFileError(&tg.Error{Code: 400, Message: "Bad Request: can't parse entities: Unsupported start tag"}))
+	require.False(t, isRejectedFileError(&tg.Error{Code: 400, Message: "Bad Request: message is not modified"}))
+	require.False(t, isRejectedFileError(&tg.Error{Code: 429, Message: "Too Many Requests"}))
+	require.False(t, isRejectedFileError(errors.New("network error")))
+}
@@ -0,0 +1,82 @@
+package pickup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+)
+
+// Provider names used in directory file
+var providerNames = map[string]domain.PickupProvider{
+	"cdek":      domain.PickupProviderCDEK,
+	"pickpoint": domain.PickupProviderPickPoint,
+}
+
+type filePoint struct {
+	Provider string `json:"provider"`
+	Code     string `json:"code"`
+	City     string `json:"city"`
+	Address  string `json:"address"`
+}
+
+type key struct {
+	provider domain.PickupProvider
+	code     string
+}
+
+// FileDirectory is pickup point directory backed by JSON file.
+// Points are read once, directory is read-only afterwards
+type FileDirectory struct {
+	points map[key]domain.PickupPoint
+}
+
+func NewFileDirectory(path string) (*FileDirectory, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var filePoints []filePoint
+	if err := json.Unmarshal(content, &filePoints); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if len(filePoints) == 0 {
+		return nil, fmt.Errorf("no pickup points in %s", path)
+	}
+
+	points := make(map[key]domain.PickupPoint, len(filePoints))
+	for i, p := range filePoints {
+		provider, ok := providerNames[strings.ToLower(p.Provider)]
+		if !ok {
+			return nil, fmt.Errorf("point %d: unknown provider %q", i, p.Provider)
+		}
+		if p.Code == "" || p.City == "" {
+			return nil, fmt.Errorf("point %d: code and city are required", i)
+		}
+		k := key{provider: provider, code: strings.ToUpper(p.Code)}
+		if _, ok := points[k]; ok {
+			return nil, fmt.Errorf("point %d: duplicate code %q", i, p.Code)
+		}
+		points[k] = domain.PickupPoint{
+			Provider: provider,
+			Code:     k.code,
+			City:     p.City,
+			Address:  p.Address,
+		}
+	}
+
+	return &FileDirectory{points: points}, nil
+}
+
+// Find looks point up by case-insensitive code
+func (d *FileDirectory) Find(_ context.Context, provider domain.PickupProvider, code string) (domain.PickupPoint, error) {
+	p, ok := d.points[key{provider: provider, code: strings.ToUpper(strings.TrimSpace(code))}]
+	if !ok {
+		return domain.PickupPoint{}, domain.ErrPickupPointNotFound
+	}
+	return p, nil
+}
@@ -0,0 +1,60 @@
+package pickup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileDirectory(t *testing.T) {
+	d, err := NewFileDirectory("testdata/points.json")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	p, err := d.Find(ctx, domain.PickupProviderCDEK, " msk123 ")
+	require.NoError(t, err)
+	require.Equal(t, domain.PickupPoint{
+		Provider: domain.PickupProviderCDEK,
+		Code:     "MSK123",
+		City:     "Москва",
+		Address:  "Тверская ул., 1",
+	}, p)
+
+	p, err = d.Find(ctx, domain.PickupProviderPickPoint, "7701-001")
+	require.NoError(t, err)
+	require.Equal(t, "Москва", p.City)
+
+	// Same code of other provider
+	_, err = d.Find(ctx, domain.PickupProviderPickPoint, "MSK123")
+	require.ErrorIs(t, err, domain.ErrPickupPointNotFound)
+}
+
+func TestFileDirectoryInvalidFile(t *testing.T) {
+	tests := []struct {
+		description string
+		content     string
+	}{
+		{description: "not json", content: "points"},
+		{description: "no points", content: "[]"},
+		{description: "unknown provider", content: `[{"provider": "dhl", "code": "A1", "city": "Москва"}]`},
+		{description: "missing code", content: `[{"provider": "cdek", "city": "Москва"}]`},
+		{description: "duplicate code", content: `[{"provider": "cdek", "code": "A1", "city": "Москва"}, {"provider": "cdek", "code": "a1", "city": "Москва"}]`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "points.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			_, err := NewFileDirectory(path)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := NewFileDirectory(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -11,7 +11,6 @@ import (
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/pkg/logger"
-	"github.com/sonyamoonglade/poison-tg/pkg/utils/ranges"
 	"go.uber.org/zap"
 )
 
@@ -20,8 +19,9 @@ var (
 	ErrNoRoute   = errors.New("no route")
 )
 
-type StateProvider interface {
-	GetState(ctx context.Context, telegramID int64) (domain.State, error)
+// ActivityTracker records activity of customer and returns their language, see repositories.Customer
+type ActivityTracker interface {
+	TouchActivity(ctx context.Context, telegramID int64, at time.Time) (domain.Customer, error)
 }
 
 type RouteHandler interface {
@@ -30,19 +30,36 @@ type RouteHandler interface {
 	Menu(ctx context.Context, chatID int64) error
 	Catalog(ctx context.Context, chatID int64) error
 	MyOrders(ctx context.Context, chatID int64) error
+	MyOrdersPage(ctx context.Context, chatID int64, ordersMsgID int, page string) error
+	OrderDetails(ctx context.Context, chatID int64, shortOrderID string) error
+	RepeatOrder(ctx context.Context, chatID int64, shortOrderID string) error
 	FAQ(ctx context.Context, chatID int64) error
-
-	AnswerQuestion(chatID int64, n int) error
+	ToggleDropsSubscription(ctx context.Context, chatID int64) error
+	Favourites(ctx context.Context, chatID int64) error
+	AddToFavourites(ctx context.Context, chatID int64, itemID string) error
+	RemoveFromFavourites(ctx context.Context, chatID int64, favouritesMsgID int, itemID string) error
+	OptOutCartReminders(ctx context.Context, chatID int64) error
+	SelectPositionToEdit(ctx context.Context, chatID int64, positionN string) error
+	StartPositionEdit(ctx context.Context, chatID int64, positionN string, field domain.State) error
+	ChangePositionQuantity(ctx context.Context, chatID int64, cartPreviewMsgID int, positionN string, increase bool) error
+	Profile(ctx context.Context, chatID int64) error
+	Language(ctx context.Context, chatID int64) error
+	SetLanguage(ctx context.Context, chatID int64, lang string) error
+	EditProfileFIO(ctx context.Context, chatID int64) error
+	EditProfilePhoneNumber(ctx context.Context, chatID int64) error
+	AskForProfileAddress(ctx context.Context, chatID int64) error
+	RemoveProfileAddress(ctx context.Context, chatID int64, profileMsgID int, addressN string) error
+
+	AnswerQuestion(ctx context.Context, chatID int64, n int) error
 
 	AskForCalculatorOrderType(ctx context.Context, chatID int64) error
 	HandleCalculatorOrderTypeInput(ctx context.Context, chatID int64, typ domain.OrderType) error
 	HandleCalculatorCategoryInput(ctx context.Context, chatID int64, cat domain.Category) error
 	AskForCalculatorCategory(ctx context.Context, chatID int64) error
-	HandleCalculatorInput(ctx context.Context, m *tg.Message) error
 
 	GetCart(ctx context.Context, chatID int64) error
 	EditCart(ctx context.Context, chatID int64, cartPreviewMsgID int) error
-	RemoveCartPosition(ctx context.Context, chatID int64, callbackData int, originalMsgID, cartPreviewMsgID int) error
+	RemoveCartPosition(ctx context.Context, chatID int64, positionN int, originalMsgID, cartPreviewMsgID int) error
 
 	// Add position is like StartmakeOrderGuide but without instruction
 	AddPosition(ctx context.Context, chatID int64) error
@@ -60,18 +77,23 @@ type RouteHandler interface {
 	MakeOrderGuideStep5(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error
 	MakeOrderGuideStep6(ctx context.Context, chatID int64, controlButtonsMessageID int, guideMsgIDs []int) error
 
+	// Navigation buttons of flow step prompts
+	FlowBack(ctx context.Context, chatID int64) error
+	FlowCancel(ctx context.Context, chatID int64) error
+
 	AskForFIO(ctx context.Context, chatID int64) error
+	// HandleFlowInput handles text and shared contact sent at any step of a flow.
 	// Use tg.Message because user types in and userID is user's
-	HandleFIOInput(ctx context.Context, m *tg.Message) error
-	HandlePhoneNumberInput(ctx context.Context, m *tg.Message) error
-	HandleDeliveryAddressInput(ctx context.Context, m *tg.Message) error
+	HandleFlowInput(ctx context.Context, m *tg.Message) error
+	UseSavedDetails(ctx context.Context, chatID int64) error
+	EnterNewDetails(ctx context.Context, chatID int64) error
+	UseSavedAddress(ctx context.Context, chatID int64, addressN string) error
+	NewDeliveryAddress(ctx context.Context, chatID int64) error
+	HandlePickupProviderSelect(ctx context.Context, chatID int64, provider domain.PickupProvider) error
 	HandlePayment(ctx context.Context, shortOrderID string, c *tg.CallbackQuery) error
 
-	HandleSizeInput(ctx context.Context, m *tg.Message) error
 	// Use tg.CallbackQuery because callback is asosiated with c.User.ID, message is from bot
 	HandleButtonSelect(ctx context.Context, c *tg.CallbackQuery, button domain.Button) error
-	HandlePriceInput(ctx context.Context, m *tg.Message) error
-	HandleLinkInput(ctx context.Context, m *tg.Message) error
 
 	// Catalog manupulations
 	HandleCatalogNext(ctx context.Context, chatID int64, controlButtonsMessageID int64, thumnailMsgIDs []int) error
@@ -79,75 +101,153 @@ type RouteHandler interface {
 
 	// Utils
 	HandleError(ctx context.Context, err error, m tg.Update)
-	AnswerCallback(callbackID string) error
+	AnswerCallback(ctx context.Context, callbackID string) error
 }
 
 type Router struct {
 	h              RouteHandler
 	updates        <-chan tg.Update
-	shutdown       chan struct{}
 	handlerTimeout time.Duration
 	wg             *sync.WaitGroup
-	stateProvider  StateProvider
+	customers      ActivityTracker
+	codec          *CallbackCodec
+	// Updates of one chat are handled in order they came, see chatQueues
+	queues *chatQueues
+	// Closed when Bootstrap has pushed every update into queues
+	bootstrapped chan struct{}
 }
 
-func NewRouter(updates <-chan tg.Update, h RouteHandler, stateProvider StateProvider, timeout time.Duration) *Router {
-	return &Router{
+func NewRouter(updates <-chan tg.Update, h RouteHandler, customers ActivityTracker, codec *CallbackCodec, timeout time.Duration) *Router {
+	r := &Router{
 		h:              h,
 		updates:        updates,
 		handlerTimeout: timeout,
-		stateProvider:  stateProvider,
-		shutdown:       make(chan struct{}),
+		customers:      customers,
+		codec:          codec,
+		bootstrapped:   make(chan struct{}),
 		wg:             new(sync.WaitGroup),
 	}
+	r.queues = newChatQueues(maxQueuedUpdatesPerChat, r.wg, r.handle)
+	return r
 }
 
-// Cosmetic error return
+// Bootstrap returns when updates channel is closed and buffered updates are taken. Cosmetic error return
 func (r *Router) Bootstrap() error {
+	defer close(r.bootstrapped)
 	logger.Get().Info("router is listening for updates")
-	for {
-		select {
-		case <-r.shutdown:
-			logger.Get().Info("router is shutting down")
-			return nil
-		case update, ok := <-r.updates:
-			if !ok {
-				return nil
-			}
-
-			ctx, cancel := context.WithTimeout(context.Background(), r.handlerTimeout)
-			r.wg.Add(1)
-			go func() {
-				defer func() {
-					if panicMsg := recover(); panicMsg != nil {
-						logger.Get().Error("panic in handler",
-							zap.Any("msg", panicMsg),
-							zap.ByteString("stacktrace", debug.Stack()))
-					}
-				}()
-				if err := r.mapToHandler(ctx, update); err != nil {
-					var (
-						username   = domain.MakeUsername(update.FromChat().UserName)
-						telegramID = update.FromChat().ID
-					)
-
-					logger.Get().Error("error in handler occurred",
-						zap.String("from", username),
-						zap.Int64("telegramId", telegramID),
-						zap.Error(err))
-
-					r.h.HandleError(ctx, err, update)
-				}
-				defer cancel()
-				defer r.wg.Done()
-			}()
+	for update := range r.updates {
+		// Handlers of the same customer read and update the same document, so they must not race
+		chatID := updateChatID(update)
+		if err := r.queues.push(chatID, update); err != nil {
+			logger.Get().Warn("update is dropped",
+				zap.Int64("chatId", chatID),
+				zap.Int("updateId", update.UpdateID),
+				zap.Error(err))
 		}
 	}
+	logger.Get().Info("router is shutting down")
+	return nil
 }
 
-func (r *Router) Shutdown() {
-	close(r.shutdown)
-	r.wg.Wait()
+// handle is called by chat queue, timeout starts when handling starts and not when update is queued
+func (r *Router) handle(update tg.Update) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.handlerTimeout)
+	defer cancel()
+	// Customer sees the id if handler fails, support finds the failure in logs with it
+	ctx = withCorrelationID(ctx, newCorrelationID())
+	// Error replies are localized too, so language is known before handler is called
+	ctx = r.withSenderLanguage(ctx, update)
+	defer func() {
+		if panicMsg := recover(); panicMsg != nil {
+			logger.Get().Error("panic in handler",
+				zap.String("correlationId", correlationID(ctx)),
+				zap.Any("msg", panicMsg),
+				zap.ByteString("stacktrace", debug.Stack()))
+			r.h.HandleError(ctx, fmt.Errorf("panic: %v", panicMsg), update)
+		}
+	}()
+	if err := r.mapToHandler(ctx, update); err != nil {
+		r.logError(ctx, err, update)
+		r.h.HandleError(ctx, err, update)
+	}
+}
+
+// withSenderLanguage records activity of customer who sent update and sets their language.
+// Customer who is not registered yet gets language of Telegram app
+func (r *Router) withSenderLanguage(ctx context.Context, u tg.Update) context.Context {
+	from := u.SentFrom()
+	if from == nil {
+		return ctx
+	}
+	// Activity is used for audience filtering, not critical for handling
+	customer, err := r.customers.TouchActivity(ctx, from.ID, time.Now())
+	if err != nil && !errors.Is(err, domain.ErrCustomerNotFound) {
+		logger.Get().Error("can't touch customer activity", zap.Error(err))
+	}
+	return withLanguage(ctx, customerLanguage(customer, from.LanguageCode))
+}
+
+// logError logs failures customer can't fix as errors, the rest are expected to happen now and then
+func (r *Router) logError(ctx context.Context, err error, update tg.Update) {
+	var (
+		kind, _ = classifyError(ctx, err)
+		fields  = []zap.Field{
+			zap.String("correlationId", correlationID(ctx)),
+			zap.Stringer("kind", kind),
+			zap.Error(err),
+		}
+	)
+	if chat := update.FromChat(); chat != nil {
+		fields = append(fields,
+			zap.String("from", domain.MakeUsername(chat.UserName)),
+			zap.Int64("telegramId", chat.ID))
+	}
+
+	if kind == ErrorKindInternal {
+		logger.Get().Error("error in handler occurred", fields...)
+		return
+	}
+	logger.Get().Warn("handler rejected update", fields...)
+}
+
+// updateChatID is a key of chat queue. Handlers use sender id as customer id, so is the queue
+func updateChatID(u tg.Update) int64 {
+	if from := u.SentFrom(); from != nil {
+		return from.ID
+	}
+	if chat := u.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
+
+// QueueStats shows how many updates are waiting to be handled
+func (r *Router) QueueStats() QueueStats {
+	return r.queues.Stats()
+}
+
+// Shutdown waits for Bootstrap to take the rest of updates and for handlers until ctx is done.
+// Updates channel must be closed before, see Webhook.Close
+func (r *Router) Shutdown(ctx context.Context) error {
+	select {
+	case <-r.bootstrapped:
+	case <-ctx.Done():
+		return fmt.Errorf("updates are still coming: %w", ctx.Err())
+	}
+	r.queues.close()
+
+	drained := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("handlers are still running: %w", ctx.Err())
+	}
 }
 
 func (r *Router) mapToHandler(ctx context.Context, u tg.Update) error {
@@ -166,7 +266,7 @@ func (r *Router) mapToCommandHandler(ctx context.Context, m *tg.Message) error {
 		chatID = m.Chat.ID
 		cmd    = r.command(m.Text)
 	)
-	// get state and route accordingly
+	// Commands go first, anything else is input of the flow customer is at
 	logger.Get().Debug("message info",
 		zap.String("text", m.Text),
 		zap.String("from", domain.MakeUsername(m.From.String())),
@@ -181,30 +281,8 @@ func (r *Router) mapToCommandHandler(ctx context.Context, m *tg.Message) error {
 	case cmd(addPositionCommand):
 		return r.h.AddPosition(ctx, chatID)
 	default:
-		customerState, err := r.stateProvider.GetState(ctx, chatID)
-		if err != nil {
-			return err
-		}
-		switch customerState {
-		case domain.StateWaitingForSize:
-			return r.h.HandleSizeInput(ctx, m)
-		case domain.StateWaitingForPrice:
-			return r.h.HandlePriceInput(ctx, m)
-		case domain.StateWaitingForLink:
-			return r.h.HandleLinkInput(ctx, m)
-		case domain.StateWaitingForCalculatorInput:
-			return r.h.HandleCalculatorInput(ctx, m)
-		case domain.StateWaitingForFIO:
-			return r.h.HandleFIOInput(ctx, m)
-		case domain.StateWaitingForPhoneNumber:
-			return r.h.HandlePhoneNumberInput(ctx, m)
-		case domain.StateWaitingForDeliveryAddress:
-			return r.h.HandleDeliveryAddressInput(ctx, m)
-		case domain.StateDefault:
-			return ErrNoHandler
-		default:
-			return ErrNoHandler
-		}
+		// Input of flow step customer is at, contact is shared with contact request button
+		return r.h.HandleFlowInput(ctx, m)
 	}
 }
 
@@ -214,31 +292,24 @@ func (r *Router) mapToCallbackHandler(ctx context.Context, c *tg.CallbackQuery)
 		zap.String("from", domain.MakeUsername(c.From.String())),
 		zap.String("date", c.Message.Time().Format(time.RFC822)))
 
-	defer r.h.AnswerCallback(c.ID)
+	defer r.h.AnswerCallback(ctx, c.ID)
 
 	var (
-		chatID             = c.From.ID
-		msgID              = c.Message.MessageID
-		intCallbackData    int
-		callbackDataMsgIDs []int
-		stringData         string
+		chatID = c.From.ID
+		msgID  = c.Message.MessageID
 	)
 
-	out, callback, err := parseCallbackData(c.Data)
+	data, err := r.codec.decode(ctx, c.Data)
 	if err != nil {
-		return fmt.Errorf("parseCallbackData: %w", err)
+		return fmt.Errorf("codec.decode: %w", err)
 	}
 
-	intCallbackData = callback
-
-	switch v := out.(type) {
-	case []int:
-		callbackDataMsgIDs = v
-	case string:
-		stringData = v
-	}
+	var (
+		callbackDataMsgIDs = data.ints
+		stringData         = data.str
+	)
 
-	switch intCallbackData {
+	switch data.action {
 	case noopCallback:
 		// Do not do anything
 		return nil
@@ -248,26 +319,81 @@ func (r *Router) mapToCallbackHandler(ctx context.Context, c *tg.CallbackQuery)
 		return r.h.AskForCalculatorOrderType(ctx, chatID)
 	case calculateMoreCallback:
 		return r.h.AskForCalculatorOrderType(ctx, chatID)
-	case orderGuideStep0Callback:
-		return r.h.MakeOrderGuideStep1(ctx, chatID, msgID, callbackDataMsgIDs)
-	case orderGuideStep1Callback:
-		return r.h.MakeOrderGuideStep2(ctx, chatID, msgID, callbackDataMsgIDs)
-	case orderGuideStep2Callback:
-		return r.h.MakeOrderGuideStep3(ctx, chatID, msgID, callbackDataMsgIDs)
-	case orderGuideStep3Callback:
-		return r.h.MakeOrderGuideStep4(ctx, chatID, msgID, callbackDataMsgIDs)
-	case orderGuideStep4Callback:
-		return r.h.MakeOrderGuideStep5(ctx, chatID, msgID, callbackDataMsgIDs)
-	case orderGuideStep5Callback:
-		return r.h.MakeOrderGuideStep6(ctx, chatID, msgID, callbackDataMsgIDs)
+	case orderGuideStepCallback:
+		return r.mapToGuideStepHandler(ctx, chatID, msgID, data)
 	case makeOrderCallback:
 		return r.h.AskForFIO(ctx, chatID)
 	case menuCatalogCallback:
 		return r.h.Catalog(ctx, chatID)
 	case menuMyOrdersCallback:
 		return r.h.MyOrders(ctx, chatID)
+	case myOrdersPageCallback:
+		// msgID in this case is id of orders list, stringData is page
+		return r.h.MyOrdersPage(ctx, chatID, msgID, stringData)
+	case orderDetailsCallback:
+		// stringData in this case is orderShortID
+		return r.h.OrderDetails(ctx, chatID, stringData)
+	case repeatOrderCallback:
+		// stringData in this case is orderShortID
+		return r.h.RepeatOrder(ctx, chatID, stringData)
 	case menuFaqCallback:
 		return r.h.FAQ(ctx, chatID)
+	case toggleDropsSubscriptionCallback:
+		return r.h.ToggleDropsSubscription(ctx, chatID)
+	case menuFavouritesCallback:
+		return r.h.Favourites(ctx, chatID)
+	case addToFavouritesCallback:
+		// stringData in this case is itemID
+		return r.h.AddToFavourites(ctx, chatID, stringData)
+	case removeFromFavouritesCallback:
+		return r.h.RemoveFromFavourites(ctx, chatID, msgID, stringData)
+	case cartRemindersOptOutCallback:
+		return r.h.OptOutCartReminders(ctx, chatID)
+	case editPositionCallback:
+		// stringData in this case is 1-based position number
+		return r.h.SelectPositionToEdit(ctx, chatID, stringData)
+	case editPositionSizeCallback:
+		return r.h.StartPositionEdit(ctx, chatID, stringData, domain.StateWaitingForSize)
+	case editPositionButtonCallback:
+		return r.h.StartPositionEdit(ctx, chatID, stringData, domain.StateWaitingForButton)
+	case editPositionPriceCallback:
+		return r.h.StartPositionEdit(ctx, chatID, stringData, domain.StateWaitingForPrice)
+	case editPositionLinkCallback:
+		return r.h.StartPositionEdit(ctx, chatID, stringData, domain.StateWaitingForLink)
+	case increaseQuantityCallback:
+		// msgID in this case is id of cart preview
+		return r.h.ChangePositionQuantity(ctx, chatID, msgID, stringData, true)
+	case decreaseQuantityCallback:
+		return r.h.ChangePositionQuantity(ctx, chatID, msgID, stringData, false)
+	case menuProfileCallback:
+		return r.h.Profile(ctx, chatID)
+	case menuLanguageCallback:
+		return r.h.Language(ctx, chatID)
+	case setLanguageCallback:
+		// stringData in this case is language code
+		return r.h.SetLanguage(ctx, chatID, stringData)
+	case useSavedDetailsCallback:
+		return r.h.UseSavedDetails(ctx, chatID)
+	case enterNewDetailsCallback:
+		return r.h.EnterNewDetails(ctx, chatID)
+	case useSavedAddressCallback:
+		// stringData in this case is 1-based address number
+		return r.h.UseSavedAddress(ctx, chatID, stringData)
+	case editProfileFIOCallback:
+		return r.h.EditProfileFIO(ctx, chatID)
+	case editProfilePhoneNumberCallback:
+		return r.h.EditProfilePhoneNumber(ctx, chatID)
+	case addProfileAddressCallback:
+		return r.h.AskForProfileAddress(ctx, chatID)
+	case removeProfileAddressCallback:
+		// msgID in this case is id of profile message
+		return r.h.RemoveProfileAddress(ctx, chatID, msgID, stringData)
+	case newDeliveryAddressCallback:
+		return r.h.NewDeliveryAddress(ctx, chatID)
+	case pickupProviderCDEKCallback:
+		return r.h.HandlePickupProviderSelect(ctx, chatID, domain.PickupProviderCDEK)
+	case pickupProviderPickPointCallback:
+		return r.h.HandlePickupProviderSelect(ctx, chatID, domain.PickupProviderPickPoint)
 	case buttonTorqoiseSelectCallback:
 		return r.h.HandleButtonSelect(ctx, c, domain.ButtonTorqoise)
 	case buttonGreySelectCallback:
@@ -303,35 +429,67 @@ func (r *Router) mapToCallbackHandler(ctx context.Context, c *tg.CallbackQuery)
 	case paymentCallback:
 		// stringData in this case is orderShortID
 		return r.h.HandlePayment(ctx, stringData, c)
-	default:
-		// intCallback > edit
-		// Remove position callback
-		if ranges.IsBetween(intCallbackData, editCartRemovePositionOffset, catalogOffset) {
-			// callbackDataMsgIDs[0] - id of preview cart message
-			return r.h.RemoveCartPosition(ctx, chatID, intCallbackData, msgID, callbackDataMsgIDs[0])
+	case removeCartPositionCallback:
+		// ints in this case are 1-based position number and id of preview cart message
+		positionN, err := data.intAt(0)
+		if err != nil {
+			return err
 		}
-
-		// Prev or next callback
-		if ranges.IsBetweenInc(intCallbackData, catalogOffset, faqOffset) {
-			switch intCallbackData - catalogOffset {
-			case catalogNextCallback:
-				return r.h.HandleCatalogNext(ctx, chatID, int64(msgID), callbackDataMsgIDs)
-			case catalogPrevCallback:
-				return r.h.HandleCatalogPrev(ctx, chatID, int64(msgID), callbackDataMsgIDs)
-			}
+		cartPreviewMsgID, err := data.intAt(1)
+		if err != nil {
+			return err
 		}
-		// todo: rm faqOffset + 1000
-		if ranges.IsBetween(intCallbackData, faqOffset, faqOffset+1000) {
-			n_question := intCallbackData - faqOffset
-			return r.h.AnswerQuestion(chatID, n_question)
+		return r.h.RemoveCartPosition(ctx, chatID, positionN, msgID, cartPreviewMsgID)
+	case catalogNextCallback:
+		return r.h.HandleCatalogNext(ctx, chatID, int64(msgID), callbackDataMsgIDs)
+	case catalogPrevCallback:
+		return r.h.HandleCatalogPrev(ctx, chatID, int64(msgID), callbackDataMsgIDs)
+	case flowBackCallback:
+		return r.h.FlowBack(ctx, chatID)
+	case flowCancelCallback:
+		return r.h.FlowCancel(ctx, chatID)
+	case answerQuestionCallback:
+		n, err := data.intAt(0)
+		if err != nil {
+			return err
 		}
+		return r.h.AnswerQuestion(ctx, chatID, n)
+	default:
+		// Most likely button from previous version
+		return fmt.Errorf("%w: unknown action %s", ErrInvalidCallback, data.action)
+	}
+}
 
+// mapToGuideStepHandler routes guide step, ints in guide callback are step and guide message ids
+func (r *Router) mapToGuideStepHandler(ctx context.Context, chatID int64, msgID int, data callbackData) error {
+	step, err := data.intAt(0)
+	if err != nil {
+		return err
+	}
+	guideMsgIDs := data.ints[1:]
+
+	switch step {
+	case 0:
+		return r.h.MakeOrderGuideStep1(ctx, chatID, msgID, guideMsgIDs)
+	case 1:
+		return r.h.MakeOrderGuideStep2(ctx, chatID, msgID, guideMsgIDs)
+	case 2:
+		return r.h.MakeOrderGuideStep3(ctx, chatID, msgID, guideMsgIDs)
+	case 3:
+		return r.h.MakeOrderGuideStep4(ctx, chatID, msgID, guideMsgIDs)
+	case 4:
+		return r.h.MakeOrderGuideStep5(ctx, chatID, msgID, guideMsgIDs)
+	case 5:
+		return r.h.MakeOrderGuideStep6(ctx, chatID, msgID, guideMsgIDs)
+	default:
 		return ErrNoHandler
 	}
 }
 
+// command matches text against command. Reply keyboard commands are message ids,
+// text of the button is recognized in any language since customer might have switched it
 func (r *Router) command(actual string) func(string) bool {
 	return func(want string) bool {
-		return actual == want
+		return actual == want || bundle().Matches(want, actual)
 	}
 }
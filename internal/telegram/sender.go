@@ -0,0 +1,545 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram allows about 30 messages per second in total and about one message per second to the same chat.
+// Limits are a bit lower so that bursts don't end up in 429
+const (
+	globalSendRate  = 25
+	globalSendBurst = 25
+	chatSendRate    = 1
+	chatSendBurst   = 3
+
+	maxSendAttempts = 3
+	// Doubled with every attempt
+	sendRetryBackoff = time.Second
+	// Idle chat limiters are dropped after it
+	chatLimiterTTL = time.Minute
+)
+
+var errSenderClosed = errors.New("sender is closed")
+
+// notSentError is returned for request which never got to telegram, e.g. its ctx was done while it was queued.
+// Other errors don't tell whether telegram got request
+type notSentError struct {
+	err error
+}
+
+func (e notSentError) Error() string {
+	return "request is not sent: " + e.err.Error()
+}
+
+func (e notSentError) Unwrap() error {
+	return e.err
+}
+
+// isNotSent reports whether request surely didn't get to telegram
+func isNotSent(err error) bool {
+	var notSent notSentError
+	return errors.As(err, &notSent)
+}
+
+// sendPriority orders queued requests, interactive replies go before bulk sends
+type sendPriority int
+
+const (
+	priorityInteractive sendPriority = iota
+	priorityBulk
+	prioritiesCount
+)
+
+// clock is replaced in tests
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// tokenBucket allows burst requests at once and then rate requests per second
+type tokenBucket struct {
+	tokens float64
+	rate   float64
+	burst  float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{tokens: burst, rate: rate, burst: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// wait returns how long it takes to have cost tokens, 0 if they're available now
+func (b *tokenBucket) wait(now time.Time, cost float64) time.Duration {
+	b.refill(now)
+	if cost > b.burst {
+		cost = b.burst
+	}
+	if b.tokens >= cost {
+		return 0
+	}
+	return time.Duration((cost - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) take(now time.Time, cost float64) {
+	b.refill(now)
+	b.tokens -= cost
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+type sendRequest struct {
+	// Request is given up when ctx is done
+	ctx    context.Context
+	chatID int64
+	// New messages count towards per-chat limit, edits and callback answers don't
+	message bool
+	// Media group is as many messages as there're media
+	cost     float64
+	priority sendPriority
+	call     func() error
+	attempts int
+	// Request is in progress or was sent at least once
+	started   bool
+	notBefore time.Time
+	done      chan error
+}
+
+// Sender is Bot which keeps sends within Telegram limits. Requests are queued by priority and
+// sent in order within a chat. Sender retries transient errors and waits as long as Telegram asks when it returns 429.
+// Send blocks until request is done or its context is done (see WithContext), Run must be running
+type Sender struct {
+	bot   Bot
+	clock clock
+
+	mu     sync.Mutex
+	queues [prioritiesCount][]*sendRequest
+	global *tokenBucket
+	chats  map[int64]*tokenBucket
+	// Chats with request in progress, the next one waits for it to keep order of messages
+	busy map[int64]bool
+	// Chats Telegram asked to slow down for, 0 is the whole bot
+	paused    map[int64]time.Time
+	lastPrune time.Time
+	closed    bool
+	// Wakes Run up when there's something to send
+	wake chan struct{}
+}
+
+func NewSender(bot Bot) *Sender {
+	return newSender(bot, realClock{})
+}
+
+func newSender(bot Bot, c clock) *Sender {
+	now := c.Now()
+	return &Sender{
+		bot:       bot,
+		clock:     c,
+		global:    newTokenBucket(globalSendRate, globalSendBurst, now),
+		chats:     make(map[int64]*tokenBucket),
+		busy:      make(map[int64]bool),
+		paused:    make(map[int64]time.Time),
+		lastPrune: now,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Bulk is Bot with low priority, e.g. for broadcasts and reminders
+func (s *Sender) Bulk() Bot {
+	return prioritySender{s: s, priority: priorityBulk}
+}
+
+// WithContext returns Bot which gives up waiting when ctx is done, e.g. on handler timeout.
+// Request which is already sent to telegram is not cancelled
+func (s *Sender) WithContext(ctx context.Context) Bot {
+	return prioritySender{s: s, priority: priorityInteractive, ctx: ctx}
+}
+
+func (s *Sender) Send(c tg.Chattable) (tg.Message, error) {
+	return prioritySender{s: s, priority: priorityInteractive}.Send(c)
+}
+
+func (s *Sender) CleanRequest(c tg.Chattable) error {
+	return prioritySender{s: s, priority: priorityInteractive}.CleanRequest(c)
+}
+
+func (s *Sender) SendMediaGroup(c tg.MediaGroupConfig) ([]tg.Message, error) {
+	return prioritySender{s: s, priority: priorityInteractive}.SendMediaGroup(c)
+}
+
+// contextBot is Bot that can bind requests to context, see Sender.WithContext
+type contextBot interface {
+	WithContext(ctx context.Context) Bot
+}
+
+// botWithContext binds requests of bot to ctx if bot supports it
+func botWithContext(ctx context.Context, bot Bot) Bot {
+	if b, ok := bot.(contextBot); ok {
+		return b.WithContext(ctx)
+	}
+	return bot
+}
+
+type prioritySender struct {
+	s        *Sender
+	priority sendPriority
+	// nil is context.Background
+	ctx context.Context
+}
+
+func (p prioritySender) WithContext(ctx context.Context) Bot {
+	p.ctx = ctx
+	return p
+}
+
+func (p prioritySender) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+func (p prioritySender) Send(c tg.Chattable) (tg.Message, error) {
+	var msg tg.Message
+	chatID, message := requestTarget(c)
+	err := p.s.do(p.context(), p.priority, chatID, message, 1, func() error {
+		var err error
+		msg, err = p.s.bot.Send(c)
+		return err
+	})
+	if err != nil {
+		// Request might be still in progress if ctx is done
+		return tg.Message{}, err
+	}
+	return msg, nil
+}
+
+func (p prioritySender) CleanRequest(c tg.Chattable) error {
+	chatID, message := requestTarget(c)
+	return p.s.do(p.context(), p.priority, chatID, message, 1, func() error {
+		return p.s.bot.CleanRequest(c)
+	})
+}
+
+func (p prioritySender) SendMediaGroup(c tg.MediaGroupConfig) ([]tg.Message, error) {
+	var msgs []tg.Message
+	err := p.s.do(p.context(), p.priority, c.ChatID, true, float64(len(c.Media)), func() error {
+		var err error
+		msgs, err = p.s.bot.SendMediaGroup(c)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// requestTarget returns chat of request, 0 if request isn't bound to chat (e.g. callback answer)
+func requestTarget(c tg.Chattable) (chatID int64, message bool) {
+	switch c := c.(type) {
+	case tg.MessageConfig:
+		return c.ChatID, true
+	case tg.PhotoConfig:
+		return c.ChatID, true
+	case tg.VideoConfig:
+		return c.ChatID, true
+	case tg.MediaGroupConfig:
+		return c.ChatID, true
+	case tg.EditMessageTextConfig:
+		return c.ChatID, false
+	case tg.EditMessageReplyMarkupConfig:
+		return c.ChatID, false
+	case tg.DeleteMessageConfig:
+		return c.ChatID, false
+	default:
+		return 0, false
+	}
+}
+
+func (s *Sender) do(ctx context.Context, priority sendPriority, chatID int64, message bool, cost float64, call func() error) error {
+	if err := ctx.Err(); err != nil {
+		return notSentError{err}
+	}
+	r := &sendRequest{
+		ctx:      ctx,
+		chatID:   chatID,
+		message:  message,
+		cost:     cost,
+		priority: priority,
+		call:     call,
+		done:     make(chan error, 1),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return notSentError{errSenderClosed}
+	}
+	s.queues[priority] = append(s.queues[priority], r)
+	s.mu.Unlock()
+	s.signal()
+
+	// Retry of request in progress is dropped by execute
+	select {
+	case <-ctx.Done():
+		if s.dequeue(r) {
+			return notSentError{ctx.Err()}
+		}
+		return ctx.Err()
+	case err := <-r.done:
+		return err
+	}
+}
+
+// dequeue removes request which isn't started yet, false if it's started
+func (s *Sender) dequeue(r *sendRequest) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.started {
+		return false
+	}
+	queue := s.queues[r.priority]
+	for i := range queue {
+		if queue[i] == r {
+			s.queues[r.priority] = append(queue[:i], queue[i+1:]...)
+			return true
+		}
+	}
+	// Already dropped by dispatch or close
+	return true
+}
+
+func (s *Sender) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued requests until ctx is done, requests left in queue fail then
+func (s *Sender) Run(ctx context.Context) {
+	for {
+		var timer <-chan time.Time
+		if wait, ok := s.dispatch(); ok {
+			timer = s.clock.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			s.close()
+			return
+		case <-s.wake:
+		case <-timer:
+		}
+	}
+}
+
+// dispatch starts every request which can be sent now. It returns time until the next one can be sent,
+// false if nothing is to be sent until a request in progress is done or a new one comes
+func (s *Sender) dispatch() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		now      = s.clock.Now()
+		next     time.Duration
+		waiting  bool
+		skipped  = make(map[int64]bool)
+		earliest = func(d time.Duration) {
+			if !waiting || d < next {
+				next = d
+			}
+			waiting = true
+		}
+	)
+
+	for p := range s.queues {
+		kept := s.queues[p][:0]
+		for _, r := range s.queues[p] {
+			// Nobody waits for it anymore
+			if err := r.ctx.Err(); err != nil {
+				if r.started {
+					r.done <- err
+				} else {
+					r.done <- notSentError{err}
+				}
+				continue
+			}
+			// Previous request of the chat goes first, Run is woken up when it's done
+			if skipped[r.chatID] || s.busy[r.chatID] {
+				kept = append(kept, r)
+				continue
+			}
+			if wait := s.readyIn(now, r); wait > 0 {
+				if r.chatID != 0 {
+					skipped[r.chatID] = true
+				}
+				earliest(wait)
+				kept = append(kept, r)
+				continue
+			}
+
+			s.global.take(now, r.cost)
+			if r.message {
+				s.chatLimiter(r.chatID, now).take(now, 1)
+			}
+			if r.chatID != 0 {
+				s.busy[r.chatID] = true
+			}
+			r.started = true
+			go s.execute(r)
+		}
+		s.queues[p] = kept
+	}
+
+	s.prune(now)
+	return next, waiting
+}
+
+func (s *Sender) readyIn(now time.Time, r *sendRequest) time.Duration {
+	wait := r.notBefore.Sub(now)
+	for _, chatID := range []int64{0, r.chatID} {
+		if until, ok := s.paused[chatID]; ok {
+			if d := until.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if d := s.global.wait(now, r.cost); d > wait {
+		wait = d
+	}
+	if r.message {
+		if d := s.chatLimiter(r.chatID, now).wait(now, 1); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+func (s *Sender) chatLimiter(chatID int64, now time.Time) *tokenBucket {
+	b, ok := s.chats[chatID]
+	if !ok {
+		b = newTokenBucket(chatSendRate, chatSendBurst, now)
+		s.chats[chatID] = b
+	}
+	return b
+}
+
+// prune drops state of chats which haven't been sent anything for a while
+func (s *Sender) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < chatLimiterTTL {
+		return
+	}
+	s.lastPrune = now
+	for chatID, b := range s.chats {
+		if !s.busy[chatID] && b.full(now) {
+			delete(s.chats, chatID)
+		}
+	}
+	for chatID, until := range s.paused {
+		if now.After(until) {
+			delete(s.paused, chatID)
+		}
+	}
+}
+
+func (s *Sender) execute(r *sendRequest) {
+	err := r.call()
+	r.attempts++
+
+	s.mu.Lock()
+	delete(s.busy, r.chatID)
+	delay, retry := retryDelay(err, r.attempts)
+	if retry && !s.closed && r.ctx.Err() == nil {
+		r.notBefore = s.clock.Now().Add(delay)
+		if isTooManyRequests(err) {
+			s.paused[r.chatID] = r.notBefore
+		}
+		// Back to the head of queue, so messages of the chat stay in order
+		s.queues[r.priority] = append([]*sendRequest{r}, s.queues[r.priority]...)
+		s.mu.Unlock()
+		s.signal()
+		return
+	}
+	s.mu.Unlock()
+
+	r.done <- err
+	s.signal()
+}
+
+// retryDelay returns false if request must not be retried
+func retryDelay(err error, attempts int) (time.Duration, bool) {
+	if err == nil || attempts >= maxSendAttempts {
+		return 0, false
+	}
+
+	var tgErr *tg.Error
+	if errors.As(err, &tgErr) {
+		switch {
+		case tgErr.Code == http.StatusTooManyRequests:
+			delay := time.Duration(tgErr.RetryAfter) * time.Second
+			if delay <= 0 {
+				delay = sendRetryBackoff
+			}
+			return delay, true
+		case tgErr.Code >= http.StatusInternalServerError:
+			return sendRetryBackoff << (attempts - 1), true
+		default:
+			return 0, false
+		}
+	}
+
+	// Connection errors, telegram might have got the request though
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return sendRetryBackoff << (attempts - 1), true
+	}
+	return 0, false
+}
+
+func isTooManyRequests(err error) bool {
+	var tgErr *tg.Error
+	return errors.As(err, &tgErr) && tgErr.Code == http.StatusTooManyRequests
+}
+
+func (s *Sender) close() {
+	s.mu.Lock()
+	s.closed = true
+	var left []*sendRequest
+	for p := range s.queues {
+		left = append(left, s.queues[p]...)
+		s.queues[p] = nil
+	}
+	s.mu.Unlock()
+
+	for _, r := range left {
+		if r.started {
+			r.done <- errSenderClosed
+		} else {
+			r.done <- notSentError{errSenderClosed}
+		}
+	}
+}
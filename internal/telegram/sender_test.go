@@ -0,0 +1,288 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// fakeClock moves only with Advance
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			kept = append(kept, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = kept
+}
+
+func (c *fakeClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func startSender(t *testing.T, bot Bot) (*Sender, *fakeClock) {
+	clock := newFakeClock()
+	s := newSender(bot, clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Run(ctx)
+	return s, clock
+}
+
+// advance moves clock once sender waits for it
+func advance(t *testing.T, clock *fakeClock, d time.Duration) {
+	require.Eventually(t, func() bool { return clock.pending() > 0 }, time.Second, time.Millisecond)
+	clock.Advance(d)
+}
+
+func (s *Sender) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func TestSenderChatLimit(t *testing.T) {
+	var (
+		bot      = newFakeBot()
+		s, clock = startSender(t, bot)
+		errs     = make(chan error, 5)
+	)
+
+	go func() {
+		for _, text := range []string{"1", "2", "3", "4", "5"} {
+			_, err := s.Send(tg.NewMessage(1, text))
+			errs <- err
+		}
+	}()
+
+	// Burst is sent right away
+	require.Eventually(t, func() bool { return len(bot.sentTexts()) == chatSendBurst }, time.Second, time.Millisecond)
+
+	// Then one message per second
+	advance(t, clock, time.Second/2)
+	require.Len(t, bot.sentTexts(), chatSendBurst)
+	advance(t, clock, time.Second/2)
+	require.Eventually(t, func() bool { return len(bot.sentTexts()) == 4 }, time.Second, time.Millisecond)
+	advance(t, clock, time.Second)
+	require.Eventually(t, func() bool { return len(bot.sentTexts()) == 5 }, time.Second, time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, []string{"1", "2", "3", "4", "5"}, bot.sentTexts())
+}
+
+func TestSenderRetries(t *testing.T) {
+	t.Run("retry after", func(t *testing.T) {
+		var (
+			bot      = newFakeBot()
+			s, clock = startSender(t, bot)
+			errs     = make(chan error, 1)
+		)
+		bot.fail(1, &tg.Error{Code: http.StatusTooManyRequests, ResponseParameters: tg.ResponseParameters{RetryAfter: 5}})
+		go func() {
+			_, err := s.Send(tg.NewMessage(1, "hi"))
+			errs <- err
+		}()
+
+		advance(t, clock, time.Second*4)
+		require.Empty(t, bot.sentTexts())
+		advance(t, clock, time.Second)
+		require.NoError(t, <-errs)
+		require.Equal(t, []string{"hi"}, bot.sentTexts())
+	})
+
+	t.Run("transient errors", func(t *testing.T) {
+		var (
+			bot      = newFakeBot()
+			s, clock = startSender(t, bot)
+			errs     = make(chan error, 1)
+		)
+		bot.fail(1, &tg.Error{Code: http.StatusBadGateway}, &tg.Error{Code: http.StatusBadGateway}, &tg.Error{Code: http.StatusBadGateway})
+		go func() {
+			_, err := s.Send(tg.NewMessage(1, "hi"))
+			errs <- err
+		}()
+
+		// Backoff is doubled, request fails after the last attempt
+		advance(t, clock, sendRetryBackoff)
+		advance(t, clock, sendRetryBackoff*2)
+		err := <-errs
+		require.Error(t, err)
+		require.Empty(t, bot.sentTexts())
+	})
+
+	t.Run("client errors aren't retried", func(t *testing.T) {
+		var (
+			bot  = newFakeBot()
+			s, _ = startSender(t, bot)
+		)
+		bot.fail(1, errBlockedByUser)
+		_, err := s.Send(tg.NewMessage(1, "hi"))
+		require.ErrorIs(t, err, errBlockedByUser)
+		require.True(t, isBlockedError(err))
+	})
+}
+
+func TestSenderPriority(t *testing.T) {
+	var (
+		bot      = newFakeBot()
+		s, clock = startSender(t, bot)
+		errs     = make(chan error, 2)
+	)
+
+	// Global limit is exhausted
+	s.mu.Lock()
+	s.global.tokens = 0
+	s.mu.Unlock()
+
+	go func() {
+		_, err := s.Bulk().Send(tg.NewMessage(1, "bulk"))
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+	go func() {
+		_, err := s.Send(tg.NewMessage(2, "reply"))
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return s.queued() == 2 }, time.Second, time.Millisecond)
+
+	// Token for one request, reply goes first though it came later
+	advance(t, clock, time.Second/globalSendRate)
+	require.Eventually(t, func() bool { return len(bot.sentTexts()) == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"reply"}, bot.sentTexts())
+
+	advance(t, clock, time.Second/globalSendRate)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+	require.Equal(t, []string{"reply", "bulk"}, bot.sentTexts())
+}
+
+func TestSenderClose(t *testing.T) {
+	var (
+		bot         = newFakeBot()
+		clock       = newFakeClock()
+		s           = newSender(bot, clock)
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan struct{})
+		errs        = make(chan error, 1)
+	)
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	s.mu.Lock()
+	s.global.tokens = 0
+	s.mu.Unlock()
+	go func() {
+		_, err := s.Send(tg.NewMessage(1, "hi"))
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	require.ErrorIs(t, <-errs, errSenderClosed)
+	_, err := s.Send(tg.NewMessage(1, "hi"))
+	require.ErrorIs(t, err, errSenderClosed)
+}
+
+func TestSenderContext(t *testing.T) {
+	t.Run("queued request is given up", func(t *testing.T) {
+		var (
+			bot         = newFakeBot()
+			s, clock    = startSender(t, bot)
+			ctx, cancel = context.WithCancel(context.Background())
+			errs        = make(chan error, 1)
+		)
+		s.mu.Lock()
+		s.global.tokens = 0
+		s.mu.Unlock()
+
+		go func() {
+			_, err := s.WithContext(ctx).Send(tg.NewMessage(1, "hi"))
+			errs <- err
+		}()
+		require.Eventually(t, func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+
+		cancel()
+		err := <-errs
+		require.ErrorIs(t, err, context.Canceled)
+		require.True(t, isNotSent(err))
+
+		// Dropped instead of being sent
+		advance(t, clock, time.Second)
+		require.Eventually(t, func() bool { return s.queued() == 0 }, time.Second, time.Millisecond)
+		require.Empty(t, bot.sentTexts())
+	})
+
+	t.Run("retry after is not waited for", func(t *testing.T) {
+		var (
+			bot         = newFakeBot()
+			s, clock    = startSender(t, bot)
+			ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+		)
+		bot.fail(1, &tg.Error{Code: http.StatusTooManyRequests, ResponseParameters: tg.ResponseParameters{RetryAfter: 60}})
+		defer cancel()
+
+		_, err := s.Bulk().(contextBot).WithContext(ctx).Send(tg.NewMessage(1, "hi"))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		// Telegram might have got the first attempt
+		require.False(t, isNotSent(err))
+
+		advance(t, clock, time.Minute)
+		require.Eventually(t, func() bool { return s.queued() == 0 }, time.Second, time.Millisecond)
+		require.Empty(t, bot.sentTexts())
+	})
+}
@@ -2,143 +2,405 @@ package telegram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
 
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
 )
 
-var t = new(templates)
+// Templates by language, see LoadTemplates. Replaced as a whole when templates are reloaded
+var (
+	templatesMu     sync.RWMutex
+	localeTemplates = map[string]*templates{defaultLanguage: new(templates)}
+)
 
 const (
 	yes string = "✅"
 	no         = "❌"
 )
 
-const (
-	askForDeliveryAddressTemplate = "Отправь адрес ближайшего постамата PickPoint или отделения СДЭК ⛳️ в формате:\n\n" +
-		"Страна, область, город, улица, номер дома/строения 🏡\n\n" +
-		"Я доставлю твой заказ туда 🚚"
-
-	askForPhoneNumberTemplate = "Отправь мне свой контактный номер телефона в формате:\n 👉 79128000000"
-
-	invalidFIOInputTemplate = "Неправильный формат полного имени.\\n Отправь полное имя в " +
-		"формате - Иванов Иван Иванович"
-
-	askForFIOTemplate = "Укажи ФИО получателя \U0001FAAA"
-
-	askForButtonColorTemplate = "Выбери цвет кнопки\n(влияет на условия доставки 🚚 и цену 🥬 в дальнейшем)"
-
-	askForSizeTemplate = "Шаг 1. Выбери размер 📏\nНапример: L или 54\nЕсли товар безразмерный, то отправь #"
-
-	askForPriceTemplate = "Отправь стоимость товара в юанях ¥\n(указана на выбранной кнопке) 💴"
+// templates are text/template with named placeholders, keys of templates file are json tags.
+// Placeholders are fields of data template is executed with, see templateData
+type templates struct {
+	Menu               *template.Template `json:"menu"`
+	Start              *template.Template `json:"start"`
+	Catalog            *template.Template `json:"catalog"`
+	CartPreviewStart   *template.Template `json:"cartPreviewStart"`
+	CartPreviewEnd     *template.Template `json:"cartPreviewEnd"`
+	CartPosition       *template.Template `json:"cartPosition"`
+	CalculatorOutput   *template.Template `json:"calculatorOutput"`
+	OrderStart         *template.Template `json:"order"`
+	OrderEnd           *template.Template `json:"orderEnd"`
+	AfterPaid          *template.Template `json:"afterPaid"`
+	Requisites         *template.Template `json:"requisites"`
+	GuideStep1         *template.Template `json:"guide_step1"`
+	GuideStep2         *template.Template `json:"guide_step2"`
+	GuideStep3         *template.Template `json:"guide_step3"`
+	GuideStep4         *template.Template `json:"guide_step4"`
+	GuideStep5         *template.Template `json:"guide_step5"`
+	GuideStep6         *template.Template `json:"guide_step6"`
+	MyOrdersStart      *template.Template `json:"myOrdersStart"`
+	MyOrdersEnd        *template.Template `json:"myOrdersEnd"`
+	SingleOrderPreview *template.Template `json:"singleOrderPreview"`
+}
 
-	askForCategoryTemplate = "Выбери категорию товара (влияет на итоговую стоимость) 💴\n\n" +
-		"В категорию «легкой одежды»относится вся обувь, кроме зимней и одежда, кроме курток 👟🧢\n\n" +
-		"В категорию «тяжелая одежда»относятся все куртки и зимняя обувь 🧥🥾"
+// templateData is data each template is executed with by key in templates file.
+// Templates not listed here are plain texts without placeholders
+var templateData = map[string]any{
+	"start":              usernameData{},
+	"catalog":            usernameData{},
+	"cartPreviewStart":   cartPreviewStartData{},
+	"cartPreviewEnd":     cartPreviewEndData{},
+	"cartPosition":       cartPositionData{},
+	"calculatorOutput":   calculatorOutputData{},
+	"order":              orderStartData{},
+	"orderEnd":           orderEndData{},
+	"afterPaid":          afterPaidData{},
+	"requisites":         requisitesData{},
+	"myOrdersStart":      myOrdersStartData{},
+	"singleOrderPreview": singleOrderPreviewData{},
+}
 
-	askForLinkTemplate = "Отправь ссылку на выбранный товар (строго по инструкции) 📝"
+// getTemplate returns templates of language of ctx, default ones if language has no file
+func getTemplate(ctx context.Context) *templates {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	if t, ok := localeTemplates[language(ctx)]; ok {
+		return t
+	}
+	return localeTemplates[defaultLanguage]
+}
 
-	askForCalculatorInputTemplate = "Отправь стоимость товара в ¥, я посчитаю это в ₽  🇨🇳🇷🇺\n\n" +
-		"Стоимость указана с учетом доставки товара из Китая до Москвы, доставка в другие города и " +
-		"районы России просчитывается и оплачивается отдельно в ТК СДЕК 🚚"
+// execute renders template with data of its type, see templateData. Templates are checked on load,
+// so error here means a bug and customer gets what was rendered before it
+func execute(t *template.Template, data any) string {
+	if t == nil {
+		return ""
+	}
+	var out strings.Builder
+	if err := t.Execute(&out, data); err != nil {
+		logger.Get().Error("can't execute template", zap.String("template", t.Name()), zap.Error(err))
+	}
+	return out.String()
+}
 
-	editPositionTemplate = "Выбери номер позиции, чтобы удалить её 🙅‍♂️\n\nПо клику на " +
-		"кнопку позиция изчезнет из твоей корзины!"
+// LoadTemplates reads templates of default language from path and templates of other languages
+// from files next to it named with language code, e.g. templates.en.json.
+// Templates are replaced only if every file is valid, otherwise ones loaded before stay in use
+func LoadTemplates(path string) error {
+	byLanguage := make(map[string]*templates)
 
-	newPositionWarnTemplate = "Новый добавленный товар будет соответствовать типу доставки первоначально добавленного товара в корзине 🦧"
+	templates, err := readTemplates(path)
+	if err != nil {
+		return err
+	}
+	byLanguage[defaultLanguage] = templates
 
-	deliveryOnlyToMoscowTemplate = "Стоимость указана с учетом доставки товара из Китая до Москвы, доставка в другие " +
-		"города и районы России просчитывается и оплачивается отдельно в ТК СДЕК 🚚"
-)
+	ext := filepath.Ext(path)
+	pattern := strings.TrimSuffix(path, ext) + ".*" + ext
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("can't find templates of languages: %w", err)
+	}
+	for _, localePath := range paths {
+		lang := strings.TrimSuffix(strings.TrimPrefix(localePath, strings.TrimSuffix(path, ext)+"."), ext)
+		templates, err := readTemplates(localePath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", lang, err)
+		}
+		byLanguage[lang] = templates
+	}
 
-type templates struct {
-	Menu                string `json:"menu,omitempty"`
-	Start               string `json:"start,omitempty"`
-	Catalog             string `json:"catalog,omitempty"`
-	CartPreviewStartFMT string `json:"cartPreviewStart,omitempty"`
-	CartPreviewEndFMT   string `json:"cartPreviewEnd,omitempty"`
-	CartPositionFMT     string `json:"cartPosition,omitempty"`
-	CalculatorOutput    string `json:"calculatorOutput,omitempty"`
-	OrderStart          string `json:"order,omitempty"`
-	OrderEnd            string `json:"orderEnd,omitempty"`
-	AfterPaid           string `json:"afterPaid,omitempty"`
-	Requisites          string `json:"requisites,omitempty"`
-	GuideStep1          string `json:"guide_step1,omitempty"`
-	GuideStep2          string `json:"guide_step2,omitempty"`
-	GuideStep3          string `json:"guide_step3,omitempty"`
-	GuideStep4          string `json:"guide_step4,omitempty"`
-	GuideStep5          string `json:"guide_step5,omitempty"`
-	GuideStep6          string `json:"guide_step6,omitempty"`
-	MyOrdersStart       string `json:"myOrdersStart,omitempty"`
-	MyOrdersEnd         string `json:"myOrdersEnd,omitempty"`
-	SingleOrderPreview  string `json:"singleOrderPreview,omitempty"`
-}
-
-func getTemplate() *templates {
-	return t
+	templatesMu.Lock()
+	localeTemplates = byLanguage
+	templatesMu.Unlock()
+	return nil
 }
 
-func LoadTemplates(path string) error {
-	var templates templates
+func readTemplates(path string) (*templates, error) {
+	var sources map[string]string
 
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("can't read file %s: %w", path, err)
+		return nil, fmt.Errorf("can't read file %s: %w", path, err)
 	}
 	if len(content) < 10 {
-		return fmt.Errorf("can't decode file content. File is empty")
+		return nil, fmt.Errorf("can't decode file content. File is empty")
 	}
-	if err := json.NewDecoder(bytes.NewReader(content)).Decode(&templates); err != nil {
-		return fmt.Errorf("can't decode file content: %w", err)
+	if err := json.NewDecoder(bytes.NewReader(content)).Decode(&sources); err != nil {
+		return nil, fmt.Errorf("can't decode file content: %w", err)
 	}
 
+	var templates templates
 	v := reflect.ValueOf(&templates).Elem()
 
+	known := make(map[string]bool, v.NumField())
 	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		if field.Interface() == "" {
-			return fmt.Errorf("missing %s template", v.Type().Field(i).Name)
+		field := v.Type().Field(i)
+		key := field.Tag.Get("json")
+		known[key] = true
+
+		source, ok := sources[key]
+		if !ok || source == "" {
+			return nil, fmt.Errorf("missing %s template", field.Name)
+		}
+		t, err := parseTemplate(key, source, templateData[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", field.Name, err)
+		}
+		v.Field(i).Set(reflect.ValueOf(t))
+	}
+
+	// Misspelled key would leave template missing, it's reported above. Extra key is a leftover or a typo too
+	var unknown []string
+	for key := range sources {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown templates: %s", strings.Join(unknown, ", "))
+	}
+
+	return &templates, nil
+}
+
+// parseTemplate checks that every placeholder of source is a field of data and that template executes with it
+func parseTemplate(name, source string, data any) (*template.Template, error) {
+	if data == nil {
+		data = struct{}{}
+	}
+	t, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFields(t.Root, reflect.TypeOf(data), reflect.TypeOf(data)); err != nil {
+		return nil, err
+	}
+	if err := t.Execute(io.Discard, data); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkFields walks parse tree looking for placeholders data has no field for. dot is type of . at node,
+// nil inside range and with where it's not data. $ is always data
+func checkFields(node parse.Node, dot, data reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkFields(child, dot, data); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkFields(n.Pipe, dot, data)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err := checkFields(arg, dot, data); err != nil {
+					return err
+				}
+			}
+		}
+	case *parse.ChainNode:
+		return checkFields(n.Node, dot, data)
+	case *parse.FieldNode:
+		if dot != nil {
+			return checkField(n.Ident, dot)
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			return checkField(n.Ident[1:], data)
 		}
+	case *parse.IfNode:
+		return checkBranch(n.BranchNode, dot, dot, data)
+	case *parse.RangeNode:
+		return checkBranch(n.BranchNode, dot, nil, data)
+	case *parse.WithNode:
+		return checkBranch(n.BranchNode, dot, nil, data)
+	case *parse.TemplateNode:
+		return checkFields(n.Pipe, dot, data)
 	}
+	return nil
+}
+
+// checkBranch checks pipe and else with dot outside of branch and body with dot of it
+func checkBranch(n parse.BranchNode, dot, bodyDot, data reflect.Type) error {
+	if err := checkFields(n.Pipe, dot, data); err != nil {
+		return err
+	}
+	if err := checkFields(n.List, bodyDot, data); err != nil {
+		return err
+	}
+	return checkFields(n.ElseList, dot, data)
+}
 
-	*t = templates
+func checkField(idents []string, typ reflect.Type) error {
+	for i, name := range idents {
+		if typ == nil || typ.Kind() != reflect.Struct {
+			return fmt.Errorf("unknown placeholder {{.%s}}", strings.Join(idents[:i+1], "."))
+		}
+		field, ok := typ.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return fmt.Errorf("unknown placeholder {{.%s}}", strings.Join(idents[:i+1], "."))
+		}
+		typ = field.Type
+	}
 	return nil
 }
 
-func getCartPreviewStartTemplate(numPositions int, isExpress bool) string {
-	var orderTypeText string
-	if isExpress {
-		orderTypeText = "Экспресс"
-	} else {
-		orderTypeText = "Обычный"
+func getInternalErrorTemplate(ctx context.Context, correlationID string) string {
+	return tr(ctx, "error.internal", correlationID)
+}
+
+type cartPreviewStartData struct {
+	// Number with noun in plural form, e.g. 3 позиции
+	Positions string
+}
+
+func getCartPreviewStartTemplate(ctx context.Context, numPositions int) string {
+	return execute(getTemplate(ctx).CartPreviewStart, cartPreviewStartData{
+		Positions: trPlural(ctx, "cart.positions", numPositions),
+	})
+}
+
+func getOrderTypeText(ctx context.Context, typ domain.OrderType) string {
+	switch typ {
+	case domain.OrderTypeExpress:
+		return tr(ctx, "order_type.express")
+	case domain.OrderTypeNormal:
+		return tr(ctx, "order_type.normal")
+	default:
+		return tr(ctx, "order_type.none")
+	}
+}
+
+// Categories and button colors are stored as russian text, so they're shown by message of the value
+var (
+	categoryMessages = map[domain.Category]string{
+		domain.CategoryLight: "category.light",
+		domain.CategoryHeavy: "category.heavy",
+		domain.CategoryOther: "category.other",
+	}
+	buttonColorMessages = map[domain.Button]string{
+		domain.ButtonTorqoise: "button_color.torquoise",
+		domain.ButtonGrey:     "button_color.grey",
+		domain.Button95:       "button_color.95",
+	}
+	pickupProviderMessages = map[domain.PickupProvider]string{
+		domain.PickupProviderCDEK:      "pickup_provider.cdek",
+		domain.PickupProviderPickPoint: "pickup_provider.pickpoint",
+	}
+)
+
+func getCategoryText(ctx context.Context, cat domain.Category) string {
+	if id, ok := categoryMessages[cat]; ok {
+		return tr(ctx, id)
+	}
+	return string(cat)
+}
+
+func getButtonColorText(ctx context.Context, button domain.Button) string {
+	if id, ok := buttonColorMessages[button]; ok {
+		return tr(ctx, id)
+	}
+	return string(button)
+}
+
+func getPickupProviderText(ctx context.Context, provider domain.PickupProvider) string {
+	if id, ok := pickupProviderMessages[provider]; ok {
+		return tr(ctx, id)
 	}
-	return fmt.Sprintf(t.CartPreviewStartFMT, numPositions, orderTypeText)
+	return provider.String()
 }
 
 type cartPositionPreviewArgs struct {
 	n         int
 	link      string
 	size      string
+	quantity  uint
+	orderType domain.OrderType
 	priceRub  uint64
-	category  string
+	category  domain.Category
 	priceYuan uint64
 }
 
-func getPositionTemplate(args cartPositionPreviewArgs) string {
+// newCartPositionPreviewArgs uses totals of position, so prices account for quantity
+func newCartPositionPreviewArgs(n int, p domain.Position) cartPositionPreviewArgs {
+	return cartPositionPreviewArgs{
+		n:         n,
+		link:      p.ShopLink,
+		size:      p.Size,
+		quantity:  p.GetQuantity(),
+		orderType: p.OrderType,
+		category:  p.Category,
+		priceRub:  p.TotalRUB(),
+		priceYuan: p.TotalYUAN(),
+	}
+}
+
+type cartPositionData struct {
+	N         int
+	Link      string
+	Size      string
+	Category  string
+	OrderType string
+	Quantity  uint
+	PriceRUB  uint64
+	PriceYUAN uint64
+}
+
+func getPositionTemplate(ctx context.Context, args cartPositionPreviewArgs) string {
 	if args.size == "#" {
-		args.size = "без размера"
+		args.size = tr(ctx, "position.no_size")
 	}
-	return fmt.Sprintf(t.CartPositionFMT, args.n, args.link, args.size, args.category, args.priceRub, args.priceYuan)
+	return execute(getTemplate(ctx).CartPosition, cartPositionData{
+		N:         args.n,
+		Link:      args.link,
+		Size:      args.size,
+		Category:  getCategoryText(ctx, args.category),
+		OrderType: getOrderTypeText(ctx, args.orderType),
+		Quantity:  args.quantity,
+		PriceRUB:  args.priceRub,
+		PriceYUAN: args.priceYuan,
+	})
 }
-func getCartPreviewEndTemplate(totalRub uint64, totalYuan uint64) string {
-	return fmt.Sprintf(t.CartPreviewEndFMT, totalRub, totalYuan)
+
+type cartPreviewEndData struct {
+	TotalRUB  uint64
+	TotalYUAN uint64
 }
 
-func getCalculatorOutput(price uint64) string {
-	return fmt.Sprintf(t.CalculatorOutput, price)
+func getCartPreviewEndTemplate(ctx context.Context, totalRub uint64, totalYuan uint64) string {
+	return execute(getTemplate(ctx).CartPreviewEnd, cartPreviewEndData{TotalRUB: totalRub, TotalYUAN: totalYuan})
+}
+
+type calculatorOutputData struct {
+	PriceRUB uint64
+}
+
+func getCalculatorOutput(ctx context.Context, price uint64) string {
+	return execute(getTemplate(ctx).CalculatorOutput, calculatorOutputData{PriceRUB: price})
 }
 
 type orderStartArgs struct {
@@ -150,35 +412,199 @@ type orderStartArgs struct {
 	nCartItems      int
 }
 
-func getOrderStart(args orderStartArgs) string {
-	var expressStr string
+type orderStartData struct {
+	FullName        string
+	ShortOrderID    string
+	OrderType       string
+	PhoneNumber     string
+	DeliveryAddress string
+	// Number with noun in plural form, e.g. 5 товаров
+	CartItems string
+}
+
+func getOrderStart(ctx context.Context, args orderStartArgs) string {
+	orderType := domain.OrderTypeNormal
 	if args.isExpress {
-		expressStr = "Экспресс"
-	} else {
-		expressStr = "Обычный"
+		orderType = domain.OrderTypeExpress
+	}
+
+	return execute(getTemplate(ctx).OrderStart, orderStartData{
+		FullName:        args.fullName,
+		ShortOrderID:    args.shortOrderID,
+		OrderType:       getOrderTypeText(ctx, orderType),
+		PhoneNumber:     args.phoneNumber,
+		DeliveryAddress: args.deliveryAddress,
+		CartItems:       trPlural(ctx, "order.items", args.nCartItems),
+	})
+}
+
+type orderEndData struct {
+	AmountRUB uint64
+}
+
+func getOrderEnd(ctx context.Context, amountRub uint64) string {
+	return execute(getTemplate(ctx).OrderEnd, orderEndData{AmountRUB: amountRub})
+}
+
+type requisitesData struct {
+	ShortOrderID string
+	SberID       string
+	TinkoffID    string
+}
+
+func getRequisites(ctx context.Context, reqs domain.Requisites, shortOrderID string) string {
+	return execute(getTemplate(ctx).Requisites, requisitesData{
+		ShortOrderID: shortOrderID,
+		SberID:       reqs.SberID,
+		TinkoffID:    reqs.TinkoffID,
+	})
+}
+
+func getSplitOrders(ctx context.Context, shortOrderIDs []string, totalRUB uint64) string {
+	return tr(ctx, "order.split", strings.Join(shortOrderIDs, ", "), totalRUB)
+}
+
+var addressStepMessages = map[domain.AddressField]string{
+	domain.AddressFieldCountry:         "address.ask_country",
+	domain.AddressFieldRegion:          "address.ask_region",
+	domain.AddressFieldCity:            "address.ask_city",
+	domain.AddressFieldStreet:          "address.ask_street",
+	domain.AddressFieldHouse:           "address.ask_house",
+	domain.AddressFieldPickupPointCode: "address.ask_pickup_point_code",
+}
+
+func getAddressStepTemplate(ctx context.Context, field domain.AddressField) string {
+	return tr(ctx, addressStepMessages[field])
+}
+
+func getPickupPointNotFound(ctx context.Context, provider domain.PickupProvider, code string) string {
+	return tr(ctx, "address.pickup_point_not_found", getPickupProviderText(ctx, provider), code)
+}
+
+func getPickupPointWrongCity(ctx context.Context, point domain.PickupPoint, city string) string {
+	return tr(ctx, "address.pickup_point_wrong_city", point.Code, point.City, city)
+}
+
+func getPreviousAnswer(ctx context.Context, answer string) string {
+	return tr(ctx, "flow.previous_answer", answer)
+}
+
+func getSavedDetails(ctx context.Context, fullName, phoneNumber string) string {
+	return tr(ctx, "order.saved_details", fullName, phoneNumber)
+}
+
+func getProfile(ctx context.Context, customer domain.Customer) string {
+	var (
+		fullName    = tr(ctx, "profile.no_full_name")
+		phoneNumber = tr(ctx, "profile.no_phone_number")
+	)
+	if customer.FullName != nil && *customer.FullName != "" {
+		fullName = *customer.FullName
 	}
+	if customer.PhoneNumber != nil && *customer.PhoneNumber != "" {
+		phoneNumber = *customer.PhoneNumber
+	}
+
+	out := tr(ctx, "profile.start", fullName, phoneNumber)
+	if len(customer.Addresses) == 0 {
+		return out + tr(ctx, "profile.no_addresses")
+	}
+	out += tr(ctx, "profile.addresses")
+	for i, a := range customer.Addresses {
+		out += fmt.Sprintf("%d. %s — %s\n", i+1, a.Name, a.Address)
+		if !a.IsStructured() {
+			out += tr(ctx, "profile.address_outdated")
+		}
+	}
+	return out
+}
 
-	return fmt.Sprintf(t.OrderStart, args.fullName, args.shortOrderID, expressStr, args.fullName, args.phoneNumber, args.deliveryAddress, args.nCartItems)
+// usernameData is for greetings
+type usernameData struct {
+	Username string
 }
 
-func getOrderEnd(amountRub uint64) string {
-	return fmt.Sprintf(t.OrderEnd, amountRub)
+func getCatalog(ctx context.Context, username string) string {
+	return execute(getTemplate(ctx).Catalog, usernameData{Username: username})
 }
 
-func getRequisites(reqs domain.Requisites, shortOrderID string) string {
-	return fmt.Sprintf(t.Requisites, shortOrderID, reqs.SberID, reqs.TinkoffID, shortOrderID)
+func getCatalogItemCaption(ctx context.Context, item domain.CatalogItem) string {
+	return tr(ctx, "catalog.item", item.ShopLink, item.Title, item.FormatSizes(), item.FormatCities(), item.Quantity, item.PriceRUB)
 }
 
-func getCatalog(username string) string {
-	return fmt.Sprintf(t.Catalog, username)
+type afterPaidData struct {
+	FullName     string
+	ShortOrderID string
 }
 
-func getAfterPaid(fullname, shortOrderID string) string {
-	return fmt.Sprintf(t.AfterPaid, fullname, shortOrderID)
+func getAfterPaid(ctx context.Context, fullname, shortOrderID string) string {
+	return execute(getTemplate(ctx).AfterPaid, afterPaidData{FullName: fullname, ShortOrderID: shortOrderID})
 }
 
-func getMyOrdersStart(fullname string) string {
-	return fmt.Sprintf(t.MyOrdersStart, fullname)
+func getRepeatedOrder(ctx context.Context, shortOrderID string, changes []domain.PriceChange) string {
+	out := tr(ctx, "order.repeated", shortOrderID)
+	if len(changes) == 0 {
+		return out + tr(ctx, "order.repeated_same_prices")
+	}
+
+	out += tr(ctx, "order.repeated_new_prices")
+	for _, c := range changes {
+		trend := "📈"
+		if c.Position.PriceRUB < c.OldPriceRUB {
+			trend = "📉"
+		}
+		out += fmt.Sprintf("%d. %s: %d ₽ → %d ₽ %s\n", c.N, c.Position.ShopLink, c.OldPriceRUB, c.Position.PriceRUB, trend)
+	}
+	return out
+}
+
+type myOrdersStartData struct {
+	FullName string
+}
+
+func getMyOrdersStart(ctx context.Context, fullname string) string {
+	return execute(getTemplate(ctx).MyOrdersStart, myOrdersStartData{FullName: fullname})
+}
+
+var (
+	noOrdersMessages = map[domain.OrdersFilter]string{
+		domain.OrdersFilterAll:       "orders.empty_all",
+		domain.OrdersFilterActive:    "orders.empty_active",
+		domain.OrdersFilterCompleted: "orders.empty_completed",
+	}
+	statusMessages = map[domain.Status]string{
+		domain.StatusNotApproved:      "order.status_not_approved",
+		domain.StatusApproved:         "order.status_approved",
+		domain.StatusBuyout:           "order.status_buyout",
+		domain.StatusTransferToPoison: "order.status_transfer_to_poison",
+		domain.StatusSentFromPoison:   "order.status_sent_from_poison",
+		domain.StatusGotToRussia:      "order.status_got_to_russia",
+		domain.StatusCheckTrack:       "order.status_check_track",
+		domain.StatusGotToOrdererCity: "order.status_got_to_orderer_city",
+	}
+)
+
+func getStatusText(ctx context.Context, status domain.Status) string {
+	if id, ok := statusMessages[status]; ok {
+		return tr(ctx, id)
+	}
+	return domain.StatusTexts[status]
+}
+
+// getMyOrdersList is a page of order summaries, offset is number of orders on previous pages
+func getMyOrdersList(ctx context.Context, fullname string, filter domain.OrdersFilter, orders []domain.Order, offset, page, nPages int) string {
+	if len(orders) == 0 {
+		return tr(ctx, noOrdersMessages[filter])
+	}
+
+	out := getMyOrdersStart(ctx, fullname)
+	for i, o := range orders {
+		out += tr(ctx, "orders.item", offset+i+1, o.ShortID, o.AmountRUB, getStatusText(ctx, o.Status))
+	}
+	if nPages > 1 {
+		out += tr(ctx, "orders.page", page+1, nPages)
+	}
+	return out + tr(ctx, "orders.select")
 }
 
 type singleOrderArgs struct {
@@ -192,17 +618,29 @@ type singleOrderArgs struct {
 	totalRub                      uint64
 }
 
-func getSingleOrderPreview(args singleOrderArgs) string {
+type singleOrderPreviewData struct {
+	ShortOrderID    string
+	OrderType       string
+	DeliveryAddress string
+	Paid            string
+	Approved        string
+	Status          string
+	// Number with noun in plural form, e.g. 5 товаров
+	CartItems string
+	TotalRUB  uint64
+	TotalYUAN uint64
+	Comment   string
+}
+
+func getSingleOrderPreview(ctx context.Context, args singleOrderArgs) string {
 	var (
-		expressStr  string
+		orderType   = domain.OrderTypeNormal
 		paidStr     string
 		approvedStr string
 		commentStr  string
 	)
 	if args.isExpress {
-		expressStr = "Экспресс"
-	} else {
-		expressStr = "Обычный"
+		orderType = domain.OrderTypeExpress
 	}
 
 	if args.isPaid {
@@ -218,14 +656,60 @@ func getSingleOrderPreview(args singleOrderArgs) string {
 	}
 
 	if args.comment == nil {
-		commentStr = "временно отсутствует"
+		commentStr = tr(ctx, "order.no_comment")
 	} else {
 		commentStr = *args.comment
 	}
 
-	return fmt.Sprintf(t.SingleOrderPreview, args.shortID, expressStr, args.deliveryAddress, paidStr, approvedStr, domain.StatusTexts[args.status], args.cartLen, args.totalRub, args.totalYuan, commentStr)
+	return execute(getTemplate(ctx).SingleOrderPreview, singleOrderPreviewData{
+		ShortOrderID:    args.shortID,
+		OrderType:       getOrderTypeText(ctx, orderType),
+		DeliveryAddress: args.deliveryAddress,
+		Paid:            paidStr,
+		Approved:        approvedStr,
+		Status:          getStatusText(ctx, args.status),
+		CartItems:       trPlural(ctx, "order.items", args.cartLen),
+		TotalRUB:        args.totalRub,
+		TotalYUAN:       args.totalYuan,
+		Comment:         commentStr,
+	})
+}
+
+func getStartTemplate(ctx context.Context, username string) string {
+	return execute(getTemplate(ctx).Start, usernameData{Username: username})
+}
+
+func getDropNotification(ctx context.Context, drops []domain.CatalogItem) string {
+	out := tr(ctx, "drops.notification")
+	for _, item := range drops {
+		out += fmt.Sprintf("%s — %d ₽\n", item.Title, item.PriceRUB)
+	}
+	return out
+}
+
+func getFavourites(ctx context.Context, items []domain.CatalogItem, unavailable int) string {
+	out := tr(ctx, "favourites.start")
+	for i, item := range items {
+		stock := tr(ctx, "favourites.in_stock") + " " + yes
+		if item.Quantity <= 0 {
+			stock = tr(ctx, "favourites.out_of_stock") + " " + no
+		}
+		out += fmt.Sprintf("%d. %s — %d ₽, %s\n", i+1, item.Title, item.PriceRUB, stock)
+	}
+	if unavailable > 0 {
+		out += trPlural(ctx, "favourites.unavailable", unavailable)
+	}
+	return out
 }
 
-func getStartTemplate(username string) string {
-	return fmt.Sprintf(t.Start, username)
+func getFavouriteChange(ctx context.Context, change domain.FavouriteChange) string {
+	item := change.Item
+	switch {
+	case change.PriceDropped && change.BackInStock:
+		return tr(ctx, "favourites.back_in_stock_cheaper", item.Title, change.OldPriceRUB, item.PriceRUB)
+	case change.PriceDropped:
+		return tr(ctx, "favourites.cheaper", item.Title, change.OldPriceRUB, item.PriceRUB)
+	default:
+		return tr(ctx, "favourites.back_in_stock", item.Title, item.PriceRUB)
+	}
 }
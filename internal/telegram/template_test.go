@@ -1,119 +1,219 @@
 package telegram
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
 	"github.com/stretchr/testify/require"
 )
 
+const (
+	templatesPath = "../../templates.json"
+	localesDir    = "../../locales"
+)
+
+// readTemplateSources is content of templates file of default language, tests change it to make invalid ones
+func readTemplateSources(t *testing.T) map[string]string {
+	content, err := os.ReadFile(templatesPath)
+	require.NoError(t, err)
+	var sources map[string]string
+	require.NoError(t, json.Unmarshal(content, &sources))
+	return sources
+}
+
+func writeTemplates(t *testing.T, path string, sources map[string]string) {
+	content, err := json.Marshal(sources)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0o644))
+}
+
 func TestLoadTemplates(t *testing.T) {
 	testcases := []struct {
 		description string
-		templates   templates
+		change      func(sources map[string]string)
 		expectedErr string
 	}{
 		{
 			description: "missing MENU template",
-			templates: templates{
-				Start:               "start_template",
-				CartPreviewStartFMT: "cart_preview_start_template",
-				CartPreviewEndFMT:   "cart_preview_end_template",
-				CartPositionFMT:     "cart_position_template",
-				CalculatorOutput:    "output",
+			change: func(sources map[string]string) {
+				delete(sources, "menu")
 			},
 			expectedErr: "missing Menu template",
 		},
 		{
 			description: "missing START template",
-			templates: templates{
-				Menu:                "menu_template",
-				CartPreviewStartFMT: "cart_preview_start_template",
-				CartPreviewEndFMT:   "cart_preview_end_template",
-				CartPositionFMT:     "cart_position_template",
-				CalculatorOutput:    "output",
+			change: func(sources map[string]string) {
+				sources["start"] = ""
 			},
 			expectedErr: "missing Start template",
 		},
 		{
-			description: "empty file",
-			templates:   templates{},
-			expectedErr: "can't decode file content. File is empty",
+			description: "unknown template",
+			change: func(sources map[string]string) {
+				sources["strat"] = "{{.Username}}"
+			},
+			expectedErr: "unknown templates: strat",
+		},
+		{
+			description: "unknown placeholder",
+			change: func(sources map[string]string) {
+				sources["start"] = "Привет, {{.Name}}"
+			},
+			expectedErr: "invalid Start template: unknown placeholder {{.Name}}",
+		},
+		{
+			description: "placeholder in plain text",
+			change: func(sources map[string]string) {
+				sources["menu"] = "Меню {{.Username}}"
+			},
+			expectedErr: "invalid Menu template: unknown placeholder {{.Username}}",
+		},
+		{
+			description: "unknown placeholder in condition",
+			change: func(sources map[string]string) {
+				sources["singleOrderPreview"] = "{{if .Paid}}{{.Total}}{{end}}"
+			},
+			expectedErr: "invalid SingleOrderPreview template: unknown placeholder {{.Total}}",
+		},
+		{
+			description: "field of field",
+			change: func(sources map[string]string) {
+				sources["cartPreviewStart"] = "{{.Positions.Count}}"
+			},
+			expectedErr: "invalid CartPreviewStart template: unknown placeholder {{.Positions.Count}}",
+		},
+		{
+			description: "syntax error",
+			change: func(sources map[string]string) {
+				sources["calculatorOutput"] = "{{.PriceRUB ₽"
+			},
+			expectedErr: "invalid CalculatorOutput template",
 		},
 	}
 
 	for _, tc := range testcases {
-		tempFile, err := ioutil.TempFile("", "test_templates.json")
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		jsonData, err := json.Marshal(tc.templates)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if _, err := tempFile.Write(jsonData); err != nil {
-			t.Fatal(err)
-		}
-		tempFile.Close()
-		defer os.Remove(tempFile.Name())
-
 		t.Run(tc.description, func(t *testing.T) {
-			err := LoadTemplates(tempFile.Name())
+			sources := readTemplateSources(t)
+			tc.change(sources)
+			path := filepath.Join(t.TempDir(), "templates.json")
+			writeTemplates(t, path, sources)
 
-			if err == nil {
-				t.Errorf("LoadTemplates() should have failed")
-			}
-			if err.Error() != tc.expectedErr {
-				t.Errorf("expected '%s', but got '%s'", tc.expectedErr, err.Error())
-			}
+			err := LoadTemplates(path)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expectedErr)
 		})
 	}
-}
 
-func TestInjectStringData(t *testing.T) {
-	tests := []struct {
-		name        string
-		callback    int
-		str         string
-		expected    string
-		expectedErr error
-	}{
-		{"basic", 123, "foo", "sfoo:123", nil},
-		{"negative callback", -789, "bar", "sbar:-789", nil},
-		{"basic", 123, "baz", "sbaz:123", nil},
-	}
+	t.Run("empty file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "templates.json")
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+		require.EqualError(t, LoadTemplates(path), "can't decode file content. File is empty")
+	})
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			result := injectStringData(test.callback, test.str)
-			require.Equal(t, test.expected, result)
-		})
-	}
+	t.Run("invalid file of other language", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTemplates(t, filepath.Join(dir, "templates.json"), readTemplateSources(t))
+		sources := readTemplateSources(t)
+		sources["start"] = "Hi, {{.Name}}"
+		writeTemplates(t, filepath.Join(dir, "templates.en.json"), sources)
+
+		err := LoadTemplates(filepath.Join(dir, "templates.json"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "en: invalid Start template")
+	})
 }
 
-func TestParseStringCallbackData(t *testing.T) {
-	tests := []struct {
-		name        string
-		data        string
-		expected    string
-		expectedCb  int
-		expectedErr error
-	}{
-		{"basic", "sfoo:123", "foo", 123, nil},
-		{"empty string", "s:456", "", 456, nil},
-		{"negative callback", "sbar:-789", "bar", -789, nil},
-		{"callback with leading zeroes", "sbaz:001", "baz", 1, nil},
-	}
+func TestReloadTemplates(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, LoadTemplates(templatesPath))
+	})
+	var (
+		ctx  = context.Background()
+		path = filepath.Join(t.TempDir(), "templates.json")
+	)
+	sources := readTemplateSources(t)
+	sources["start"] = "Привет, {{.Username}}!"
+	writeTemplates(t, path, sources)
+	require.NoError(t, LoadTemplates(path))
+	require.Equal(t, "Привет, Вадим!", getStartTemplate(ctx, "Вадим"))
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			result, cb, err := parseStringCallbackData(test.data)
-			require.Equal(t, test.expected, result)
-			require.Equal(t, test.expectedCb, cb)
-			require.Equal(t, test.expectedErr, err)
-		})
-	}
+	t.Run("invalid templates are rejected", func(t *testing.T) {
+		sources["start"] = "Привет, {{.Name}}!"
+		writeTemplates(t, path, sources)
+		require.Error(t, LoadTemplates(path))
+		require.Equal(t, "Привет, Вадим!", getStartTemplate(ctx, "Вадим"))
+	})
+
+	t.Run("watcher reloads changed file", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("waits for file system events")
+		}
+		logger.Get()
+
+		locales := copyLocales(t)
+
+		reloaded := make(chan error, 1)
+		watcher := NewTemplateWatcher(path, locales)
+		watcher.debounce = time.Millisecond * 50
+		watcher.onReload = func(err error) {
+			select {
+			case reloaded <- err:
+			default:
+			}
+		}
+		watchCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go watcher.Run(watchCtx)
+		// Changes made before directory is watched are missed
+		time.Sleep(time.Millisecond * 100)
+
+		sources["start"] = "Здравствуй, {{.Username}}"
+		writeTemplates(t, path, sources)
+		select {
+		case err := <-reloaded:
+			require.NoError(t, err)
+		case <-time.After(time.Second * 5):
+			t.Fatal("templates are not reloaded")
+		}
+		require.Equal(t, "Здравствуй, Вадим", getStartTemplate(ctx, "Вадим"))
+
+		sources["start"] = "Здравствуй, {{.Name}}"
+		writeTemplates(t, path, sources)
+		select {
+		case err := <-reloaded:
+			require.Error(t, err)
+		case <-time.After(time.Second * 5):
+			t.Fatal("templates are not reloaded")
+		}
+		require.Equal(t, "Здравствуй, Вадим", getStartTemplate(ctx, "Вадим"))
+
+		content, err := os.ReadFile(filepath.Join(locales, "en.json"))
+		require.NoError(t, err)
+		var messages map[string]any
+		require.NoError(t, json.Unmarshal(content, &messages))
+		messages["cart.empty"] = "Nothing here yet"
+		content, err = json.Marshal(messages)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(locales, "en.json"), content, 0o644))
+		// Templates are still invalid, locales are reloaded anyway
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second * 5):
+			t.Fatal("locales are not reloaded")
+		}
+		require.Equal(t, "Nothing here yet", tr(withLanguage(ctx, "en"), "cart.empty"))
+	})
+}
+
+func TestCountTexts(t *testing.T) {
+	ctx := context.Background()
+	require.Contains(t, getCartPreviewStartTemplate(ctx, 3), "3 позиции")
+	require.Contains(t, getCartPreviewStartTemplate(withLanguage(ctx, "en"), 1), "1 item\n")
+	require.Contains(t, getOrderStart(ctx, orderStartArgs{nCartItems: 5}), "5 товаров")
+	require.Contains(t, getSingleOrderPreview(withLanguage(ctx, "en"), singleOrderArgs{cartLen: 2}), "2 items")
 }
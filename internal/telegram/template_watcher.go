@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ReloadTexts loads templates and locales again, see LoadTemplates and LoadLocales.
+// Each of them is replaced only if valid, so templates are reloaded even if locales are invalid
+func ReloadTexts(templatesPath, localesDir string) error {
+	localesErr := LoadLocales(localesDir)
+	templatesErr := LoadTemplates(templatesPath)
+	switch {
+	case localesErr != nil && templatesErr != nil:
+		return fmt.Errorf("%v; %w", localesErr, templatesErr)
+	case localesErr != nil:
+		return localesErr
+	default:
+		return templatesErr
+	}
+}
+
+// TemplateWatcher reloads templates and locales when any of their files changes, see ReloadTexts.
+// Invalid file is logged and texts loaded before stay in use
+type TemplateWatcher struct {
+	path       string
+	localesDir string
+	// Editors write file in several steps, reload happens once changes settle
+	debounce time.Duration
+	// Called after every reload attempt
+	onReload func(err error)
+}
+
+func NewTemplateWatcher(path string, localesDir string) *TemplateWatcher {
+	return &TemplateWatcher{
+		path:       path,
+		localesDir: localesDir,
+		debounce:   time.Millisecond * 500,
+		onReload:   func(err error) {},
+	}
+}
+
+// Run blocks until ctx is done
+func (w *TemplateWatcher) Run(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Get().Error("can't watch templates", zap.Error(err))
+		return
+	}
+	defer watcher.Close()
+
+	// Directory is watched, file itself is gone after editor replaces it with rename
+	for _, dir := range []string{filepath.Dir(w.path), w.localesDir} {
+		if err := watcher.Add(dir); err != nil {
+			logger.Get().Error("can't watch templates", zap.String("path", dir), zap.Error(err))
+			return
+		}
+	}
+	logger.Get().Info("watching templates", zap.String("path", w.path), zap.String("locales", w.localesDir))
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Get().Info("templates watcher is shutting down")
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if (w.isTemplates(event.Name) || w.isLocale(event.Name)) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Get().Error("templates watcher error", zap.Error(err))
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *TemplateWatcher) reload() {
+	err := ReloadTexts(w.path, w.localesDir)
+	if err != nil {
+		logger.Get().Error("can't reload templates, previous ones are kept", zap.Error(err))
+	} else {
+		logger.Get().Info("templates and locales are reloaded")
+	}
+	w.onReload(err)
+}
+
+// isTemplates reports whether file is templates file of default language or of any other, see LoadTemplates
+func (w *TemplateWatcher) isTemplates(name string) bool {
+	var (
+		base = filepath.Base(w.path)
+		ext  = filepath.Ext(base)
+		file = filepath.Base(name)
+	)
+	if file == base {
+		return true
+	}
+	matched, _ := filepath.Match(strings.TrimSuffix(base, ext)+".*"+ext, file)
+	return matched
+}
+
+// isLocale reports whether file is locale of any language, see i18n.Load
+func (w *TemplateWatcher) isLocale(name string) bool {
+	return filepath.Clean(filepath.Dir(name)) == filepath.Clean(w.localesDir) && filepath.Ext(name) == ".json"
+}
@@ -3,20 +3,29 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"strings"
+	"unicode/utf16"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
 	"github.com/sonyamoonglade/poison-tg/pkg/functools"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
 )
 
-func (h *handler) sendWithKeyboard(chatID int64, text string, keyboard interface{}) error {
+func (h *handler) sendWithKeyboard(ctx context.Context, chatID int64, text string, keyboard interface{}) error {
 	m := tg.NewMessage(chatID, text)
 	m.ReplyMarkup = keyboard
-	return h.cleanSend(m)
+	return h.cleanSend(ctx, m)
 }
 
-func (h *handler) cleanSend(c tg.Chattable) error {
-	_, err := h.b.Send(c)
+// bot gives up sending when ctx is done, so slow telegram doesn't hold handler past its timeout
+func (h *handler) bot(ctx context.Context) Bot {
+	return botWithContext(ctx, h.b)
+}
+
+func (h *handler) cleanSend(ctx context.Context, c tg.Chattable) error {
+	_, err := h.bot(ctx).Send(c)
 	return err
 }
 
@@ -31,19 +40,166 @@ func (h *handler) checkRequiredState(ctx context.Context, want domain.State, tel
 	return nil
 }
 
-func (h *handler) sendMessage(chatID int64, text string) error {
-	return h.cleanSend(tg.NewMessage(chatID, text))
+// startFlow moves customer to the first step of a flow or any step flow can be resumed from
+func (h *handler) startFlow(ctx context.Context, chatID int64, state domain.State) error {
+	var telegramID = chatID
+
+	customer, err := h.customerRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("customerRepo.GetByTelegramID: %w", err)
+	}
+	return h.flows.enter(ctx, customer, state)
 }
 
-func makeThumbnails(caption string, urls ...string) []interface{} {
+// Telegram limit of message text length in UTF-16 code units
+const maxMessageLen = 4096
+
+// splitMessage splits text into parts of at most maxLen UTF-16 code units.
+// Text is split by lines, a line is split only if it doesn't fit on its own
+func splitMessage(text string, maxLen int) []string {
+	var (
+		parts []string
+		part  strings.Builder
+		n     int
+	)
+	flush := func() {
+		if part.Len() > 0 {
+			parts = append(parts, part.String())
+			part.Reset()
+			n = 0
+		}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineLen := utf16Len(line)
+		if n+lineLen > maxLen {
+			flush()
+		}
+		if lineLen <= maxLen {
+			part.WriteString(line)
+			n += lineLen
+			continue
+		}
+		for _, r := range line {
+			runeLen := utf16Len(string(r))
+			if n+runeLen > maxLen {
+				flush()
+			}
+			part.WriteRune(r)
+			n += runeLen
+		}
+	}
+	flush()
+	return parts
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+func (h *handler) sendMessage(ctx context.Context, chatID int64, text string) error {
+	return h.cleanSend(ctx, tg.NewMessage(chatID, text))
+}
+
+// makeThumbnails adds caption to first element
+func makeThumbnails(caption string, parseMode string, files ...tg.RequestFileData) []interface{} {
 	var first bool
-	return functools.Map(func(url string, i int) interface{} {
-		thumbnail := tg.NewInputMediaPhoto(tg.FileURL(url))
+	return functools.Map(func(file tg.RequestFileData, i int) interface{} {
+		thumbnail := tg.NewInputMediaPhoto(file)
 		if !first {
 			// add caption to first element
 			thumbnail.Caption = caption
+			thumbnail.ParseMode = parseMode
 			first = true
 		}
 		return thumbnail
-	}, urls)
+	}, files)
+}
+
+type photoGroup struct {
+	caption   string
+	parseMode string
+	urls      []string
+}
+
+// sendPhotoGroup sends photos as media group using cached file ids if possible.
+// Falls back to urls if telegram rejects cached ids
+func (h *handler) sendPhotoGroup(ctx context.Context, chatID int64, g photoGroup) ([]tg.Message, error) {
+	files, cached := h.mediaCache.resolve(ctx, g.urls)
+	sentMsgs, err := h.bot(ctx).SendMediaGroup(tg.NewMediaGroup(chatID, makeThumbnails(g.caption, g.parseMode, files...)))
+	if err != nil && cached && isRejectedFileError(err) {
+		logger.Get().Warn("cached file ids rejected, sending by url", zap.Error(err))
+		h.mediaCache.forget(ctx, g.urls...)
+		sentMsgs, err = h.bot(ctx).SendMediaGroup(tg.NewMediaGroup(chatID, makeThumbnails(g.caption, g.parseMode, urlFiles(g.urls)...)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.mediaCache.remember(ctx, g.urls, sentMsgs)
+	return sentMsgs, nil
+}
+
+// editPhotoGroup replaces media of msgIDs (one by one) with photos of g
+func (h *handler) editPhotoGroup(ctx context.Context, chatID int64, msgIDs []int, g photoGroup) error {
+	files, cached := h.mediaCache.resolve(ctx, g.urls)
+	thumbnails := makeThumbnails(g.caption, g.parseMode, files...)
+
+	var sentMsgs []tg.Message
+	for i, msgID := range msgIDs {
+		if i >= len(thumbnails) {
+			break
+		}
+		editOneMedia := func() (tg.Message, error) {
+			return h.bot(ctx).Send(&tg.EditMessageMediaConfig{
+				BaseEdit: tg.BaseEdit{
+					ChatID:    chatID,
+					MessageID: msgID,
+				},
+				Media: thumbnails[i],
+			})
+		}
+		sent, err := editOneMedia()
+		if err != nil && cached && isRejectedFileError(err) {
+			logger.Get().Warn("cached file ids rejected, editing by url", zap.Error(err))
+			h.mediaCache.forget(ctx, g.urls...)
+			cached = false
+			thumbnails = makeThumbnails(g.caption, g.parseMode, urlFiles(g.urls)...)
+			sent, err = editOneMedia()
+		}
+		if err != nil {
+			return err
+		}
+		sentMsgs = append(sentMsgs, sent)
+	}
+	h.mediaCache.remember(ctx, g.urls, sentMsgs)
+	return nil
+}
+
+// sendPhoto sends single photo using cached file id if possible
+func (h *handler) sendPhoto(ctx context.Context, chatID int64, caption string, url string) error {
+	makePhoto := func(file tg.RequestFileData) tg.PhotoConfig {
+		photo := tg.NewPhoto(chatID, file)
+		photo.ParseMode = parseModeHTML
+		photo.Caption = caption
+		return photo
+	}
+	files, cached := h.mediaCache.resolve(ctx, []string{url})
+	sent, err := h.bot(ctx).Send(makePhoto(files[0]))
+	if err != nil && cached && isRejectedFileError(err) {
+		logger.Get().Warn("cached file id rejected, sending by url", zap.Error(err))
+		h.mediaCache.forget(ctx, url)
+		sent, err = h.bot(ctx).Send(makePhoto(tg.FileURL(url)))
+	}
+	if err != nil {
+		return err
+	}
+	h.mediaCache.remember(ctx, []string{url}, []tg.Message{sent})
+	return nil
+}
+
+// largestPhotoFileID returns file id of the biggest photo size in message
+func largestPhotoFileID(m tg.Message) (string, bool) {
+	if len(m.Photo) == 0 {
+		return "", false
+	}
+	return m.Photo[len(m.Photo)-1].FileID, true
 }
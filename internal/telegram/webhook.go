@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// Router takes updates from channel right away, buffer is for bursts
+	webhookUpdatesBuffer = 100
+)
+
+// Webhook receives updates posted by Telegram and passes them to router the same way long polling does
+type Webhook struct {
+	secret  string
+	updates chan tg.Update
+	// Guards updates channel from sending after it's closed
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewWebhook(secret string) *Webhook {
+	return &Webhook{
+		secret:  secret,
+		updates: make(chan tg.Update, webhookUpdatesBuffer),
+	}
+}
+
+// Updates is a channel for NewRouter
+func (w *Webhook) Updates() tg.UpdatesChannel {
+	return w.updates
+}
+
+// Close stops taking updates and closes updates channel, Router.Bootstrap returns after taking buffered ones
+func (w *Webhook) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.closed = true
+		close(w.updates)
+	}
+}
+
+func (w *Webhook) RegisterRoute(router fiber.Router, path string) {
+	router.Post(path, w.receive)
+}
+
+func (w *Webhook) receive(c *fiber.Ctx) error {
+	if subtle.ConstantTimeCompare([]byte(c.Get(webhookSecretHeader)), []byte(w.secret)) != 1 {
+		logger.Get().Warn("webhook request with invalid secret token", zap.String("ip", c.IP()))
+		return c.SendStatus(http.StatusUnauthorized)
+	}
+
+	var update tg.Update
+	if err := json.Unmarshal(c.Body(), &update); err != nil {
+		return c.SendStatus(http.StatusBadRequest)
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		// Update is delivered again after restart
+		return c.SendStatus(http.StatusServiceUnavailable)
+	}
+
+	select {
+	case w.updates <- update:
+		return c.SendStatus(http.StatusOK)
+	default:
+		// Telegram retries delivery if response is not 2xx
+		logger.Get().Warn("webhook updates buffer is full", zap.Int("updateId", update.UpdateID))
+		return c.SendStatus(http.StatusServiceUnavailable)
+	}
+}
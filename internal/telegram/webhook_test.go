@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sonyamoonglade/poison-tg/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// menuHandler handles only menu command, other methods of RouteHandler panic
+type menuHandler struct {
+	RouteHandler
+	menus chan int64
+}
+
+func (h menuHandler) Menu(ctx context.Context, chatID int64) error {
+	h.menus <- chatID
+	return nil
+}
+
+func tgMenuUpdate(chatID int64) tg.Update {
+	return tg.Update{Message: &tg.Message{
+		From: &tg.User{ID: chatID},
+		Chat: &tg.Chat{ID: chatID},
+		Text: tr(context.Background(), menuCommand),
+	}}
+}
+
+func TestWebhook(t *testing.T) {
+	const (
+		secret = "test-secret"
+		path   = "/telegram/webhook"
+	)
+
+	// Default logger is created lazily, create it before handlers run concurrently
+	logger.Get()
+
+	var (
+		webhook = NewWebhook(secret)
+		h       = menuHandler{menus: make(chan int64, 1)}
+		router  = NewRouter(webhook.Updates(), h, newFakeCustomerRepo(), NewCallbackCodec(newMemoryCallbackStore()), time.Second)
+		app     = fiber.New()
+	)
+	webhook.RegisterRoute(app, path)
+
+	go router.Bootstrap() //nolint:errcheck
+	defer func() {
+		webhook.Close()
+		router.Shutdown(context.Background()) //nolint:errcheck
+	}()
+
+	post := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(webhookSecretHeader, token)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	update := `{"update_id": 1, "message": {"message_id": 1, "date": 1680000000, ` +
+		`"from": {"id": 42, "is_bot": false, "first_name": "Ivan"}, ` +
+		`"chat": {"id": 42, "type": "private"}, "text": "` + menuCommand + `"}}`
+
+	require.Equal(t, http.StatusUnauthorized, post("", update))
+	require.Equal(t, http.StatusUnauthorized, post("wrong", update))
+	require.Equal(t, http.StatusBadRequest, post(secret, "not json"))
+
+	require.Equal(t, http.StatusOK, post(secret, update))
+	select {
+	case chatID := <-h.menus:
+		require.Equal(t, int64(42), chatID)
+	case <-time.After(time.Second):
+		t.Fatal("update is not routed")
+	}
+}
+
+func TestRouterShutdown(t *testing.T) {
+	logger.Get()
+
+	var (
+		updates = make(chan tg.Update)
+		h       = menuHandler{menus: make(chan int64)}
+		router  = NewRouter(updates, h, newFakeCustomerRepo(), NewCallbackCodec(newMemoryCallbackStore()), time.Second)
+	)
+	go router.Bootstrap() //nolint:errcheck
+
+	// Handler is stuck until menu is read
+	updates <- tgMenuUpdate(1)
+	require.Eventually(t, func() bool {
+		return router.QueueStats().ActiveChats == 1
+	}, time.Second, time.Millisecond)
+
+	close(updates)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	require.ErrorIs(t, router.Shutdown(ctx), context.DeadlineExceeded)
+
+	<-h.menus
+}
+
+func TestRouterShutdownDrainsWebhook(t *testing.T) {
+	logger.Get()
+
+	const buffered = 20
+	var (
+		webhook = NewWebhook("test-secret")
+		h       = menuHandler{menus: make(chan int64, buffered)}
+		router  = NewRouter(webhook.Updates(), h, newFakeCustomerRepo(), NewCallbackCodec(newMemoryCallbackStore()), time.Second)
+	)
+	// Updates are received but not taken by router yet when shutdown starts
+	for i := 1; i <= buffered; i++ {
+		webhook.updates <- tgMenuUpdate(int64(i%5 + 1))
+	}
+	webhook.Close()
+
+	go router.Bootstrap() //nolint:errcheck
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	require.NoError(t, router.Shutdown(ctx))
+
+	require.Len(t, h.menus, buffered)
+	require.Equal(t, uint64(buffered), router.QueueStats().Processed)
+}
@@ -0,0 +1,19 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound    = errors.New("blob not found")
+	ErrInvalidName = errors.New("invalid blob name")
+)
+
+// Store is a pluggable storage for binary objects (e.g. catalog images)
+type Store interface {
+	Put(ctx context.Context, name string, r io.Reader) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
@@ -0,0 +1,48 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		content = []byte("image content")
+	)
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	t.Run("put and open", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "a.jpg", bytes.NewReader(content)))
+		rc, err := store.Open(ctx, "a.jpg")
+		require.NoError(t, err)
+		defer rc.Close()
+		got, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.Equal(t, content, got)
+	})
+
+	t.Run("open missing", func(t *testing.T) {
+		_, err := store.Open(ctx, "missing.jpg")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "b.jpg", bytes.NewReader(content)))
+		require.NoError(t, store.Delete(ctx, "b.jpg"))
+		_, err := store.Open(ctx, "b.jpg")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, name := range []string{"", "../a.jpg", "dir/a.jpg", ".hidden"} {
+			err := store.Put(ctx, name, bytes.NewReader(content))
+			require.ErrorIsf(t, err, ErrInvalidName, "name: %s", name)
+		}
+	})
+}
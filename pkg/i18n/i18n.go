@@ -0,0 +1,259 @@
+package i18n
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Message is a plain string in locale file or an object with plural forms, e.g. {"one": "%d item", "other": "%d items"}
+type Message struct {
+	forms map[Form]string
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		m.forms = map[Form]string{FormOther: text}
+		return nil
+	}
+
+	var forms map[Form]string
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return fmt.Errorf("message must be a string or an object of plural forms")
+	}
+	for form := range forms {
+		if !form.valid() {
+			return fmt.Errorf("unknown plural form %q", form)
+		}
+	}
+	m.forms = forms
+	return nil
+}
+
+func (m Message) plural() bool {
+	_, ok := m.forms[FormOther]
+	return len(m.forms) > 1 || !ok
+}
+
+func (m Message) args() int {
+	for _, text := range m.forms {
+		return countArgs(text)
+	}
+	return 0
+}
+
+func (m Message) form(f Form) string {
+	if text, ok := m.forms[f]; ok {
+		return text
+	}
+	return m.forms[FormOther]
+}
+
+// Bundle holds messages of every locale. Message missing in a locale is taken from fallback one,
+// message missing everywhere is shown as its id so that it's noticed
+type Bundle struct {
+	fallback string
+	locales  map[string]map[string]Message
+}
+
+// New is a bundle without messages, every message is shown as its id
+func New(fallback string) *Bundle {
+	return &Bundle{
+		fallback: fallback,
+		locales:  map[string]map[string]Message{fallback: {}},
+	}
+}
+
+// Load reads <lang>.json files of dir. Fallback locale must have every message,
+// other locales can't have messages fallback doesn't have and must use the same arguments
+func Load(dir string, fallback string) (*Bundle, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Glob: %w", err)
+	}
+
+	b := &Bundle{
+		fallback: fallback,
+		locales:  make(map[string]map[string]Message, len(paths)),
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("can't read file %s: %w", path, err)
+		}
+		var messages map[string]Message
+		if err := json.NewDecoder(bytes.NewReader(content)).Decode(&messages); err != nil {
+			return nil, fmt.Errorf("can't decode file %s: %w", path, err)
+		}
+		b.locales[strings.TrimSuffix(filepath.Base(path), ".json")] = messages
+	}
+
+	if _, ok := b.locales[fallback]; !ok {
+		return nil, fmt.Errorf("no messages of fallback locale %s in %s", fallback, dir)
+	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Bundle) validate() error {
+	base := b.locales[b.fallback]
+	for lang, messages := range b.locales {
+		rule := pluralRule(lang)
+		for id, m := range messages {
+			expected, ok := base[id]
+			if !ok {
+				return fmt.Errorf("%s: message %s is not in fallback locale", lang, id)
+			}
+			if m.plural() != expected.plural() {
+				return fmt.Errorf("%s: message %s must be plural in every locale or in none", lang, id)
+			}
+			if m.plural() {
+				for _, form := range rule.forms {
+					if _, ok := m.forms[form]; !ok {
+						return fmt.Errorf("%s: message %s has no %s form", lang, id, form)
+					}
+				}
+			}
+			// Every form takes the same arguments as any form of fallback message
+			args := expected.args()
+			for form, text := range m.forms {
+				if n := countArgs(text); n != args {
+					return fmt.Errorf("%s: %s form of message %s takes %d arguments, expected %d", lang, form, id, n, args)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Verbs of fmt, see countArgs. Space flag is left out, "95% off" is not a verb
+const (
+	fmtFlags = "+-#0123456789."
+	fmtVerbs = "vTtbcdoOqxXUeEfFgGsp"
+)
+
+// countArgs counts fmt verbs. %% and % not followed by a verb (e.g. "95% off") are not verbs
+func countArgs(format string) int {
+	var n int
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte(fmtFlags, format[j]) >= 0 {
+			j++
+		}
+		if j < len(format) && strings.IndexByte(fmtVerbs, format[j]) >= 0 {
+			n++
+			i = j
+		}
+	}
+	return n
+}
+
+// Languages are sorted codes of loaded locales
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.locales))
+	for lang := range b.locales {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func (b *Bundle) Has(lang string) bool {
+	_, ok := b.locales[lang]
+	return ok
+}
+
+func (b *Bundle) Fallback() string {
+	return b.fallback
+}
+
+// Match picks locale for IETF language tag Telegram sends, e.g. en-US is en. Fallback is used for unknown languages
+func (b *Bundle) Match(tag string) string {
+	lang := strings.ToLower(tag)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if b.Has(lang) {
+		return lang
+	}
+	return b.fallback
+}
+
+// Get formats message with fmt verbs, args aren't used if message has none
+func (b *Bundle) Get(lang, id string, args ...any) string {
+	m, ok := b.lookup(lang, id)
+	if !ok {
+		return id
+	}
+	return format(m.form(FormOther), args)
+}
+
+// Plural formats form of message for n, n itself is passed to the message as the first argument
+func (b *Bundle) Plural(lang, id string, n int, args ...any) string {
+	m, ok := b.lookup(lang, id)
+	if !ok {
+		return id
+	}
+	if _, own := b.locales[lang][id]; !own {
+		lang = b.fallback
+	}
+	return format(m.form(pluralRule(lang).form(n)), append([]any{n}, args...))
+}
+
+// Args is number of arguments message takes, for plural message n is one of them. False if there's no such message
+func (b *Bundle) Args(id string) (int, bool) {
+	m, ok := b.locales[b.fallback][id]
+	if !ok {
+		return 0, false
+	}
+	return m.args(), true
+}
+
+// IDs are sorted ids of messages, every one is in fallback locale
+func (b *Bundle) IDs() []string {
+	ids := make([]string, 0, len(b.locales[b.fallback]))
+	for id := range b.locales[b.fallback] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Matches reports whether text is message id in any locale, e.g. text of reply keyboard button
+func (b *Bundle) Matches(id, text string) bool {
+	for _, messages := range b.locales {
+		if m, ok := messages[id]; ok && m.form(FormOther) == text {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bundle) lookup(lang, id string) (Message, bool) {
+	if m, ok := b.locales[lang][id]; ok {
+		return m, true
+	}
+	m, ok := b.locales[b.fallback][id]
+	return m, ok
+}
+
+func format(text string, args []any) string {
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
@@ -0,0 +1,151 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeLocales(t *testing.T, locales map[string]string) string {
+	dir := t.TempDir()
+	for lang, content := range locales {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, lang+".json"), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestBundle(t *testing.T) {
+	dir := writeLocales(t, map[string]string{
+		"ru": `{
+			"hello": "Привет, %s",
+			"only_ru": "Только по-русски",
+			"discount": "Скидка 95%",
+			"items": {"one": "%d товар", "few": "%d товара", "many": "%d товаров"}
+		}`,
+		"en": `{
+			"hello": "Hi, %s",
+			"discount": "95% off",
+			"items": {"one": "%d item", "other": "%d items"}
+		}`,
+	})
+	b, err := Load(dir, "ru")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"en", "ru"}, b.Languages())
+	require.Equal(t, "Hi, Vadim", b.Get("en", "hello", "Vadim"))
+	require.Equal(t, "Привет, Vadim", b.Get("kk", "hello", "Vadim"))
+
+	t.Run("message missing in locale is taken from fallback", func(t *testing.T) {
+		require.Equal(t, "Только по-русски", b.Get("en", "only_ru"))
+	})
+
+	t.Run("unknown message is shown as its id", func(t *testing.T) {
+		require.Equal(t, "unknown", b.Get("en", "unknown"))
+	})
+
+	t.Run("message without args is not formatted", func(t *testing.T) {
+		require.Equal(t, "95% off", b.Get("en", "discount"))
+	})
+
+	t.Run("plural forms", func(t *testing.T) {
+		for n, expected := range map[int]string{1: "1 товар", 3: "3 товара", 5: "5 товаров", 11: "11 товаров", 21: "21 товар", 112: "112 товаров"} {
+			require.Equal(t, expected, b.Plural("ru", "items", n))
+		}
+		require.Equal(t, "1 item", b.Plural("en", "items", 1))
+		require.Equal(t, "2 items", b.Plural("en", "items", 2))
+	})
+
+	t.Run("match telegram language code", func(t *testing.T) {
+		require.Equal(t, "en", b.Match("en-US"))
+		require.Equal(t, "en", b.Match("EN"))
+		require.Equal(t, "ru", b.Match("de"))
+		require.Equal(t, "ru", b.Match(""))
+	})
+
+	t.Run("number of arguments", func(t *testing.T) {
+		n, ok := b.Args("hello")
+		require.True(t, ok)
+		require.Equal(t, 1, n)
+		n, ok = b.Args("items")
+		require.True(t, ok)
+		require.Equal(t, 1, n)
+		n, ok = b.Args("discount")
+		require.True(t, ok)
+		require.Equal(t, 0, n)
+		_, ok = b.Args("unknown")
+		require.False(t, ok)
+	})
+
+	t.Run("ids of fallback locale", func(t *testing.T) {
+		require.Equal(t, []string{"discount", "hello", "items", "only_ru"}, b.IDs())
+	})
+
+	t.Run("matches text of any locale", func(t *testing.T) {
+		require.True(t, b.Matches("discount", "Скидка 95%"))
+		require.True(t, b.Matches("discount", "95% off"))
+		require.False(t, b.Matches("discount", "95%"))
+		require.False(t, b.Matches("unknown", "unknown"))
+	})
+}
+
+func TestLoadValidation(t *testing.T) {
+	testcases := []struct {
+		description string
+		locales     map[string]string
+		expectedErr string
+	}{
+		{
+			description: "no fallback locale",
+			locales:     map[string]string{"en": `{"hello": "Hi"}`},
+			expectedErr: "no messages of fallback locale ru",
+		},
+		{
+			description: "message is not in fallback",
+			locales: map[string]string{
+				"ru": `{"hello": "Привет"}`,
+				"en": `{"hello": "Hi", "bye": "Bye"}`,
+			},
+			expectedErr: "en: message bye is not in fallback locale",
+		},
+		{
+			description: "different number of arguments",
+			locales: map[string]string{
+				"ru": `{"hello": "Привет, %s"}`,
+				"en": `{"hello": "Hi"}`,
+			},
+			expectedErr: "other form of message hello takes 0 arguments, expected 1",
+		},
+		{
+			description: "missing plural form",
+			locales: map[string]string{
+				"ru": `{"items": {"one": "%d товар", "many": "%d товаров"}}`,
+			},
+			expectedErr: "ru: message items has no few form",
+		},
+		{
+			description: "plural in one locale only",
+			locales: map[string]string{
+				"ru": `{"items": {"one": "%d товар", "few": "%d товара", "many": "%d товаров"}}`,
+				"en": `{"items": "%d items"}`,
+			},
+			expectedErr: "en: message items must be plural in every locale or in none",
+		},
+		{
+			description: "unknown plural form",
+			locales: map[string]string{
+				"ru": `{"items": {"one": "%d товар", "several": "%d товара"}}`,
+			},
+			expectedErr: `unknown plural form "several"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := Load(writeLocales(t, tc.locales), "ru")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package i18n
+
+// Form is CLDR plural category
+type Form string
+
+const (
+	FormOne   Form = "one"
+	FormFew   Form = "few"
+	FormMany  Form = "many"
+	FormOther Form = "other"
+)
+
+func (f Form) valid() bool {
+	switch f {
+	case FormOne, FormFew, FormMany, FormOther:
+		return true
+	default:
+		return false
+	}
+}
+
+type plural struct {
+	// Forms plural message of the language must have
+	forms []Form
+	form  func(n int) Form
+}
+
+var (
+	oneOther = plural{
+		forms: []Form{FormOne, FormOther},
+		form: func(n int) Form {
+			if n == 1 {
+				return FormOne
+			}
+			return FormOther
+		},
+	}
+	// 1, 21 товар; 2, 22 товара; 5, 11, 25 товаров
+	eastSlavic = plural{
+		forms: []Form{FormOne, FormFew, FormMany},
+		form: func(n int) Form {
+			if n < 0 {
+				n = -n
+			}
+			switch mod10, mod100 := n%10, n%100; {
+			case mod10 == 1 && mod100 != 11:
+				return FormOne
+			case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+				return FormFew
+			default:
+				return FormMany
+			}
+		},
+	}
+)
+
+var pluralRules = map[string]plural{
+	"ru": eastSlavic,
+	"uk": eastSlavic,
+	"en": oneOther,
+	"kk": oneOther,
+}
+
+func pluralRule(lang string) plural {
+	if rule, ok := pluralRules[lang]; ok {
+		return rule
+	}
+	return oneOther
+}
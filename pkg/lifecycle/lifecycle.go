@@ -0,0 +1,65 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type stopHook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager stops application components one by one in order they were added.
+// All components share one deadline, so a hanging one doesn't block the process forever
+type Manager struct {
+	hooks   []stopHook
+	timeout time.Duration
+}
+
+func New(timeout time.Duration) *Manager {
+	return &Manager{
+		timeout: timeout,
+	}
+}
+
+// OnStop adds component to stop. Order matters, e.g. database goes after everything using it
+func (m *Manager) OnStop(name string, stop func(ctx context.Context) error) {
+	m.hooks = append(m.hooks, stopHook{name: name, stop: stop})
+}
+
+// Wait blocks until SIGINT or SIGTERM is received or ctx is done
+func (m *Manager) Wait(ctx context.Context) os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		return sig
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// Shutdown stops every component even if some of them fail, errors are returned together
+func (m *Manager) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var failed []string
+	for _, h := range m.hooks {
+		if err := h.stop(ctx); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", h.name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("shutdown: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
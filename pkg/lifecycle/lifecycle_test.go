@@ -0,0 +1,55 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerShutdown(t *testing.T) {
+	var (
+		m       = New(time.Second)
+		stopped []string
+	)
+	hook := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return err
+		}
+	}
+	m.OnStop("updates", hook("updates", nil))
+	m.OnStop("router", hook("router", errors.New("handlers are still running")))
+	m.OnStop("database", hook("database", nil))
+
+	err := m.Shutdown()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "router: handlers are still running")
+	// Failed component doesn't prevent the rest from stopping
+	require.Equal(t, []string{"updates", "router", "database"}, stopped)
+}
+
+func TestManagerShutdownDeadline(t *testing.T) {
+	m := New(time.Millisecond * 10)
+	m.OnStop("hanging", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	var deadlineExceeded bool
+	m.OnStop("next", func(ctx context.Context) error {
+		deadlineExceeded = ctx.Err() != nil
+		return nil
+	})
+
+	require.ErrorContains(t, m.Shutdown(), context.DeadlineExceeded.Error())
+	require.True(t, deadlineExceeded)
+}
+
+func TestManagerWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Nil(t, New(time.Second).Wait(ctx))
+}
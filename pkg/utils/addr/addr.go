@@ -3,3 +3,7 @@ package addr
 func Int[V int | int8 | int16 | int32 | int64](v V) *V {
 	return &v
 }
+
+func Uint[V uint | uint8 | uint16 | uint32 | uint64](v V) *V {
+	return &v
+}
@@ -4,9 +4,17 @@ OS=linux
 IP=$(echo $VM_IP)
 USER=aalexandrovich
 
+# Directory of CDEK and PickPoint offices, see delivery.pickup_points_path in config
+if [ ! -f "$PICKUP_POINTS" ]; then
+  echo "PICKUP_POINTS must be path to pickup points file"
+  exit 1
+fi
+
 mkdir deploy
 GOOS=$OS GOARCH=$ARCH go build -o ./deploy/app cmd/app/main.go
-cp templates.json ./deploy
+cp templates*.json ./deploy
+cp -r locales ./deploy
+cp "$PICKUP_POINTS" ./deploy/pickup_points.json
 cp -r videos ./deploy
 echo "building..."
 
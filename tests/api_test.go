@@ -1,13 +1,18 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 
 	f "github.com/brianvoe/gofakeit/v6"
 	"github.com/sonyamoonglade/poison-tg/internal/api/input"
 	"github.com/sonyamoonglade/poison-tg/internal/domain"
+	"github.com/sonyamoonglade/poison-tg/internal/telegram"
+	"github.com/sonyamoonglade/poison-tg/pkg/utils/addr"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func (s *AppTestSuite) TestApiAddItem() {
@@ -199,3 +204,139 @@ func (s *AppTestSuite) TestDeleteItem() {
 		}
 	})
 }
+
+func (s *AppTestSuite) TestReorderCatalog() {
+	var (
+		require = s.Require()
+		ctx     = context.Background()
+	)
+
+	s.Run("move item from rank 30 to rank 2", func() {
+		for i := 0; i < 40; i++ {
+			item := catalogItemFixture()
+			item.Rank = uint(i)
+			err := s.repositories.Catalog.AddItem(ctx, item)
+			require.NoError(err)
+		}
+
+		catalog, err := s.repositories.Catalog.GetCatalog(ctx)
+		require.NoError(err)
+		moved := catalog[30]
+
+		resp, err := s.app.Test(newJsonRequest(http.MethodPut, "/api/catalog/reorder", input.ReorderInput{
+			ItemID: &moved.ItemID,
+			Rank:   addr.Uint(uint(2)),
+		}), -1)
+		require.NoError(err)
+		require.Equal(http.StatusOK, resp.StatusCode)
+
+		var respJson []domain.CatalogItem
+		require.NoError(json.NewDecoder(resp.Body).Decode(&respJson))
+		require.Equal(moved.ItemID, respJson[2].ItemID)
+		for i, item := range respJson {
+			require.Equal(uint(i), item.Rank)
+		}
+
+		// cleanup
+		for _, item := range respJson {
+			s.repositories.Catalog.RemoveItem(ctx, item.ItemID)
+		}
+	})
+
+	s.Run("full order of ids", func() {
+		for i := 0; i < 5; i++ {
+			item := catalogItemFixture()
+			item.Rank = uint(i)
+			err := s.repositories.Catalog.AddItem(ctx, item)
+			require.NoError(err)
+		}
+
+		catalog, err := s.repositories.Catalog.GetCatalog(ctx)
+		require.NoError(err)
+		var ids []primitive.ObjectID
+		for i := len(catalog) - 1; i >= 0; i-- {
+			ids = append(ids, catalog[i].ItemID)
+		}
+
+		resp, err := s.app.Test(newJsonRequest(http.MethodPut, "/api/catalog/reorder", input.ReorderInput{
+			ItemIDs: ids,
+		}), -1)
+		require.NoError(err)
+		require.Equal(http.StatusOK, resp.StatusCode)
+
+		var respJson []domain.CatalogItem
+		require.NoError(json.NewDecoder(resp.Body).Decode(&respJson))
+		for i, item := range respJson {
+			require.Equal(ids[i], item.ItemID)
+			require.Equal(uint(i), item.Rank)
+		}
+
+		// cleanup
+		for _, item := range respJson {
+			s.repositories.Catalog.RemoveItem(ctx, item.ItemID)
+		}
+	})
+
+	s.Run("both commands provided. Should return bad request", func() {
+		id := primitive.NewObjectID()
+		resp, err := s.app.Test(newJsonRequest(http.MethodPut, "/api/catalog/reorder", input.ReorderInput{
+			ItemIDs: []primitive.ObjectID{id},
+			ItemID:  &id,
+			Rank:    addr.Uint(uint(0)),
+		}), -1)
+		require.NoError(err)
+		require.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func (s *AppTestSuite) TestRouterStats() {
+	require := s.Require()
+
+	resp, err := s.app.Test(newJsonRequest(http.MethodGet, "/api/stats/router", nil), -1)
+	require.NoError(err)
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	var stats telegram.QueueStats
+	require.NoError(json.NewDecoder(resp.Body).Decode(&stats))
+	require.Equal(s.tgrouter.QueueStats(), stats)
+}
+
+func (s *AppTestSuite) TestReloadTemplates() {
+	require := s.Require()
+
+	resp, err := s.app.Test(newJsonRequest(http.MethodPost, "/api/templates/reload", nil), -1)
+	require.NoError(err)
+	require.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *AppTestSuite) TestUploadImage() {
+	require := s.Require()
+
+	upload := func(size int) *http.Response {
+		body := new(bytes.Buffer)
+		form := multipart.NewWriter(body)
+		part, err := form.CreateFormFile("image", "image.jpg")
+		require.NoError(err)
+		// JPEG signature, the rest is padding
+		_, err = part.Write(append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, size-3)...))
+		require.NoError(err)
+		require.NoError(form.Close())
+
+		req, _ := http.NewRequest(http.MethodPost, buildURL("/api/catalog/uploadImage"), body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		resp, err := s.app.Test(req, -1)
+		require.NoError(err)
+		return resp
+	}
+
+	s.Run("ok", func() {
+		resp := upload(1 << 10)
+		require.Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	s.Run("too large", func() {
+		resp := upload(5<<20 + 1)
+		require.Equal(http.StatusBadRequest, resp.StatusCode)
+		require.Contains(string(readBody(resp.Body)), "image is too large")
+	})
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/sonyamoonglade/poison-tg/internal/repositories"
 	"github.com/sonyamoonglade/poison-tg/internal/telegram"
 	"github.com/sonyamoonglade/poison-tg/internal/telegram/catalog"
+	"github.com/sonyamoonglade/poison-tg/internal/telegram/pickup"
+	"github.com/sonyamoonglade/poison-tg/pkg/blob"
 	"github.com/sonyamoonglade/poison-tg/pkg/database"
 	"github.com/sonyamoonglade/poison-tg/pkg/logger"
 	"github.com/stretchr/testify/mock"
@@ -22,6 +24,11 @@ import (
 
 var mongoURI, dbName string
 
+const (
+	webhookPath   = "/telegram/webhook"
+	webhookSecret = "e2e-secret"
+)
+
 type MockBot struct {
 	mock.Mock
 }
@@ -51,6 +58,7 @@ type AppTestSuite struct {
 
 	db           *database.Mongo
 	tgrouter     *telegram.Router
+	webhook      *telegram.Webhook
 	tghandler    telegram.RouteHandler
 	api          *api.Handler
 	repositories *repositories.Repositories
@@ -99,17 +107,39 @@ func (s *AppTestSuite) setupDeps() {
 	repos := repositories.NewRepositories(mongo, catalog.MakeUpdateOnChangeFunc(catalogProvider))
 
 	rateProvider := api.NewRateProvider()
-	apiHandler := api.NewHandler(repos.Catalog, repos.Order, repos.Customer, rateProvider)
-
-	updates := make(chan tg.Update)
+	imageStore, err := blob.NewLocalStore(os.TempDir())
+	if err != nil {
+		s.FailNow("failed to create image store", err)
+		return
+	}
+	// Updates are posted to webhook route of app, see postUpdate
+	webhook := telegram.NewWebhook(webhookSecret)
 	mockBot := new(MockBot)
-	tgHandler := telegram.NewHandler(mockBot, repos, rateProvider, catalogProvider)
-	tgRouter := telegram.NewRouter(updates, tgHandler, repos.Customer, time.Second*5)
+	pickupPoints, err := pickup.NewFileDirectory("../internal/telegram/pickup/testdata/points.json")
+	if err != nil {
+		s.FailNow("failed to load pickup points", err)
+		return
+	}
+	if err := telegram.LoadLocales("../locales"); err != nil {
+		s.FailNow("failed to load locales", err)
+		return
+	}
+	if err := telegram.LoadTemplates("../templates.json"); err != nil {
+		s.FailNow("failed to load templates", err)
+		return
+	}
+	callbackCodec := telegram.NewCallbackCodec(repos.Callback)
+	tgHandler := telegram.NewHandler(mockBot, mockBot, repos, rateProvider, catalogProvider, pickupPoints, callbackCodec)
+	tgRouter := telegram.NewRouter(webhook.Updates(), tgHandler, repos.Customer, callbackCodec, time.Second*5)
+	apiHandler := api.NewHandler(repos.Catalog, repos.Order, repos.Customer, repos.Broadcast, rateProvider, imageStore, tgRouter, func() error {
+		return telegram.ReloadTexts("../templates.json", "../locales")
+	}, baseURL)
 
 	mockBot.On("Send", mock.Anything).Return(tg.Message{}, nil)
 
 	app := fiber.New(fiber.Config{
 		Immutable:    true,
+		BodyLimit:    api.BodyLimit,
 		ReadTimeout:  time.Second * 10,
 		WriteTimeout: time.Second * 10,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -119,11 +149,13 @@ func (s *AppTestSuite) setupDeps() {
 	})
 
 	apiHandler.RegisterRoutes(app)
+	webhook.RegisterRoute(app, webhookPath)
 
 	s.app = app
 	s.db = mongo
-	s.updatesChan = updates
+	s.updatesChan = webhook.Updates()
 	s.tgrouter = tgRouter
+	s.webhook = webhook
 	s.tghandler = tgHandler
 	s.api = apiHandler
 	s.repositories = &repos
@@ -149,7 +149,7 @@ func (s *AppTestSuite) TestHandleSizeInput() {
 		require.NoError(err)
 
 		m := newTgMessage(f.IntRange(1, 10), telegramID, username, size)
-		err = s.tghandler.HandleSizeInput(ctx, m)
+		err = s.tghandler.HandleFlowInput(ctx, m)
 		require.NoError(err)
 
 		dbCustomer, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
@@ -223,7 +223,7 @@ func (s *AppTestSuite) HandleTestPriceInput() {
 
 		inpStr := strconv.Itoa(inputYuan)
 		m := newTgMessage(f.IntRange(1, 10), telegramID, username, inpStr)
-		err = s.tghandler.HandlePriceInput(ctx, m)
+		err = s.tghandler.HandleFlowInput(ctx, m)
 		require.NoError(err)
 
 		dbCustomer, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
@@ -269,7 +269,7 @@ func (s *AppTestSuite) TestHandleLinkInput() {
 		require.NoError(err)
 
 		m := newTgMessage(f.IntRange(1, 10), telegramID, username, link)
-		err = s.tghandler.HandleLinkInput(ctx, m)
+		err = s.tghandler.HandleFlowInput(ctx, m)
 		require.NoError(err)
 
 		dbCustomer, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
@@ -170,7 +170,7 @@ func (s *AppTestSuite) TestHandleCalculatorInput() {
 		require.NoError(err)
 
 		m := newTgMessage(f.IntRange(1, 10), telegramID, username, test.input)
-		err = s.tghandler.HandleCalculatorInput(ctx, m)
+		err = s.tghandler.HandleFlowInput(ctx, m)
 		require.NoError(err)
 
 		dbCustomer, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"context"
+
+	f "github.com/brianvoe/gofakeit/v6"
+	"github.com/sonyamoonglade/poison-tg/internal/domain"
+)
+
+func (s *AppTestSuite) TestMakeOrderWithEmptyCart() {
+	var (
+		require    = s.Require()
+		telegramID = f.Int64()
+		username   = f.Username()
+		ctx        = context.Background()
+	)
+
+	fullName, phoneNumber := f.Name(), "79999999999"
+	customer := domain.NewCustomer(telegramID, username)
+	customer.FullName = &fullName
+	customer.PhoneNumber = &phoneNumber
+	err := s.repositories.Customer.Save(ctx, customer)
+	require.NoError(err)
+
+	// Neither new nor saved details are asked for
+	require.NoError(s.tghandler.AskForFIO(ctx, telegramID))
+	require.NoError(s.tghandler.UseSavedDetails(ctx, telegramID))
+
+	dbCustomer, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
+	require.NoError(err)
+	require.Equal(domain.StateDefault, dbCustomer.TgState)
+
+	// cleanup
+	s.repositories.Customer.Delete(ctx, dbCustomer.CustomerID)
+}
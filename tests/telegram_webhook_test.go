@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	f "github.com/brianvoe/gofakeit/v6"
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func (s *AppTestSuite) TestWebhookStart() {
+	var (
+		require    = s.Require()
+		telegramID = f.Int64()
+		username   = f.Username()
+		ctx        = context.Background()
+	)
+
+	go s.tgrouter.Bootstrap() //nolint:errcheck
+	defer func() {
+		s.webhook.Close()
+		s.tgrouter.Shutdown(context.Background()) //nolint:errcheck
+	}()
+
+	update := tg.Update{
+		UpdateID: 1,
+		Message: &tg.Message{
+			MessageID: 1,
+			Date:      int(time.Now().Unix()),
+			From:      &tg.User{ID: telegramID, UserName: username},
+			Chat:      &tg.Chat{ID: telegramID, Type: "private"},
+			Text:      "/start",
+		},
+	}
+	res, err := s.app.Test(newUpdateRequest(update))
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.StatusCode)
+
+	// Customer is registered by /start handler
+	require.Eventually(func() bool {
+		_, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
+		return err == nil
+	}, time.Second*5, time.Millisecond*50)
+
+	dbCustomer, err := s.repositories.Customer.GetByTelegramID(ctx, telegramID)
+	require.NoError(err)
+	s.repositories.Customer.Delete(ctx, dbCustomer.CustomerID)
+}
+
+// newUpdateRequest is a request Telegram makes to webhook
+func newUpdateRequest(update tg.Update) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, webhookPath, newBody(update))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", webhookSecret)
+	return req
+}